@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AccessControl limits which scans each REST API user may see, so one
+// PortHunter instance can serve several teams. With no users configured the
+// API is open to anyone.
+type AccessControl struct {
+	Users     map[string][]string `yaml:"users"`     // Username -> targets they may see, see authorizeOne; "*" for everything
+	Passwords map[string]string   `yaml:"passwords"` // Username -> bcrypt hash, e.g. from "htpasswd -nbB user password"
+}
+
+// Enabled reports whether any users are configured
+func (ac AccessControl) Enabled() bool {
+	return len(ac.Users) > 0
+}
+
+// Authorize reports whether user may see the results for target: every
+// space-separated target in it must lie within one of the user's allowed
+// networks or domains. Scans without a recorded target are only visible to
+// users allowed "*".
+func Authorize(user, target string, ac AccessControl) bool {
+	targets := strings.Fields(target)
+	if len(targets) == 0 {
+		targets = []string{""}
+	}
+	for _, t := range targets {
+		if !authorizeOne(ac.Users[user], t) {
+			return false
+		}
+	}
+	return true
+}
+
+// authorizeOne reports whether target lies wholly within one of allowed.
+// Addresses, CIDR blocks and octet ranges are compared as networks: an
+// allowed address, CIDR block or leading octets ("10.1." is 10.1.0.0/16) must
+// contain every address the target covers, so "10.1." doesn't allow 10.10.0.1.
+// Hostnames are allowed by domain on whole labels: "example.com" allows
+// example.com and www.example.com but not badexample.com.
+func authorizeOne(allowed []string, target string) bool {
+	lo, hi, isAddress := targetBounds(target)
+	for _, entry := range allowed {
+		if entry == "*" {
+			return true
+		}
+		if target == "" {
+			continue
+		}
+		network, isNetwork := allowedNetwork(entry)
+		switch {
+		case isAddress && isNetwork:
+			// A prefix is a contiguous block, so holding both ends means holding everything between
+			if network.Contains(lo) && network.Contains(hi) {
+				return true
+			}
+		case !isAddress && !isNetwork:
+			if inDomain(target, entry) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// targetBounds returns the lowest and highest addresses an IP, CIDR or octet
+// range target covers; ok is false for hostnames
+func targetBounds(target string) (lo, hi netip.Addr, ok bool) {
+	target = FormatTarget(target) // Without brackets around IPv6 addresses
+	if p, err := netip.ParsePrefix(target); err == nil {
+		p = p.Masked()
+		last := p.Addr().AsSlice()
+		for bit := p.Bits(); bit < len(last)*8; bit++ {
+			last[bit/8] |= 0x80 >> (bit % 8)
+		}
+		hi, _ = netip.AddrFromSlice(last)
+		return p.Addr().Unmap(), hi.Unmap(), true
+	}
+	if a, err := netip.ParseAddr(target); err == nil {
+		a = a.WithZone("").Unmap()
+		return a, a, true
+	}
+	if nmapRangeRe.MatchString(target) {
+		lo, hi, _ := nmapRangeBounds(target) // Invalid bounds, which no network contains, if malformed
+		return lo, hi, true
+	}
+	return lo, hi, false
+}
+
+// allowedNetwork parses an access control entry that names addresses: an IP,
+// a CIDR block, or one to three leading IPv4 octets such as "10.1." or "10.1"
+func allowedNetwork(entry string) (netip.Prefix, bool) {
+	if p, err := netip.ParsePrefix(entry); err == nil {
+		return p.Masked(), true
+	}
+	if a, err := netip.ParseAddr(entry); err == nil {
+		a = a.WithZone("").Unmap()
+		return netip.PrefixFrom(a, a.BitLen()), true
+	}
+	octets := strings.Split(strings.TrimSuffix(entry, "."), ".")
+	if len(octets) > 3 {
+		return netip.Prefix{}, false
+	}
+	var addr [4]byte
+	for i, octet := range octets {
+		n, err := strconv.Atoi(octet)
+		if err != nil || n < 0 || n > 255 || octet != strconv.Itoa(n) {
+			return netip.Prefix{}, false
+		}
+		addr[i] = byte(n)
+	}
+	return netip.PrefixFrom(netip.AddrFrom4(addr), 8*len(octets)), true
+}
+
+// inDomain reports whether hostname is domain or one of its subdomains,
+// ignoring case and trailing dots
+func inDomain(hostname, domain string) bool {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	domain = strings.ToLower(strings.TrimPrefix(strings.TrimSuffix(domain, "."), "."))
+	return domain != "" && (hostname == domain || strings.HasSuffix(hostname, "."+domain))
+}
+
+// dummyPasswordHash is compared against for unknown users, so a failed login
+// takes as long whether or not the user exists
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("porthunter"), bcrypt.DefaultCost)
+
+// authenticate checks the request's basic auth credentials and returns the user
+func (ac AccessControl) authenticate(r *http.Request) (string, bool) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	hash, known := ac.Passwords[user]
+	if !known {
+		hash = string(dummyPasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil || !known {
+		return "", false
+	}
+	_, allowed := ac.Users[user]
+	return user, allowed
+}
+
+// apiUserKey is the request context key for the authenticated user's name
+type apiUserKey struct{}
+
+// requireAuth lets only authenticated users through to next, recording the
+// user in the request context. It does nothing if access control is off.
+func (ac AccessControl) requireAuth(next http.Handler) http.Handler {
+	if !ac.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := ac.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="PortHunter", charset="UTF-8"`)
+			writeJSONError(w, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiUserKey{}, user)))
+	})
+}
+
+// visibleScans returns the scans user may see, oldest first
+func (ac AccessControl) visibleScans(user string) ([]ScanResult, error) {
+	scans, err := LoadScanHistory()
+	if err != nil {
+		return nil, err
+	}
+	visible := scans[:0]
+	for _, scan := range scans {
+		if Authorize(user, scan.Target, ac) {
+			visible = append(visible, scan)
+		}
+	}
+	return visible, nil
+}
+
+// visibleScansAfter returns up to limit of the scans user may see taken
+// after the scan time after, and how many scans the user may see in total
+func (ac AccessControl) visibleScansAfter(user, after string, limit int) ([]ScanResult, int, error) {
+	scans, err := ac.visibleScans(user)
+	if err != nil {
+		return nil, 0, err
+	}
+	i := sort.Search(len(scans), func(i int) bool { return scanTime(scans[i].DateTime).After(scanTime(after)) })
+	return scans[i:min(len(scans), i+limit)], len(scans), nil
+}
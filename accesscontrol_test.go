@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestAuthorize(t *testing.T) {
+	ac := AccessControl{Users: map[string][]string{
+		"alice": {"10.1.", "corp.example.com"},
+		"bob":   {"192.168.0.0/16", "2001:db8:1::/48"},
+		"carol": {"10.20.30.40"},
+		"admin": {"*"},
+	}}
+	tests := []struct {
+		user, target string
+		want         bool
+	}{
+		{"alice", "10.1.0.5", true},
+		{"alice", "10.1.0.0/24", true},
+		{"alice", "10.1.0-255.*", true},
+		{"alice", "10.10.0.1", false}, // "10.1." is an octet prefix, not a string prefix
+		{"alice", "10.100.0.0/24", false},
+		{"alice", "10.0.0.0/8", false}, // Wider than what alice may see
+		{"alice", "10.0-1.0.1", false},
+		{"alice", "www.corp.example.com", true},
+		{"alice", "CORP.example.com.", true},
+		{"alice", "evilcorp.example.com", false},
+		{"alice", "corp.example.com.attacker.net", false},
+		{"alice", "10.1.0.5 10.2.0.5", false}, // Every target must be allowed
+		{"alice", "", false},
+
+		{"bob", "192.168.1.10", true},
+		{"bob", "192.168.0.0/16", true},
+		{"bob", "192.169.0.1", false},
+		{"bob", "2001:db8:1::10", true},
+		{"bob", "[2001:db8:1::10]", true},
+		{"bob", "2001:db8:1:ff::/64", true},
+		{"bob", "2001:db8:10::1", false},
+		{"bob", "2001:db8::/32", false},
+		{"bob", "::ffff:192.168.1.1", true},
+
+		{"carol", "10.20.30.40", true},
+		{"carol", "10.20.30.4", false},
+		{"carol", "10.20.30.40/24", false},
+
+		{"admin", "anything.example.org", true},
+		{"admin", "", true},
+		{"mallory", "10.1.0.5", false}, // Not configured
+	}
+	for _, tt := range tests {
+		if got := Authorize(tt.user, tt.target, ac); got != tt.want {
+			t.Errorf("Authorize(%q, %q) = %v, want %v", tt.user, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedNetwork(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  string // "" when the entry isn't a network
+	}{
+		{"10.", "10.0.0.0/8"},
+		{"10.1", "10.1.0.0/16"},
+		{"10.1.2.", "10.1.2.0/24"},
+		{"10.1.2.3", "10.1.2.3/32"},
+		{"10.1.2.3/20", "10.1.0.0/20"},
+		{"2001:db8::/32", "2001:db8::/32"},
+		{"::1", "::1/128"},
+		{"10.01", ""}, // Leading zeros are ambiguous
+		{"10.256.", ""},
+		{"example.com", ""},
+		{"web-", ""},
+	}
+	for _, tt := range tests {
+		network, ok := allowedNetwork(tt.entry)
+		got := ""
+		if ok {
+			got = network.String()
+		}
+		if got != tt.want {
+			t.Errorf("allowedNetwork(%q) = %q, want %q", tt.entry, got, tt.want)
+		}
+	}
+}
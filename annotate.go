@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// annotationsFile holds analysts' notes on hosts, keyed by IP. It is separate
+// from the stored scans, so SaveScan never touches it.
+func annotationsFile() string { return filepath.Join(scanFolder, "annotations.json") }
+
+// LoadAnnotations returns the note for each annotated host
+func LoadAnnotations() (map[string]string, error) {
+	notes := make(map[string]string)
+	data, err := os.ReadFile(annotationsFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return notes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return nil, fmt.Errorf("%s: %v", annotationsFile(), err)
+	}
+	return notes, nil
+}
+
+// GetAnnotation returns the note for ip, or "" if it has none
+func GetAnnotation(ip string) string {
+	notes, err := LoadAnnotations()
+	if err != nil {
+		logger.Warn("reading annotations: %v", err)
+	}
+	return notes[ip]
+}
+
+// SetAnnotation stores note for ip; an empty note removes it
+func SetAnnotation(ip, note string) error {
+	notes, err := LoadAnnotations()
+	if err != nil {
+		return err
+	}
+	if note == "" {
+		delete(notes, ip)
+	} else {
+		notes[ip] = note
+	}
+
+	if err := EnsureScanFolderExists(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(annotationsFile(), data, 0644)
+}
+
+// loadAnnotationsForDisplay is LoadAnnotations for output that should still
+// render if the file is unreadable
+func loadAnnotationsForDisplay() map[string]string {
+	notes, err := LoadAnnotations()
+	if err != nil {
+		logger.Warn("reading annotations: %v", err)
+	}
+	return notes
+}
+
+// runAnnotate implements "porthunter annotate -ip 10.0.0.1 -note ...". Without
+// -note it prints the host's note, and without -ip every note.
+func runAnnotate(args []string) error {
+	fs := flag.NewFlagSet("annotate", flag.ContinueOnError)
+	ip := fs.String("ip", "", "Host to annotate")
+	note := fs.String("note", "", "Note to attach to the host")
+	remove := fs.Bool("remove", false, "Remove the host's note")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	notes, err := LoadAnnotations()
+	if err != nil {
+		return err
+	}
+	if *ip == "" {
+		ips := make([]string, 0, len(notes))
+		for ip := range notes {
+			ips = append(ips, ip)
+		}
+		sort.Strings(ips)
+		for _, ip := range ips {
+			fmt.Printf("%-20s %s\n", ip, notes[ip])
+		}
+		return nil
+	}
+	if net.ParseIP(*ip) == nil {
+		return fmt.Errorf("invalid IP address %q", *ip)
+	}
+
+	switch {
+	case *remove:
+		return SetAnnotation(*ip, "")
+	case *note != "":
+		return SetAnnotation(*ip, *note)
+	case notes[*ip] == "":
+		return fmt.Errorf("no note for %s", *ip)
+	}
+	fmt.Println(notes[*ip])
+	return nil
+}
+
+// annotationSuffix returns " (note)" for an annotated host, for appending to its IP
+func annotationSuffix(notes map[string]string, ip string) string {
+	if note := notes[ip]; note != "" {
+		return " (" + note + ")"
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ansibleGroupRe matches the characters not allowed in an Ansible group name
+var ansibleGroupRe = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// ValidateAnsibleGroupBy checks an -ansible-group-by value
+func ValidateAnsibleGroupBy(groupBy string) error {
+	if groupBy != "service" && groupBy != "os" {
+		return fmt.Errorf("unknown -ansible-group-by %q (choose service or os)", groupBy)
+	}
+	return nil
+}
+
+// GenerateAnsibleInventory returns an INI-format Ansible inventory of the
+// hosts in result with open ports. Hosts are grouped by groupBy: "service"
+// for the service on their lowest open port, or "os" for nmap's OS match.
+// Hosts without one go in the "unknown" group.
+func GenerateAnsibleInventory(result ScanResult, groupBy string) string {
+	groups := make(map[string][]string)
+	for _, ip := range sortedHostKeys(result.Ports) {
+		var group string
+		lowest := -1
+		for _, entry := range result.Ports[ip] {
+			port, _, state, service := splitPortEntry(entry)
+			n, err := strconv.Atoi(port)
+			if err != nil || state != "open" {
+				continue
+			}
+			if lowest < 0 || n < lowest {
+				lowest, group = n, service
+			}
+		}
+		if lowest < 0 {
+			continue // No open ports
+		}
+		if groupBy == "os" {
+			group = result.Hosts[ip].OS
+		}
+		group = strings.Trim(ansibleGroupRe.ReplaceAllString(strings.ToLower(group), "_"), "_")
+		if group == "" || group == "unknown" {
+			group = "unknown"
+		} else if group[0] >= '0' && group[0] <= '9' {
+			group = "_" + group // Ansible group names can't start with a digit
+		}
+		groups[group] = append(groups[group], ip)
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "[%s]\n", name)
+		for _, ip := range groups[name] {
+			sb.WriteString(ip + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// newHosts returns the hosts in new that were not in old
+func newHosts(old, new ScanResult) ScanResult {
+	added := ScanResult{Ports: make(map[string][]string), Hosts: make(map[string]HostResult)}
+	for ip, ports := range new.Ports {
+		if _, ok := old.Ports[ip]; !ok {
+			added.Ports[ip] = ports
+			if host, ok := new.Hosts[ip]; ok {
+				added.Hosts[ip] = host
+			}
+		}
+	}
+	return added
+}
+
+// writeAnsibleInventory writes an inventory of the hosts with open ports that
+// are new since old to path, if there are any
+func writeAnsibleInventory(path, groupBy string, old, new ScanResult) error {
+	inventory := GenerateAnsibleInventory(newHosts(old, new), groupBy)
+	if inventory == "" {
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(inventory), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Ansible inventory of new hosts written to %s\n", path)
+	return nil
+}
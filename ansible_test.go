@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateAnsibleInventory(t *testing.T) {
+	scan := ScanResult{
+		Ports: map[string][]string{
+			"10.0.0.1": {"443/tcp [open] (https)", "80/tcp [open] (http)"},
+			"10.0.0.2": {"22/tcp [filtered] (ssh)", "80/tcp [open] (http)"},
+			"10.0.0.3": {"22/tcp [open] (ssh)"},
+			"10.0.0.4": {"25/tcp [closed] (smtp)"},
+			"10.0.0.5": {"9999/tcp [open] ()"},
+		},
+		Hosts: map[string]HostResult{
+			"10.0.0.1": {IP: "10.0.0.1", OS: "Linux 5.0 - 5.4"},
+			"10.0.0.3": {IP: "10.0.0.3", OS: "Linux 5.0 - 5.4"},
+			"10.0.0.5": {IP: "10.0.0.5", OS: "3Com 4500G switch"},
+		},
+	}
+
+	tests := []struct {
+		groupBy string
+		want    string
+	}{
+		{"service", "[http]\n10.0.0.1\n10.0.0.2\n\n[ssh]\n10.0.0.3\n\n[unknown]\n10.0.0.5\n"},
+		{"os", "[_3com_4500g_switch]\n10.0.0.5\n\n[linux_5_0_5_4]\n10.0.0.1\n10.0.0.3\n\n[unknown]\n10.0.0.2\n"},
+	}
+	for _, tt := range tests {
+		if got := GenerateAnsibleInventory(scan, tt.groupBy); got != tt.want {
+			t.Errorf("group by %s:\n%s\nwant:\n%s", tt.groupBy, got, tt.want)
+		}
+	}
+}
+
+func TestWriteAnsibleInventoryNewHosts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.ini")
+	old := ScanResult{Ports: map[string][]string{"10.0.0.1": {"80/tcp [open] (http)"}}}
+	new := ScanResult{Ports: map[string][]string{
+		"10.0.0.1": {"80/tcp [open] (http)", "22/tcp [open] (ssh)"},
+		"10.0.0.2": {"22/tcp [open] (ssh)"},
+	}}
+
+	if err := writeAnsibleInventory(path, "service", old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("inventory written without new hosts (%v)", err)
+	}
+
+	if err := writeAnsibleInventory(path, "service", old, new); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[ssh]\n10.0.0.2\n"; string(data) != want {
+		t.Errorf("inventory %q, want only the new host %q", data, want)
+	}
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// App is a configured PortHunter run. main builds one from the command line
+// and calls Run; anything else can build one with a different Store,
+// Notifiers, Logger, data folder or exclusion list, e.g. in-memory ones and a
+// temporary folder.
+//
+// Those are what an App holds. The reporting modes (-stats, -trend,
+// -serve...), the daemon, tuning such as the port database and history depth,
+// and the state kept for change windows, digests and JIRA tickets still use
+// the package-wide settings applyConfig sets.
+type App struct {
+	Config     Config
+	Store      Store      // Where scans are loaded from and saved to
+	Notifiers  []Notifier // Told about changes; see notify
+	Logger     Logger
+	DataDir    string   // Folder for the host index and archived raw output (required)
+	Exclusions []string // Targets never to scan, from -exclude-file
+	Args       []string // Arguments left after the flags, used by -export-ical
+
+	watchdog bool            // Run as "porthunter watchdog"
+	reloader *configReloader // Re-reads the configuration on reloadSignal; nil disables reloading
+}
+
+// NewApp returns an App using the store, notifiers, logger, data folder and
+// exclusion list configured by cfg. cfg should already have been applied with
+// applyConfig, which sets the package-wide settings App doesn't hold.
+func NewApp(cfg Config) (*App, error) {
+	exclusions, err := loadExclusions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &App{
+		Config:     cfg,
+		Store:      storeFor(cfg),
+		Notifiers:  configuredNotifiers(cfg),
+		Logger:     NewLogger(os.Stdout, cfg.Verbosity),
+		DataDir:    cfg.DataDir,
+		Exclusions: exclusions,
+	}, nil
+}
+
+// ExitError asks main to exit with Code once Run returns. Err, if set, is
+// reported first.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// Run does what the configuration asks for: one of the reporting modes
+// (-stats, -trend, -serve...), the daemon, the watchdog, a single scan or,
+// with -watch, scans until ctx is cancelled or the process is interrupted.
+func (a *App) Run(ctx context.Context) error {
+	cfg := a.Config
+	PrintBanner(cfg)
+	if !cfg.NoUpdateCheck && IsTerminal(os.Stdout) {
+		CheckForUpdate()
+	}
+
+	// Only page when a human is at the keyboard
+	if !cfg.NoBanner && IsTerminal(os.Stdout) && IsTerminal(os.Stdin) {
+		diffPager.PageSize = cfg.PageSize
+	}
+
+	if cfg.CheckDeps {
+		if !CheckDeps(cfg, os.Stdout) {
+			return &ExitError{Code: 1}
+		}
+		return nil
+	}
+
+	if a.watchdog {
+		return runWatchdog(cfg)
+	}
+
+	if cfg.StressTest > 0 {
+		report, err := RunStressTest(cfg.StressTest)
+		if err != nil {
+			return err
+		}
+		PrintStressReport(report, os.Stdout)
+		return nil
+	}
+
+	if cfg.ImportShodan != "" {
+		return RunShodanImport(cfg)
+	}
+
+	if cfg.BenchmarkScan {
+		command, err := ResolveCommand(cfg)
+		if err != nil {
+			return err
+		}
+		PrintScanBenchmark(RunScanBenchmark(command, cfg.Target), os.Stdout)
+		return nil
+	}
+
+	if cfg.Stats {
+		scans, err := LoadScanHistory()
+		if err != nil {
+			return fmt.Errorf("loading scan history: %v", err)
+		}
+		PrintStatistics(Statistics(scans), os.Stdout)
+		return nil
+	}
+
+	if cfg.Analyse {
+		scans, err := LoadScanHistory()
+		if err != nil {
+			return fmt.Errorf("loading scan history: %v", err)
+		}
+		PrintPortFrequency(PortFrequencyAnalysis(scans), os.Stdout)
+		return nil
+	}
+
+	if cfg.Trend != "" {
+		if err := ValidateTrendMetric(cfg.Trend); err != nil {
+			return &ExitError{Code: 1, Err: err}
+		}
+		scans, err := LoadScanHistory()
+		if err != nil {
+			return fmt.Errorf("loading scan history: %v", err)
+		}
+		if len(scans) == 0 {
+			fmt.Println("No scan history to chart.")
+			return nil
+		}
+		fmt.Print(TrendChart(scans, cfg.Trend))
+		return nil
+	}
+
+	if cfg.Cluster > 0 {
+		scan, err := LoadPreviousScan(a.Store)
+		if err != nil {
+			return fmt.Errorf("loading the last scan: %v", err)
+		}
+		PrintClusters(ClusterHosts(scan, cfg.Cluster), os.Stdout)
+		return nil
+	}
+
+	if cfg.Dashboard != "" {
+		if err := WriteOpenSearchDashboard(cfg.Dashboard); err != nil {
+			return err
+		}
+		fmt.Println("Dashboard written to", cfg.Dashboard)
+		return nil
+	}
+
+	if cfg.Serve != "" {
+		return RunServer(cfg.Serve, cfg.AccessControl)
+	}
+
+	if cfg.ExportICal {
+		return RunICalExport(cfg, a.Args)
+	}
+
+	// Warn early if the installed nmap is too old for the features in use,
+	// and stop with install instructions if it is missing altogether
+	if command, err := ResolveCommand(cfg); err == nil {
+		var notFound *NmapNotFoundError
+		if _, err := CheckNmapVersion(RequiredNmapVersion(command)); errors.As(err, &notFound) {
+			a.Logger.Error("%v", err)
+			fmt.Print(NmapInstallGuide())
+			return nil
+		} else if err != nil {
+			a.Logger.Warn("%v", err)
+		}
+	}
+
+	reloader := a.reloader
+	if reloader == nil {
+		reloader = &configReloader{}
+	}
+	if cfg.Daemon {
+		return RunDaemon(cfg, reloader)
+	}
+
+	if !cfg.Watch {
+		_, err := a.RunOnce(ctx)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	if a.reloader != nil {
+		go a.reloader.Watch(ctx)
+	}
+	if digest, ok := NewDigestNotifier(cfg); ok {
+		go digest.Run(ctx)
+	}
+
+	watcher := &AdaptiveWatcher{
+		MinInterval:   cfg.Interval,
+		MaxInterval:   cfg.MaxInterval,
+		BackoffFactor: cfg.BackoffFactor,
+		OnFailureAlert: func(failures int, err error) {
+			notifyScanFailures(a.Logger, a.Notifiers, a.Config.ChangeWindows, a.Config.Target, failures, err)
+		},
+	}
+	reports := &ScheduledReports{Every: cfg.ReportEvery, Keep: cfg.ReportsKept}
+	watcher.Run(ctx, func() (bool, error) {
+		a.reload()
+		cfg := a.Config
+		watcher.MinInterval, watcher.MaxInterval, watcher.BackoffFactor = cfg.Interval, cfg.MaxInterval, cfg.BackoffFactor
+		reports.Every, reports.Keep = cfg.ReportEvery, cfg.ReportsKept
+		changed, err := a.RunOnce(ctx)
+		if ctx.Err() != nil {
+			err = nil // Stopping, not failing
+		}
+		reports.AfterScan()
+		return changed, err
+	})
+	return nil
+}
+
+// reload switches to a newly reloaded configuration, if there is one, along
+// with the dependencies it configures
+func (a *App) reload() {
+	if a.reloader == nil {
+		return
+	}
+	cfg, ok := a.reloader.Next(a.Config)
+	if !ok {
+		return
+	}
+	next, err := NewApp(cfg)
+	if err != nil {
+		a.Logger.Error("reloading configuration, keeping the current one: %v", err)
+		return
+	}
+	a.Config, a.Store, a.Notifiers, a.Logger = next.Config, next.Store, next.Notifiers, next.Logger
+	a.DataDir, a.Exclusions = next.DataDir, next.Exclusions
+}
+
+// RunOnce performs a single scan, compares it with the previous one and saves it.
+// It reports whether any changes were detected. If ctx is cancelled while the
+// scans are compared, the new scan is not saved.
+func (a *App) RunOnce(ctx context.Context) (bool, error) {
+	cfg := a.Config
+	command, err := ResolveCommand(cfg)
+	if err != nil {
+		return false, err
+	}
+
+	var raw *RawArchive
+	if cfg.ArchiveRaw && cfg.Batch > 0 {
+		a.Logger.Warn("-archive-raw is not supported with -batch, raw output will not be kept")
+	} else if cfg.ArchiveRaw {
+		if raw, err = NewRawArchive(a.DataDir); err != nil {
+			return false, err
+		}
+	}
+
+	target := cfg.Target
+	if cfg.DiscoverFirst {
+		hosts, err := DiscoverLocalHosts(cfg.DiscoverIface, discoverTimeout)
+		if err != nil {
+			raw.Discard()
+			return false, fmt.Errorf("discovering local hosts: %w", err)
+		}
+		if len(hosts) == 0 {
+			raw.Discard()
+			return false, errors.New("discovery found no live hosts")
+		}
+		fmt.Printf("Discovered %d live hosts\n", len(hosts))
+		target = strings.Join(hosts, " ")
+	}
+
+	a.Logger.Info("Scanning %s", target)
+	a.Logger.Debug("Running: %s %s", command, target)
+	started := time.Now()
+	var scan ScanResult
+	if cfg.Batch > 0 {
+		results, batchErr := RunScanBatch(command, strings.Fields(target), cfg.Batch, ScanOptions{Quiet: true, Exclusions: a.exclusions(), Logger: a.Logger})
+		if len(results) == 0 {
+			return false, batchErr
+		}
+		if batchErr != nil {
+			a.Logger.Warn("some targets failed: %v", batchErr)
+		}
+		scan = MergeScans(results...)
+		PrintBatchMetrics(CollectBatchMetrics(results, time.Since(started)), os.Stdout)
+		if cfg.Verbosity >= VerbositySummary {
+			PrintSlowestHosts(scan.PerHostTiming, 10, os.Stdout)
+		}
+	} else if scan, err = RunScanWithOptions(command, target, ScanOptions{
+		RawOutput:  raw.Writer(),
+		Tail:       cfg.Tail || cfg.Verbosity >= VerbosityHost,
+		Exclusions: a.exclusions(),
+		Logger:     a.Logger,
+	}); err != nil {
+		raw.Discard()
+		return false, err
+	}
+	scan.Target = target
+	resolveTargetTTLs(&scan, target)
+	if cfg.DedupByFingerprint {
+		var dropped []string
+		if scan, dropped = DeduplicateByFingerprint(scan); len(dropped) > 0 {
+			fmt.Printf("Skipped %d hosts with the same open ports as another: %s\n", len(dropped), strings.Join(dropped, ", "))
+		}
+	}
+	if cfg.CMDB.Enabled() {
+		if err := EnrichParallel(&scan, []Enricher{CMDBEnricher{Client: cfg.CMDB}}, cmdbConcurrency); err != nil {
+			a.Logger.Warn("CMDB lookup failed: %v", err)
+		}
+	}
+	if raw != nil {
+		if path, err := raw.Save(scan.DateTime); err != nil {
+			a.Logger.Error("archiving raw output: %v", err)
+		} else {
+			fmt.Println("Raw nmap output archived to", path)
+		}
+	}
+	if cfg.Sound && time.Since(started) >= cfg.SoundThreshold {
+		PlayCompletionSound() // Best effort only
+	}
+
+	a.Logger.Info("Scan finished in %s: %d hosts up", formatElapsedTime(time.Since(started), true), len(scan.Ports))
+	for ip, ports := range scan.Ports {
+		a.Logger.Info("  %s: %d ports", ip, len(ports))
+		for _, port := range ports {
+			a.Logger.Debug("    %s", port)
+		}
+	}
+
+	var report DiffReport
+	prevScan, err := LoadPreviousScan(a.Store)
+	if err == nil {
+		if cfg.DoubleCheck {
+			DoubleCheckChanges(prevScan, &scan, command)
+		}
+		// Below -min-changes the diff is treated as noise: no report, no notifications
+		quiet := false
+		if cfg.MinChanges > 0 {
+			if n := BuildDiffReport(prevScan, scan).ChangeCount(); n > 0 && n < cfg.MinChanges {
+				a.Logger.Info("%d changes below threshold, not reporting", n)
+				quiet = true
+			}
+		}
+		var patch bytes.Buffer
+		switch {
+		case quiet:
+		case cfg.Format == "json-patch":
+			report = BuildDiffReport(prevScan, scan)
+			if err := WriteDiffAsJSONPatch(prevScan, scan, io.MultiWriter(os.Stdout, &patch)); err != nil {
+				a.Logger.Error("writing JSON patch: %v", err)
+			}
+		default:
+			var diffErr error
+			if report, diffErr = CompareScansDiff(ctx, prevScan, scan); diffErr != nil {
+				if ctx.Err() != nil {
+					return false, diffErr // Shutting down: leave the baseline as it was
+				}
+				a.Logger.Error("%v", diffErr)
+			}
+		}
+		if cfg.Clipboard && !quiet {
+			text := report.Text()
+			if cfg.Format == "json-patch" {
+				text = patch.String()
+			}
+			if err := CopyToClipboard(text); err != nil {
+				a.Logger.Warn("not copied to clipboard: %v", err)
+			} else {
+				fmt.Println("Diff copied to clipboard.")
+			}
+		}
+		report.Target = cfg.Target
+		if report.Target == "" {
+			report.Target = target
+		}
+	} else {
+		a.Logger.Info("No previous scan data found.")
+		writeUnauthorisedPorts(os.Stdout, unauthorisedPorts(scan)) // Nothing to diff, so check everything that is open
+	}
+	changed := report.HasChanges()
+
+	// A scan the analyst rejects is dropped entirely, notifications included
+	if cfg.ConfirmSave {
+		if !IsTerminal(os.Stdin) {
+			a.Logger.Warn("-interactive-save needs a terminal, saving without asking")
+		} else if save, err := ConfirmSaveScan(scan, report, os.Stdin, os.Stdout); err != nil || !save {
+			fmt.Println("Scan discarded, the saved baseline is unchanged.")
+			return false, err
+		}
+	}
+	if changed {
+		a.notify(report, scan)
+	}
+	SummariseChangeWindows(cfg.ChangeWindows, a.Notifiers, time.Now())
+	if digest, ok := NewDigestNotifier(cfg); ok {
+		if err := digest.SendIfDue(time.Now()); err != nil {
+			a.Logger.Error("sending digest: %v", err)
+		}
+	}
+
+	if err := SaveScan(a.Store, a.DataDir, scan); err != nil {
+		a.Logger.Error("saving scan: %v", err)
+	}
+	fmt.Println("Scan completed and saved.")
+
+	if cfg.OpenSearch.Enabled() {
+		if err := cfg.OpenSearch.Export(scan); err != nil {
+			a.Logger.Error("exporting to OpenSearch: %v", err)
+		}
+	}
+
+	if err := WriteOutputs(cfg, scan, report); err != nil {
+		a.Logger.Error("%v", err)
+	}
+	if cfg.AnsibleInventory != "" {
+		if err := writeAnsibleInventory(cfg.AnsibleInventory, cfg.AnsibleGroupBy, prevScan, scan); err != nil {
+			a.Logger.Error("writing Ansible inventory: %v", err)
+		}
+	}
+
+	if cfg.Interactive && IsTerminal(os.Stdin) {
+		session := scan
+		if err := RunInteractiveRescan(&session, cfg.DeepCommand, os.Stdin, os.Stdout); err != nil {
+			a.Logger.Error("%v", err)
+		}
+	}
+	return changed, nil
+}
+
+// exclusions returns the App's exclusion list, never nil, so an App without
+// one scans everything rather than falling back to the -exclude-file list
+func (a *App) exclusions() []string {
+	if a.Exclusions == nil {
+		return []string{}
+	}
+	return a.Exclusions
+}
+
+// RunOnce performs a single scan with the dependencies configured by cfg
+func RunOnce(ctx context.Context, cfg Config) (bool, error) {
+	app, err := NewApp(cfg)
+	if err != nil {
+		return false, err
+	}
+	return app.RunOnce(ctx)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// memStore is a Store that keeps scans in memory
+type memStore struct {
+	scans []ScanResult
+}
+
+func (s *memStore) Save(scan ScanResult) error {
+	s.scans = append(s.scans, scan)
+	return nil
+}
+
+func (s *memStore) Latest() (ScanResult, error) {
+	if len(s.scans) == 0 {
+		return ScanResult{}, os.ErrNotExist
+	}
+	return s.scans[len(s.scans)-1], nil
+}
+
+func (s *memStore) List() ([]string, error) {
+	keys := make([]string, len(s.scans))
+	for i, scan := range s.scans {
+		keys[i] = scan.DateTime
+	}
+	return keys, nil
+}
+
+func (s *memStore) Load(key string) (ScanResult, error) {
+	for _, scan := range s.scans {
+		if scan.DateTime == key {
+			return scan, nil
+		}
+	}
+	return ScanResult{}, os.ErrNotExist
+}
+
+// recordingNotifier keeps the subject of every notification sent
+type recordingNotifier struct {
+	subjects []string
+	bodies   []string
+}
+
+func (n *recordingNotifier) Send(subject, body string) error {
+	n.subjects = append(n.subjects, subject)
+	n.bodies = append(n.bodies, body)
+	return nil
+}
+
+// fakeNmap puts an "nmap" on PATH that prints the testdata fixture, so the
+// whole scan path runs without a real scan
+func fakeNmap(t *testing.T, fixture string) {
+	t.Helper()
+	installFakeNmap(t, fakeNmapRun{fixture: fixture})
+}
+
+// fakeNmapRun is what the fake nmap installed by installFakeNmap does for a scan
+type fakeNmapRun struct {
+	fixture  string // testdata file printed on stdout, if set
+	stderr   string // Printed on stderr after the fixture
+	exitCode int
+	// If set, only the fixture's first line is printed until this file
+	// exists, for watching output arrive while nmap runs
+	release string
+}
+
+// installFakeNmap puts an "nmap" on PATH that reports version 7.94 for
+// --version and otherwise behaves as run describes
+func installFakeNmap(t *testing.T, run fakeNmapRun) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake nmap is a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = --version ]; then echo 'Nmap version 7.94 ( https://nmap.org )'; exit 0; fi\n"
+	if run.fixture != "" {
+		output, err := filepath.Abs(filepath.Join("testdata", run.fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.release != "" {
+			script += "head -n 1 '" + output + "'\n" +
+				"while [ ! -e '" + run.release + "' ]; do sleep 0.05; done\n" +
+				"tail -n +2 '" + output + "'\n"
+		} else {
+			script += "cat '" + output + "'\n"
+		}
+	}
+	if run.stderr != "" {
+		stderr := filepath.Join(dir, "stderr")
+		if err := os.WriteFile(stderr, []byte(run.stderr), 0644); err != nil {
+			t.Fatal(err)
+		}
+		script += "cat '" + stderr + "' >&2\n"
+	}
+	script += fmt.Sprintf("exit %d\n", run.exitCode)
+	if err := os.WriteFile(filepath.Join(dir, "nmap"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// useTempScanFolder points the package-wide data folder, which code outside
+// App still uses, at a temporary directory for the test
+func useTempScanFolder(t *testing.T) {
+	saved := scanFolder
+	scanFolder = t.TempDir()
+	t.Cleanup(func() { scanFolder = saved })
+}
+
+func TestAppRunOnceWithMocks(t *testing.T) {
+	fakeNmap(t, "multiple_hosts.txt")
+
+	previous := ScanResult{
+		Version:  currentScanVersion,
+		DateTime: "2024-05-01T10:00:00Z",
+		Ports: map[string][]string{
+			"10.0.0.1":  {"53/tcp [open] (domain)", "80/tcp [open] (http)"},
+			"10.0.0.12": {"3306/tcp [open] (mysql)"},
+		},
+	}
+	store := &memStore{scans: []ScanResult{previous}}
+	notifier := &recordingNotifier{}
+
+	cfg := DefaultConfig()
+	cfg.Command = "nmap -sT"
+	cfg.Target = "10.0.0.0/24"
+	app := &App{Config: cfg, Store: store, Notifiers: []Notifier{notifier}, Logger: logger, DataDir: t.TempDir()}
+
+	changed, err := app.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("RunOnce reported no changes")
+	}
+
+	if len(store.scans) != 2 {
+		t.Fatalf("store has %d scans, want the new one saved after the previous", len(store.scans))
+	}
+	saved := store.scans[1]
+	if saved.Target != "10.0.0.0/24" || len(saved.Ports) != 3 || saved.ScannerVersion != "7.94" {
+		t.Errorf("saved scan: target %q, %d hosts, nmap %q", saved.Target, len(saved.Ports), saved.ScannerVersion)
+	}
+
+	if len(notifier.subjects) != 1 {
+		t.Fatalf("notifier got %d notifications, want 1", len(notifier.subjects))
+	}
+	if notifier.subjects[0] != "PortHunter: 4 ports added, 0 removed" {
+		t.Errorf("subject %q", notifier.subjects[0])
+	}
+	for _, want := range []string{"443/tcp [open] (https)", "10.0.0.40"} {
+		if !strings.Contains(notifier.bodies[0], want) {
+			t.Errorf("notification does not mention %s:\n%s", want, notifier.bodies[0])
+		}
+	}
+}
+
+func TestAppRunOnceFirstScan(t *testing.T) {
+	fakeNmap(t, "tcp_default.txt")
+
+	store := &memStore{}
+	notifier := &recordingNotifier{}
+	cfg := DefaultConfig()
+	cfg.Command = "nmap -sT"
+	cfg.Target = "192.168.1.10"
+	dir := t.TempDir()
+	app := &App{Config: cfg, Store: store, Notifiers: []Notifier{notifier}, Logger: logger, DataDir: dir}
+
+	changed, err := app.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || len(notifier.subjects) != 0 {
+		t.Errorf("first scan: changed %v, %d notifications; nothing to compare with", changed, len(notifier.subjects))
+	}
+	if len(store.scans) != 1 {
+		t.Errorf("store has %d scans, want 1", len(store.scans))
+	}
+	if index, err := LoadHistoryIndex(dir); err != nil || index.Hosts["192.168.1.10"].State != HostUp {
+		t.Errorf("host index in the App's data folder: %+v, %v", index, err)
+	}
+}
+
+func TestAppRunOnceExclusions(t *testing.T) {
+	fakeNmap(t, "tcp_default.txt")
+
+	store := &memStore{}
+	cfg := DefaultConfig()
+	cfg.Command = "nmap -sT"
+	cfg.Target = "192.168.1.10"
+	app := &App{Config: cfg, Store: store, Logger: logger, DataDir: t.TempDir(), Exclusions: []string{"192.168.1.0/24"}}
+
+	_, err := app.RunOnce(context.Background())
+	var skip *ExcludedTargetError
+	if !errors.As(err, &skip) || skip.Rule != "192.168.1.0/24" {
+		t.Errorf("got %v, want the target excluded by the App's list", err)
+	}
+	if len(store.scans) != 0 {
+		t.Error("an excluded target was scanned and saved")
+	}
+}
+
+func TestRunScanBatchAllTargetsExcluded(t *testing.T) {
+	fakeNmap(t, "tcp_default.txt")
+	opts := ScanOptions{Quiet: true, Logger: logger, Exclusions: []string{"192.168.1.0/24"}}
+
+	results, err := RunScanBatch("nmap -sT", []string{"192.168.1.10", "192.168.1.11"}, 2, opts)
+	if !errors.Is(err, ErrAllTargetsExcluded) || len(results) != 0 {
+		t.Errorf("got %d results and %v, want ErrAllTargetsExcluded", len(results), err)
+	}
+
+	results, err = RunScanBatch("nmap -sT", []string{"192.168.1.10", "10.0.0.1"}, 2, opts)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("got %d results and %v, want the one allowed target scanned", len(results), err)
+	}
+	if got := results[0].ExcludedTargets; len(got) != 1 || got[0] != "192.168.1.10" {
+		t.Errorf("ExcludedTargets = %v, want [192.168.1.10]", got)
+	}
+
+	// RunOnce reports the batch as failed rather than succeeding with no scan
+	store := &memStore{}
+	cfg := DefaultConfig()
+	cfg.Command = "nmap -sT"
+	cfg.Target = "192.168.1.10 192.168.1.11"
+	cfg.Batch = 2
+	app := &App{Config: cfg, Store: store, Logger: logger, DataDir: t.TempDir(), Exclusions: opts.Exclusions}
+	if _, err := app.RunOnce(context.Background()); !errors.Is(err, ErrAllTargetsExcluded) {
+		t.Errorf("RunOnce returned %v, want ErrAllTargetsExcluded", err)
+	}
+	if len(store.scans) != 0 {
+		t.Error("a scan was saved when every target was excluded")
+	}
+}
+
+func TestAppRunOnceInvalidTarget(t *testing.T) {
+	store := &memStore{}
+	cfg := DefaultConfig()
+	cfg.Command = "nmap -sT"
+	cfg.Target = "10.0.0.1; rm -rf /"
+	app := &App{Config: cfg, Store: store, Logger: logger, DataDir: t.TempDir()}
+
+	_, err := app.RunOnce(context.Background())
+	var verr *TargetValidationError
+	if !errors.As(err, &verr) {
+		t.Errorf("got %v, want a TargetValidationError", err)
+	}
+	if len(store.scans) != 0 {
+		t.Error("a scan was saved after a failed run")
+	}
+}
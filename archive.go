@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// rawArchiveSuffix names archived raw nmap output: scan_data/<datetime>_raw.txt
+const rawArchiveSuffix = "_raw.txt"
+
+// RawArchive captures nmap's raw stdout into scan_data for later inspection
+// or replay. Output is streamed to a temporary file and renamed once the scan
+// time is known, so large scans are never buffered in memory.
+type RawArchive struct {
+	file *os.File
+	dir  string
+}
+
+// NewRawArchive creates the temporary archive file in the data folder dir
+func NewRawArchive(dir string) (*RawArchive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, ".raw-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	f.Chmod(0644) // Match the permissions of the JSON scan files
+	return &RawArchive{file: f, dir: dir}, nil
+}
+
+// Writer returns the destination for raw output, or nil when not archiving
+func (a *RawArchive) Writer() io.Writer {
+	if a == nil {
+		return nil
+	}
+	return a.file
+}
+
+// Save moves the captured output to its final name for the given scan time
+func (a *RawArchive) Save(datetime string) (string, error) {
+	if err := a.file.Close(); err != nil {
+		return "", err
+	}
+	path := RawArchivePath(a.dir, datetime)
+	if err := os.Rename(a.file.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Discard removes the temporary file after a failed scan
+func (a *RawArchive) Discard() {
+	if a == nil {
+		return
+	}
+	a.file.Close()
+	os.Remove(a.file.Name())
+}
+
+// RawArchivePath is where the raw output of the scan at datetime is stored in dir
+func RawArchivePath(dir, datetime string) string {
+	return filepath.Join(dir, fileTimestamp(datetime)+rawArchiveSuffix)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAllTargetsExcluded is returned by RunScanBatch when every target it was
+// given is on the exclusion list
+var ErrAllTargetsExcluded = errors.New("every target is excluded")
+
+// RunScanBatch scans each target in its own nmap process, at most
+// concurrency at a time. Results are returned in target order with Target and
+// PerHostTiming filled in; targets that fail are left out and their errors
+// joined. Every host found by a target is timed with that target's nmap run,
+// so targets that are single hosts give exact per-host timings. Excluded
+// targets are skipped with a warning, and ErrAllTargetsExcluded is returned
+// when that leaves nothing to scan.
+func RunScanBatch(command string, targets []string, concurrency int, opts ScanOptions) ([]ScanResult, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var allowed, excluded []string
+	for _, target := range targets {
+		var skip *ExcludedTargetError
+		if err := validateTarget(target, opts.exclusions()); errors.As(err, &skip) {
+			opts.logger().Warn("skipping %s: excluded by %q", target, skip.Rule)
+			excluded = append(excluded, target)
+		} else {
+			allowed = append(allowed, target) // Other errors are reported by the scan itself
+		}
+	}
+	if len(allowed) == 0 && len(excluded) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrAllTargetsExcluded, strings.Join(excluded, ", "))
+	}
+	targets = allowed
+
+	results := make([]ScanResult, len(targets))
+	errs := make([]error, len(targets))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			started := time.Now()
+			result, err := RunScanWithOptions(command, target, opts)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", target, err)
+				return
+			}
+			elapsed := time.Since(started)
+			result.Target = target
+			result.ExcludedTargets = append(result.ExcludedTargets, excluded...)
+			result.PerHostTiming = make(map[string]time.Duration, len(result.Ports))
+			for ip := range result.Ports {
+				result.PerHostTiming[ip] = elapsed
+			}
+			if len(result.Ports) == 0 {
+				result.PerHostTiming[target] = elapsed // Nothing answered, but the time still counts
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	done := make([]ScanResult, 0, len(results))
+	for i, result := range results {
+		if errs[i] == nil {
+			done = append(done, result)
+		}
+	}
+	return done, errors.Join(errs...)
+}
+
+// BatchMetrics summarises a batch scan for tuning timing and concurrency
+type BatchMetrics struct {
+	Scans          int
+	Hosts          int
+	Ports          int
+	States         map[string]int // Port count per state, e.g. "open", "filtered"
+	Duration       time.Duration  // Wall-clock time for the whole batch
+	AverageHost    time.Duration  // Mean scan time per host
+	SlowestHost    string
+	SlowestElapsed time.Duration
+}
+
+// CollectBatchMetrics aggregates the results of RunScanBatch
+func CollectBatchMetrics(results []ScanResult, duration time.Duration) BatchMetrics {
+	m := BatchMetrics{Scans: len(results), States: make(map[string]int), Duration: duration}
+	var total time.Duration
+	var timed int
+	for _, result := range results {
+		m.Hosts += len(result.Ports)
+		for _, ports := range result.Ports {
+			m.Ports += len(ports)
+			for _, entry := range ports {
+				_, _, state, _ := splitPortEntry(entry)
+				m.States[state]++
+			}
+		}
+
+		for host, elapsed := range result.PerHostTiming {
+			total += elapsed
+			timed++
+			if elapsed > m.SlowestElapsed {
+				m.SlowestHost, m.SlowestElapsed = host, elapsed
+			}
+		}
+	}
+	if timed > 0 {
+		m.AverageHost = total / time.Duration(timed)
+	}
+	return m
+}
+
+// PrintBatchMetrics writes the metrics block shown after a batch scan
+func PrintBatchMetrics(m BatchMetrics, w io.Writer) {
+	fmt.Fprintln(w, "\n--- Batch Metrics ---")
+	fmt.Fprintf(w, "Targets scanned:   %d\n", m.Scans)
+	fmt.Fprintf(w, "Hosts up:          %d\n", m.Hosts)
+	fmt.Fprintf(w, "Ports found:       %d\n", m.Ports)
+
+	// open/closed/filtered first, then anything else nmap reported
+	states := []string{"open", "closed", "filtered"}
+	var others []string
+	for state := range m.States {
+		if state != "open" && state != "closed" && state != "filtered" {
+			others = append(others, state)
+		}
+	}
+	sort.Strings(others)
+	for _, state := range append(states, others...) {
+		fmt.Fprintf(w, "  %-16s %d\n", state+":", m.States[state])
+	}
+
+	fmt.Fprintf(w, "Scan duration:     %s\n", formatElapsedTime(m.Duration, true))
+	fmt.Fprintf(w, "Average per host:  %s\n", m.AverageHost.Round(time.Millisecond))
+	if m.SlowestHost != "" {
+		fmt.Fprintf(w, "Slowest host:      %s (%s)\n", m.SlowestHost, m.SlowestElapsed.Round(time.Millisecond))
+	}
+}
+
+// PrintSlowestHosts lists up to n hosts from timing, slowest first
+func PrintSlowestHosts(timing map[string]time.Duration, n int, w io.Writer) {
+	if len(timing) == 0 {
+		return
+	}
+	hosts := sortedHostKeys(timing)
+	sort.SliceStable(hosts, func(i, j int) bool { return timing[hosts[i]] > timing[hosts[j]] })
+	if len(hosts) > n {
+		hosts = hosts[:n]
+	}
+
+	fmt.Fprintf(w, "\nSlowest %d hosts:\n", len(hosts))
+	for _, host := range hosts {
+		fmt.Fprintf(w, "  %-40s %s\n", host, timing[host].Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tcpScanTypes are the nmap flags selecting how TCP ports are probed;
+// -benchmark-scan replaces any of these in the command
+var tcpScanTypes = map[string]bool{"-sS": true, "-sT": true, "-sA": true, "-sW": true, "-sM": true, "-sN": true, "-sF": true, "-sX": true}
+
+// BenchmarkRun is one of the scans made by -benchmark-scan
+type BenchmarkRun struct {
+	Name     string // e.g. "SYN (-sS)"
+	Command  string
+	Duration time.Duration
+	Result   ScanResult
+	Err      error
+}
+
+// ScanBenchmark compares a SYN scan with a TCP connect scan of the same target
+type ScanBenchmark struct {
+	Target  string
+	SYN     BenchmarkRun
+	Connect BenchmarkRun
+}
+
+// withScanType returns command with its TCP scan type replaced by scanType
+func withScanType(command, scanType string) string {
+	fields := strings.Fields(command)
+	args := []string{fields[0], scanType}
+	for _, f := range fields[1:] {
+		if !tcpScanTypes[f] {
+			args = append(args, f)
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+// RunScanBenchmark scans target with a SYN scan (which needs root) and then a
+// TCP connect scan, one after the other so they don't compete for bandwidth.
+// A failing scan is recorded in its run rather than stopping the other.
+func RunScanBenchmark(command, target string) ScanBenchmark {
+	b := ScanBenchmark{
+		Target:  target,
+		SYN:     BenchmarkRun{Name: "SYN (-sS)", Command: withScanType(command, "-sS")},
+		Connect: BenchmarkRun{Name: "Connect (-sT)", Command: withScanType(command, "-sT")},
+	}
+	for _, run := range []*BenchmarkRun{&b.SYN, &b.Connect} {
+		logger.Info("Running: %s %s", run.Command, target)
+		started := time.Now()
+		run.Result, run.Err = RunScanWithOptions(run.Command, target, ScanOptions{Quiet: true})
+		run.Duration = time.Since(started)
+	}
+	return b
+}
+
+// openPorts returns the scan's open ports as "ip port/proto" keys
+func openPorts(scan ScanResult) map[string]string {
+	open := make(map[string]string)
+	for ip, entries := range scan.Ports {
+		for _, entry := range entries {
+			if p := ParsePortEntry(entry); p.State == "open" {
+				open[fmt.Sprintf("%s %d/%s", ip, p.Port, p.Protocol)] = ip + ": " + entry
+			}
+		}
+	}
+	return open
+}
+
+// onlyIn lists the open ports in a that are not open in b, sorted
+func onlyIn(a, b map[string]string) []string {
+	var only []string
+	for key, entry := range a {
+		if _, ok := b[key]; !ok {
+			only = append(only, entry)
+		}
+	}
+	sort.Strings(only)
+	return only
+}
+
+// PrintScanBenchmark writes a table comparing the two scans and lists the
+// open ports only one of them found
+func PrintScanBenchmark(b ScanBenchmark, w io.Writer) {
+	fmt.Fprintf(w, "\n--- Scan Benchmark: %s ---\n\n", b.Target)
+	fmt.Fprintf(w, "  %-15s %-10s %-7s %s\n", "Scan", "Duration", "Hosts", "Open ports")
+	for _, run := range []BenchmarkRun{b.SYN, b.Connect} {
+		if run.Err != nil {
+			fmt.Fprintf(w, "  %-15s failed: %v\n", run.Name, run.Err)
+			continue
+		}
+		fmt.Fprintf(w, "  %-15s %-10s %-7d %d\n", run.Name, run.Duration.Round(10*time.Millisecond), len(run.Result.Ports), len(openPorts(run.Result)))
+	}
+
+	if b.SYN.Err != nil || b.Connect.Err != nil {
+		if b.SYN.Err != nil {
+			fmt.Fprintln(w, "\nThe SYN scan needs root (or Administrator with Npcap on Windows).")
+		}
+		return
+	}
+
+	if b.SYN.Duration > 0 {
+		fmt.Fprintf(w, "\nThe connect scan took %.1fx as long as the SYN scan.\n", b.Connect.Duration.Seconds()/b.SYN.Duration.Seconds())
+	}
+	syn, connect := openPorts(b.SYN.Result), openPorts(b.Connect.Result)
+	for _, diff := range []struct {
+		label string
+		ports []string
+	}{
+		{"Only found by the SYN scan", onlyIn(syn, connect)},
+		{"Only found by the connect scan", onlyIn(connect, syn)},
+	} {
+		if len(diff.ports) == 0 {
+			fmt.Fprintf(w, "%s: none\n", diff.label)
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", diff.label)
+		for _, entry := range diff.ports {
+			fmt.Fprintf(w, "  %s\n", entry)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// campaignFolder holds one <slug>.json state file per campaign, plus a
+// <slug>/ directory with each job's scan result
+func campaignFolder() string { return filepath.Join(scanFolder, "campaigns") }
+
+// Job states
+const (
+	JobPending = "pending"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// ScanJob is one scan within a campaign
+type ScanJob struct {
+	ID          int       `json:"id"`
+	Command     string    `json:"command"`
+	Target      string    `json:"target"`
+	Status      string    `json:"status"`
+	StartedAt   time.Time `json:"started_at,omitempty"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Campaign is a named set of scan jobs run over one or more sessions, e.g. a
+// multi-day assessment. Jobs that have not completed are picked up by the next run.
+type Campaign struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Jobs        []ScanJob `json:"jobs"`
+	StartedAt   time.Time `json:"started_at,omitempty"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+}
+
+var slugRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// campaignSlug turns a campaign name into a file name, e.g. "Q1 Assessment" -> "q1-assessment"
+func campaignSlug(name string) string {
+	return strings.Trim(slugRe.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+func campaignPath(name string) string {
+	return filepath.Join(campaignFolder(), campaignSlug(name)+".json")
+}
+
+func campaignJobPath(name string, id int) string {
+	return filepath.Join(campaignFolder(), campaignSlug(name), fmt.Sprintf("job-%d.json", id))
+}
+
+// LoadCampaign reads a campaign's saved state
+func LoadCampaign(name string) (Campaign, error) {
+	data, err := os.ReadFile(campaignPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return Campaign{}, fmt.Errorf("no campaign named %q", name)
+	} else if err != nil {
+		return Campaign{}, err
+	}
+	var c Campaign
+	return c, json.Unmarshal(data, &c)
+}
+
+// SaveCampaign writes a campaign's state
+func SaveCampaign(c Campaign) error {
+	if err := os.MkdirAll(campaignFolder(), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(campaignPath(c.Name), data, 0644)
+}
+
+// CreateCampaign starts a new, empty campaign
+func CreateCampaign(name, description string) (Campaign, error) {
+	if campaignSlug(name) == "" {
+		return Campaign{}, errors.New("campaign name cannot be empty")
+	}
+	if _, err := os.Stat(campaignPath(name)); err == nil {
+		return Campaign{}, fmt.Errorf("campaign %q already exists", name)
+	}
+	c := Campaign{Name: name, Description: description, Jobs: []ScanJob{}}
+	return c, SaveCampaign(c)
+}
+
+// AddJob appends a validated scan job to the campaign
+func (c *Campaign) AddJob(command, target string) (ScanJob, error) {
+	if err := ValidateNmapCommand(command); err != nil {
+		return ScanJob{}, err
+	}
+	if err := ValidateTarget(target); err != nil {
+		return ScanJob{}, err
+	}
+	job := ScanJob{ID: len(c.Jobs) + 1, Command: command, Target: target, Status: JobPending}
+	c.Jobs = append(c.Jobs, job)
+	c.CompletedAt = time.Time{} // A new job reopens a finished campaign
+	return job, nil
+}
+
+// Run executes every job that has not completed, saving the campaign after
+// each one so an interrupted run can be resumed
+func (c *Campaign) Run(out io.Writer) error {
+	if c.StartedAt.IsZero() {
+		c.StartedAt = time.Now()
+	}
+	for i := range c.Jobs {
+		job := &c.Jobs[i]
+		if job.Status == JobDone {
+			continue
+		}
+
+		fmt.Fprintf(out, "[%d/%d] %s %s\n", job.ID, len(c.Jobs), job.Command, job.Target)
+		job.StartedAt, job.Error = time.Now(), ""
+		result, err := RunScan(job.Command, job.Target)
+		if err == nil {
+			err = writeCampaignResult(c.Name, job.ID, result)
+		}
+		job.CompletedAt = time.Now()
+		if err != nil {
+			job.Status, job.Error = JobFailed, err.Error()
+			fmt.Fprintf(out, "  failed: %v\n", err)
+			var nmapErr *NmapError
+			if errors.As(err, &nmapErr) {
+				fmt.Fprintf(out, "  hint: %s\n", nmapErr.Hint)
+			}
+		} else {
+			job.Status = JobDone
+			fmt.Fprintf(out, "  done: %d hosts up\n", len(result.Ports))
+		}
+		if err := SaveCampaign(*c); err != nil {
+			return err
+		}
+	}
+
+	for _, job := range c.Jobs {
+		if job.Status != JobDone {
+			return SaveCampaign(*c)
+		}
+	}
+	c.CompletedAt = time.Now()
+	return SaveCampaign(*c)
+}
+
+func writeCampaignResult(name string, id int, result ScanResult) error {
+	path := campaignJobPath(name, id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := encodeScan(result, path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Report writes a summary of the campaign's jobs and everything they found
+func (c Campaign) Report(w io.Writer) error {
+	fmt.Fprintf(w, "Campaign: %s\n", c.Name)
+	if c.Description != "" {
+		fmt.Fprintf(w, "%s\n", c.Description)
+	}
+	switch {
+	case c.StartedAt.IsZero():
+		fmt.Fprintln(w, "Status: not started")
+	case c.CompletedAt.IsZero():
+		fmt.Fprintf(w, "Status: in progress since %s\n", c.StartedAt.Format(time.RFC1123))
+	default:
+		fmt.Fprintf(w, "Status: completed %s (took %s)\n", c.CompletedAt.Format(time.RFC1123), formatElapsedTime(c.CompletedAt.Sub(c.StartedAt), false))
+	}
+
+	fmt.Fprintf(w, "\n%-4s %-8s %-30s %s\n", "ID", "STATUS", "TARGET", "COMMAND")
+	var results []ScanResult
+	for _, job := range c.Jobs {
+		fmt.Fprintf(w, "%-4d %-8s %-30s %s\n", job.ID, job.Status, job.Target, job.Command)
+		if job.Error != "" {
+			fmt.Fprintf(w, "     %s\n", job.Error)
+		}
+		if job.Status != JobDone {
+			continue
+		}
+		path := campaignJobPath(c.Name, job.ID)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		result, err := decodeScan(data, path)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		return nil
+	}
+
+	merged := MergeScans(results...)
+	fmt.Fprintf(w, "\nFindings (%d hosts up):\n", len(merged.Ports))
+	for _, ip := range sortedHostKeys(merged.Ports) {
+		var open []string
+		for _, entry := range merged.Ports[ip] {
+			if _, _, state, _ := splitPortEntry(entry); state == "open" {
+				open = append(open, entry)
+			}
+		}
+		fmt.Fprintf(w, "  %s: %d open\n", ip, len(open))
+		for _, entry := range open {
+			fmt.Fprintf(w, "    - %s%s\n", entry, sensitiveNote(entry))
+		}
+	}
+	return nil
+}
+
+// runCampaign implements "porthunter campaign create|add-job|run|report"
+func runCampaign(args []string) error {
+	usage := errors.New("usage: porthunter campaign create|add-job|run|report -name <campaign> [flags]")
+	if len(args) == 0 {
+		return usage
+	}
+
+	fs := flag.NewFlagSet("campaign "+args[0], flag.ContinueOnError)
+	name := fs.String("name", "", "Campaign name")
+	var description, command, target *string
+	switch args[0] {
+	case "create":
+		description = fs.String("description", "", "What the campaign covers")
+	case "add-job":
+		command = fs.String("c", "", "Full scan command")
+		target = fs.String("t", "", "Target IP/hostname/CIDR")
+	case "run", "report":
+	default:
+		return usage
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if args[0] == "create" {
+		if _, err := CreateCampaign(*name, *description); err != nil {
+			return err
+		}
+		fmt.Printf("Campaign %q created. Add scans with: porthunter campaign add-job -name %q -c <command> -t <target>\n", *name, *name)
+		return nil
+	}
+
+	c, err := LoadCampaign(*name)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "add-job":
+		job, err := c.AddJob(*command, *target)
+		if err != nil {
+			return err
+		}
+		if err := SaveCampaign(c); err != nil {
+			return err
+		}
+		fmt.Printf("Job %d added to %q\n", job.ID, c.Name)
+		return nil
+	case "run":
+		return c.Run(os.Stdout)
+	default:
+		return c.Report(os.Stdout)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ChangeWindow is a planned maintenance period. Changes found during it are
+// still reported and saved, but notifications are held back and summarised
+// once the window ends.
+type ChangeWindow struct {
+	Start   time.Time `yaml:"start"`
+	End     time.Time `yaml:"end"`
+	Targets []string  `yaml:"targets"` // IPs or CIDRs affected; empty means every target
+	Reason  string    `yaml:"reason"`  // e.g. the change ticket, "CHG-12345"
+}
+
+// ID identifies the window in the change window state file
+func (w ChangeWindow) ID() string {
+	return w.Reason + "@" + w.Start.Format(time.RFC3339)
+}
+
+// Covers reports whether every host changed in report is within the window at t
+func (w ChangeWindow) Covers(report DiffReport, t time.Time) bool {
+	if t.Before(w.Start) || !t.Before(w.End) {
+		return false
+	}
+	for _, host := range changedHosts(report) {
+		if !w.coversHost(host) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w ChangeWindow) coversHost(host string) bool {
+	if len(w.Targets) == 0 {
+		return true
+	}
+	ip := net.ParseIP(host)
+	_, hostNet, cidrErr := net.ParseCIDR(host)
+	for _, target := range w.Targets {
+		if _, network, err := net.ParseCIDR(target); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			// A scanned range, for alerts about a whole target
+			if cidrErr == nil {
+				ones, _ := network.Mask.Size()
+				hostOnes, _ := hostNet.Mask.Size()
+				if network.Contains(hostNet.IP) && hostOnes >= ones {
+					return true
+				}
+			}
+		} else if target == host {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateChangeWindows checks that every window ends after it starts
+func ValidateChangeWindows(windows []ChangeWindow) error {
+	for _, w := range windows {
+		if !w.End.After(w.Start) {
+			return fmt.Errorf("change window %q must end after it starts", w.Reason)
+		}
+	}
+	return nil
+}
+
+// changedHosts returns the hosts with any change in report
+func changedHosts(report DiffReport) []string {
+	var hosts []string
+	for _, h := range report.Hosts {
+		hosts = append(hosts, h.IP)
+	}
+	for _, c := range report.HostStateChanges {
+		hosts = append(hosts, c.IP)
+	}
+	for _, c := range report.MACChanges {
+		hosts = append(hosts, c.IP)
+	}
+	for _, c := range report.RouteChanges {
+		hosts = append(hosts, c.IP)
+	}
+	for _, c := range report.OSChanges {
+		hosts = append(hosts, c.IP)
+	}
+	for _, c := range report.DNSChanges {
+		hosts = append(hosts, c.NewIP)
+	}
+	for _, c := range report.ScriptChanges {
+		hosts = append(hosts, c.Host)
+	}
+	return hosts
+}
+
+// changeWindowsFile counts the changes seen during each window, by window ID
+func changeWindowsFile() string { return filepath.Join(scanFolder, "change_windows.json") }
+
+// changeWindowRecord is what happened during one change window
+type changeWindowRecord struct {
+	Changes    int  `json:"changes"`
+	Alerts     int  `json:"alerts,omitempty"` // Scan failure and watchdog alerts held back
+	Summarised bool `json:"summarised"`
+}
+
+func loadChangeWindowRecords() (map[string]changeWindowRecord, error) {
+	records := make(map[string]changeWindowRecord)
+	data, err := os.ReadFile(changeWindowsFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return records, json.Unmarshal(data, &records)
+}
+
+func saveChangeWindowRecords(records map[string]changeWindowRecord) error {
+	if err := EnsureScanFolderExists(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(changeWindowsFile(), data, 0644)
+}
+
+// suppressingWindow returns the change window covering report now, if any,
+// and records the report's changes against it
+func suppressingWindow(windows []ChangeWindow, report DiffReport, now time.Time) (ChangeWindow, bool) {
+	return holdBack(windows,
+		func(w ChangeWindow) bool { return w.Covers(report, now) },
+		func(record *changeWindowRecord) { record.Changes += report.ChangeCount() })
+}
+
+// suppressingAlertWindow returns the change window covering an alert about
+// target now, if any, and counts the alert against it (see alertWindow)
+func suppressingAlertWindow(windows []ChangeWindow, target string, now time.Time) (ChangeWindow, bool) {
+	return holdBack(windows,
+		func(w ChangeWindow) bool { return w.coversTarget(target, now) },
+		func(record *changeWindowRecord) { record.Alerts++ })
+}
+
+// alertWindow returns the change window covering an alert about target now,
+// if any, without counting it. Every host of target must be in the window; an
+// alert without a target, such as the watchdog's, is only covered by windows
+// for every target.
+func alertWindow(windows []ChangeWindow, target string, now time.Time) (ChangeWindow, bool) {
+	for _, w := range windows {
+		if w.coversTarget(target, now) {
+			return w, true
+		}
+	}
+	return ChangeWindow{}, false
+}
+
+// holdBack returns the first window covers accepts and updates its record
+func holdBack(windows []ChangeWindow, covers func(ChangeWindow) bool, update func(*changeWindowRecord)) (ChangeWindow, bool) {
+	for _, w := range windows {
+		if !covers(w) {
+			continue
+		}
+		recordHeldBack(w, update)
+		return w, true
+	}
+	return ChangeWindow{}, false
+}
+
+// recordHeldBack applies update to the record of window w
+func recordHeldBack(w ChangeWindow, update func(*changeWindowRecord)) {
+	records, err := loadChangeWindowRecords()
+	if err == nil {
+		record := records[w.ID()]
+		update(&record)
+		records[w.ID()] = record
+		err = saveChangeWindowRecords(records)
+	}
+	if err != nil {
+		logger.Error("recording held back notifications for change window %s: %v", w.Reason, err)
+	}
+}
+
+// coversTarget reports whether the window is open at t and includes every
+// host of target, a space-separated list of IPs, CIDRs and hostnames
+func (w ChangeWindow) coversTarget(target string, t time.Time) bool {
+	if t.Before(w.Start) || !t.Before(w.End) {
+		return false
+	}
+	hosts := strings.Fields(target)
+	if len(hosts) == 0 {
+		return len(w.Targets) == 0
+	}
+	for _, host := range hosts {
+		if !w.coversHost(host) {
+			return false
+		}
+	}
+	return true
+}
+
+// changeWindowSummary is the message sent once window has ended
+func changeWindowSummary(w ChangeWindow, record changeWindowRecord) string {
+	summary := fmt.Sprintf("%d changes occurred during maintenance window %s.", record.Changes, w.Reason)
+	if record.Alerts > 0 {
+		summary += fmt.Sprintf(" %d scan alerts were held back.", record.Alerts)
+	}
+	return summary
+}
+
+// SummariseChangeWindows reports, once, how many changes each change window
+// that has ended held back: on stdout and to every notifier except JIRA, as
+// a summary is not worth a ticket of its own
+func SummariseChangeWindows(windows []ChangeWindow, notifiers []Notifier, now time.Time) {
+	if len(windows) == 0 {
+		return
+	}
+	records, err := loadChangeWindowRecords()
+	if err != nil {
+		logger.Error("reading change window records: %v", err)
+		return
+	}
+	updated := false
+	for _, w := range windows {
+		record, ok := records[w.ID()]
+		if !ok || record.Summarised || now.Before(w.End) {
+			continue
+		}
+		summary := changeWindowSummary(w, record)
+		fmt.Println(summary)
+		for _, n := range notifiers {
+			if _, ok := n.(JIRAClient); ok {
+				continue
+			}
+			if err := n.Send("PortHunter: maintenance window "+w.Reason+" ended", summary+"\n"); err != nil {
+				logger.Error("sending change window summary: %v", err)
+			}
+		}
+		record.Summarised = true
+		records[w.ID()] = record
+		updated = true
+	}
+	if updated {
+		if err := saveChangeWindowRecords(records); err != nil {
+			logger.Error("saving change window records: %v", err)
+		}
+	}
+}
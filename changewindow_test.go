@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChangeWindowCoversTarget(t *testing.T) {
+	start := time.Date(2024, 5, 1, 22, 0, 0, 0, time.UTC)
+	w := ChangeWindow{Start: start, End: start.Add(2 * time.Hour), Targets: []string{"10.0.0.0/16", "db.example.com"}, Reason: "CHG-1"}
+	during := start.Add(time.Hour)
+
+	tests := []struct {
+		target string
+		at     time.Time
+		want   bool
+	}{
+		{"10.0.4.0/24", during, true},
+		{"10.0.0.7 db.example.com", during, true},
+		{"10.0.0.0/8", during, false}, // Wider than the window
+		{"10.0.0.7 192.168.1.1", during, false},
+		{"", during, false}, // Only windows for every target cover target-less alerts
+		{"10.0.4.0/24", start.Add(3 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		if got := w.coversTarget(tt.target, tt.at); got != tt.want {
+			t.Errorf("coversTarget(%q) at %s = %v, want %v", tt.target, tt.at.Format(time.Kitchen), got, tt.want)
+		}
+	}
+	if everything := (ChangeWindow{Start: start, End: start.Add(time.Hour)}); !everything.coversTarget("", during.Add(-30*time.Minute)) {
+		t.Error("a window without targets does not cover a target-less alert")
+	}
+}
+
+func TestScanAlertsHeldBackDuringChangeWindow(t *testing.T) {
+	useTempScanFolder(t)
+	now := time.Now()
+	window := ChangeWindow{Start: now.Add(-time.Hour), End: now.Add(time.Hour), Targets: []string{"10.0.0.0/24"}, Reason: "CHG-12345"}
+	notifier := &recordingNotifier{}
+
+	notifyScanFailures(logger, []Notifier{notifier}, []ChangeWindow{window}, "10.0.0.0/24", 3, errors.New("nmap exited 1"))
+	if len(notifier.subjects) != 0 {
+		t.Fatalf("failure alert sent during the change window: %v", notifier.subjects)
+	}
+	notifyScanFailures(logger, []Notifier{notifier}, []ChangeWindow{window}, "10.0.1.0/24", 3, errors.New("nmap exited 1"))
+	if len(notifier.subjects) != 1 {
+		t.Errorf("%d alerts for a target outside the window, want 1", len(notifier.subjects))
+	}
+
+	// The watchdog's alert is held back by a window for every target, and
+	// counted once however many checks fall inside it
+	useTempScanFolder(t)
+	dir := t.TempDir()
+	last := now.Add(-48 * time.Hour)
+	if err := SaveHistoryIndex(dir, HistoryIndex{LastScan: last.Format(time.RFC3339)}); err != nil {
+		t.Fatal(err)
+	}
+	everything := ChangeWindow{Start: now.Add(-time.Hour), End: now.Add(time.Hour), Reason: "CHG-2"}
+	notifier = &recordingNotifier{}
+	watchdog := &Watchdog{Threshold: 24 * time.Hour, Notifiers: []Notifier{notifier}, DataDir: dir, ChangeWindows: []ChangeWindow{everything}}
+	for _, at := range []time.Time{now, now.Add(5 * time.Minute)} {
+		if err := watchdog.Check(at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(notifier.subjects) != 0 {
+		t.Fatalf("watchdog alerted during the change window: %v", notifier.subjects)
+	}
+	records, err := loadChangeWindowRecords()
+	if err != nil || records[everything.ID()].Alerts != 1 {
+		t.Errorf("held back alerts recorded as %+v (%v), want 1", records[everything.ID()], err)
+	}
+	if err := watchdog.Check(now.Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.subjects) != 1 {
+		t.Errorf("%d watchdog alerts once the window ended, want 1", len(notifier.subjects))
+	}
+}
+
+func TestSummariseChangeWindowsNotifies(t *testing.T) {
+	useTempScanFolder(t)
+	start := time.Date(2024, 5, 1, 22, 0, 0, 0, time.UTC)
+	window := ChangeWindow{Start: start, End: start.Add(2 * time.Hour), Reason: "CHG-12345"}
+	report := DiffReport{Target: "10.0.0.0/24", Hosts: []HostDiff{{IP: "10.0.0.5", Added: []string{"22/tcp [open] (ssh)", "80/tcp [open] (http)"}}}}
+
+	if _, ok := suppressingWindow([]ChangeWindow{window}, report, start.Add(time.Hour)); !ok {
+		t.Fatal("the change window did not hold back the report")
+	}
+	suppressingAlertWindow([]ChangeWindow{window}, "10.0.0.0/24", start.Add(time.Hour))
+
+	notifier := &recordingNotifier{}
+	notifiers := []Notifier{notifier, JIRAClient{URL: "http://jira.invalid", ProjectKey: "SEC"}}
+	SummariseChangeWindows([]ChangeWindow{window}, notifiers, start.Add(time.Hour))
+	if len(notifier.subjects) != 0 {
+		t.Fatal("summary sent before the window ended")
+	}
+
+	SummariseChangeWindows([]ChangeWindow{window}, notifiers, start.Add(3*time.Hour))
+	SummariseChangeWindows([]ChangeWindow{window}, notifiers, start.Add(4*time.Hour))
+	if len(notifier.subjects) != 1 {
+		t.Fatalf("%d summaries sent, want exactly 1", len(notifier.subjects))
+	}
+	want := "2 changes occurred during maintenance window CHG-12345. 1 scan alerts were held back."
+	if !strings.Contains(notifier.subjects[0], "CHG-12345") || !strings.Contains(notifier.bodies[0], want) {
+		t.Errorf("summary %q: %q, want %q", notifier.subjects[0], notifier.bodies[0], want)
+	}
+}
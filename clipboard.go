@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardTools lists the clipboard commands to try on this platform, in order
+func clipboardTools() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip"}}
+	default:
+		return [][]string{
+			{"wl-copy"},
+			{"xclip", "-selection", "clipboard"},
+			{"xsel", "--clipboard", "--input"},
+		}
+	}
+}
+
+// CopyToClipboard copies text to the system clipboard using the first
+// clipboard tool found (pbcopy, clip, wl-copy, xclip or xsel)
+func CopyToClipboard(text string) error {
+	for _, tool := range clipboardTools() {
+		if _, err := exec.LookPath(tool[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(tool[0], tool[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+
+	var names []string
+	for _, tool := range clipboardTools() {
+		names = append(names, tool[0])
+	}
+	return errors.New("no clipboard tool found (tried " + strings.Join(names, ", ") + ")")
+}
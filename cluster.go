@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// clusterMaxIterations bounds ClusterHosts in case assignments never settle
+const clusterMaxIterations = 100
+
+// HostCluster is a group of hosts with similar open ports, which usually
+// share a role such as web server or database server
+type HostCluster struct {
+	Label       string   `json:"label"`        // The services of CommonPorts, e.g. "http, https"
+	Hosts       []string `json:"hosts"`        // Sorted
+	CommonPorts []string `json:"common_ports"` // Ports open on most of the cluster's hosts, e.g. "443/tcp"
+}
+
+// ClusterHosts groups the hosts in result into at most k clusters by their
+// open ports. Each host is a binary vector with one element per port, and
+// hosts are assigned k-means style to the nearest centre by Manhattan
+// distance, i.e. the number of ports open on one but not the other. A centre
+// has the ports open on most of its hosts. The result is deterministic.
+func ClusterHosts(result ScanResult, k int) []HostCluster {
+	hosts := sortedHostKeys(result.Ports)
+	if len(hosts) == 0 || k < 1 {
+		return nil
+	}
+	k = min(k, len(hosts))
+
+	// Build the binary vectors over every port open anywhere
+	services := make(map[string]string)
+	open := make(map[string]map[string]bool, len(hosts))
+	for _, ip := range hosts {
+		open[ip] = make(map[string]bool)
+		for _, entry := range result.Ports[ip] {
+			port, proto, state, service := splitPortEntry(entry)
+			if state == "open" {
+				key := port + "/" + proto
+				open[ip][key] = true
+				services[key] = service
+			}
+		}
+	}
+	ports := sortedPortKeys(services)
+	vectors := make([][]bool, len(hosts))
+	for i, ip := range hosts {
+		vectors[i] = make([]bool, len(ports))
+		for j, port := range ports {
+			vectors[i][j] = open[ip][port]
+		}
+	}
+
+	// Seed with the first host, then repeatedly the host farthest from every centre
+	centres := [][]bool{slices.Clone(vectors[0])}
+	for len(centres) < k {
+		farthest, farthestDist := -1, -1
+		for i, v := range vectors {
+			if d := nearestCentre(v, centres).dist; d > farthestDist {
+				farthest, farthestDist = i, d
+			}
+		}
+		if farthestDist == 0 {
+			break // Fewer distinct port profiles than k
+		}
+		centres = append(centres, slices.Clone(vectors[farthest]))
+	}
+
+	assign := make([]int, len(hosts))
+	for iter := 0; iter < clusterMaxIterations; iter++ {
+		changed := iter == 0
+		for i, v := range vectors {
+			if c := nearestCentre(v, centres).index; c != assign[i] {
+				assign[i], changed = c, true
+			}
+		}
+		if !changed {
+			break
+		}
+		for c := range centres {
+			centres[c] = majorityVector(vectors, assign, c, len(ports))
+		}
+	}
+
+	clusters := make([]HostCluster, len(centres))
+	for i, ip := range hosts {
+		clusters[assign[i]].Hosts = append(clusters[assign[i]].Hosts, ip)
+	}
+	var out []HostCluster
+	for c, cluster := range clusters {
+		if len(cluster.Hosts) == 0 {
+			continue
+		}
+		var names []string
+		for j, port := range ports {
+			if centres[c][j] {
+				cluster.CommonPorts = append(cluster.CommonPorts, port)
+				if !slices.Contains(names, services[port]) {
+					names = append(names, services[port])
+				}
+			}
+		}
+		cluster.Label = strings.Join(names, ", ")
+		if cluster.Label == "" {
+			cluster.Label = "no common open ports"
+		}
+		out = append(out, cluster)
+	}
+	return out
+}
+
+type centreDistance struct{ index, dist int }
+
+// nearestCentre returns the centre closest to v by Manhattan distance, the
+// first on a tie
+func nearestCentre(v []bool, centres [][]bool) centreDistance {
+	best := centreDistance{index: -1}
+	for c, centre := range centres {
+		d := 0
+		for j := range v {
+			if v[j] != centre[j] {
+				d++
+			}
+		}
+		if best.index < 0 || d < best.dist {
+			best = centreDistance{c, d}
+		}
+	}
+	return best
+}
+
+// majorityVector is the new centre of cluster c: each port is set if it is
+// open on at least half of the cluster's hosts
+func majorityVector(vectors [][]bool, assign []int, c, size int) []bool {
+	counts := make([]int, size)
+	members := 0
+	for i, v := range vectors {
+		if assign[i] != c {
+			continue
+		}
+		members++
+		for j, set := range v {
+			if set {
+				counts[j]++
+			}
+		}
+	}
+	centre := make([]bool, size)
+	for j, n := range counts {
+		centre[j] = members > 0 && 2*n >= members
+	}
+	return centre
+}
+
+// sortedPortKeys returns the "port/proto" keys of m by port number, then protocol
+func sortedPortKeys[V any](m map[string]V) []string {
+	keys := sortedHostKeys(m)
+	slices.SortStableFunc(keys, func(a, b string) int {
+		numA, _ := strconv.Atoi(strings.Split(a, "/")[0])
+		numB, _ := strconv.Atoi(strings.Split(b, "/")[0])
+		return numA - numB
+	})
+	return keys
+}
+
+// PrintClusters writes clusters in a human-readable form
+func PrintClusters(clusters []HostCluster, w io.Writer) {
+	if len(clusters) == 0 {
+		fmt.Fprintln(w, "No hosts to cluster.")
+		return
+	}
+	for i, cluster := range clusters {
+		fmt.Fprintf(w, "Cluster %d: %s (%d hosts)\n", i+1, cluster.Label, len(cluster.Hosts))
+		if len(cluster.CommonPorts) > 0 {
+			fmt.Fprintf(w, "  Ports: %s\n", strings.Join(cluster.CommonPorts, ", "))
+		}
+		fmt.Fprintf(w, "  Hosts: %s\n", strings.Join(cluster.Hosts, ", "))
+	}
+}
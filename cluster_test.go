@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestClusterHosts(t *testing.T) {
+	scan := ScanResult{Ports: map[string][]string{
+		"10.0.0.1":  {"80/tcp [open] (http)", "443/tcp [open] (https)"},
+		"10.0.0.2":  {"80/tcp [open] (http)", "443/tcp [open] (https)", "8080/tcp [open] (http-proxy)"},
+		"10.0.0.3":  {"80/tcp [open] (http)", "443/tcp [open] (https)", "22/tcp [filtered] (ssh)"},
+		"10.0.0.11": {"22/tcp [open] (ssh)", "3306/tcp [open] (mysql)"},
+		"10.0.0.12": {"22/tcp [open] (ssh)", "3306/tcp [open] (mysql)"},
+		"10.0.0.13": {"22/tcp [open] (ssh)", "3306/tcp [open] (mysql)", "33060/tcp [open] (mysqlx)"},
+		"10.0.0.21": {"25/tcp [open] (smtp)", "587/tcp [open] (submission)", "993/tcp [open] (imaps)"},
+		"10.0.0.22": {"25/tcp [open] (smtp)", "587/tcp [open] (submission)", "993/tcp [open] (imaps)"},
+	}}
+
+	got := ClusterHosts(scan, 3)
+	want := []HostCluster{
+		{Label: "http, https", Hosts: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, CommonPorts: []string{"80/tcp", "443/tcp"}},
+		{Label: "ssh, mysql", Hosts: []string{"10.0.0.11", "10.0.0.12", "10.0.0.13"}, CommonPorts: []string{"22/tcp", "3306/tcp"}},
+		{Label: "smtp, submission, imaps", Hosts: []string{"10.0.0.21", "10.0.0.22"}, CommonPorts: []string{"25/tcp", "587/tcp", "993/tcp"}},
+	}
+	if fmt.Sprint(sortClusters(got)) != fmt.Sprint(sortClusters(want)) {
+		t.Errorf("got clusters\n%v\nwant\n%v", got, want)
+	}
+
+	// Deterministic whatever order the map is read in
+	for range 10 {
+		if again := ClusterHosts(scan, 3); fmt.Sprint(again) != fmt.Sprint(got) {
+			t.Fatalf("second run gave %v, first %v", again, got)
+		}
+	}
+}
+
+// sortClusters orders clusters by their first host, to compare them
+// regardless of the order the centres were seeded in
+func sortClusters(clusters []HostCluster) []HostCluster {
+	sorted := slices.Clone(clusters)
+	slices.SortFunc(sorted, func(a, b HostCluster) int { return strings.Compare(a.Hosts[0], b.Hosts[0]) })
+	return sorted
+}
+
+func TestClusterHostsLimits(t *testing.T) {
+	same := ScanResult{Ports: map[string][]string{
+		"10.0.0.1": {"22/tcp [open] (ssh)"},
+		"10.0.0.2": {"22/tcp [open] (ssh)"},
+		"10.0.0.3": {"22/tcp [closed] (ssh)"},
+	}}
+	tests := []struct {
+		name string
+		scan ScanResult
+		k    int
+		want int
+	}{
+		{"k of zero", same, 0, 0},
+		{"no hosts", ScanResult{}, 3, 0},
+		{"fewer profiles than k", same, 5, 2},
+		{"one cluster", same, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := ClusterHosts(tt.scan, tt.k); len(got) != tt.want {
+			t.Errorf("%s: got %d clusters %v, want %d", tt.name, len(got), got, tt.want)
+		}
+	}
+
+	var sb strings.Builder
+	PrintClusters(ClusterHosts(same, 5), &sb)
+	if !strings.Contains(sb.String(), "no common open ports (1 hosts)\n  Hosts: 10.0.0.3\n") {
+		t.Errorf("host without open ports printed as:\n%s", sb.String())
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cmdbConcurrency is how many CMDB lookups run at once
+const cmdbConcurrency = 8
+
+// AssetInfo is what the asset inventory records about a host
+type AssetInfo struct {
+	Owner       string `json:"owner,omitempty"`
+	Environment string `json:"environment,omitempty"` // e.g. production, staging
+	Criticality string `json:"criticality,omitempty"`
+	// Ports the host is approved to expose, as "443" or "443/tcp". Left out
+	// (null) means no policy is recorded; an empty list means none are allowed.
+	ExpectedPorts []string `json:"expected_ports"`
+}
+
+// ErrAssetNotFound is returned by a CMDBClient for hosts it has no record of
+var ErrAssetNotFound = errors.New("asset not found")
+
+// CMDBClient looks hosts up in an asset inventory
+type CMDBClient interface {
+	Lookup(ip string) (AssetInfo, error)
+}
+
+// HTTPCMDB queries a REST CMDB that answers GET <url>/<ip> (or the URL with
+// {ip} replaced, if it contains one) with an AssetInfo as JSON, and 404 for
+// unknown hosts
+type HTTPCMDB struct {
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"` // Sent as a bearer token, if set
+}
+
+// Enabled reports whether a CMDB URL is configured
+func (c HTTPCMDB) Enabled() bool {
+	return c.URL != ""
+}
+
+// Lookup fetches the asset record for ip
+func (c HTTPCMDB) Lookup(ip string) (AssetInfo, error) {
+	endpoint := strings.TrimRight(c.URL, "/") + "/" + url.PathEscape(ip)
+	if strings.Contains(c.URL, "{ip}") {
+		endpoint = strings.ReplaceAll(c.URL, "{ip}", url.PathEscape(ip))
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return AssetInfo{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return AssetInfo{}, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return AssetInfo{}, ErrAssetNotFound
+	case resp.StatusCode != http.StatusOK:
+		return AssetInfo{}, fmt.Errorf("CMDB returned %s", resp.Status)
+	}
+
+	var asset AssetInfo
+	if err := json.NewDecoder(resp.Body).Decode(&asset); err != nil {
+		return AssetInfo{}, fmt.Errorf("reading CMDB response: %v", err)
+	}
+	return asset, nil
+}
+
+// CMDBEnricher attaches each host's asset record and flags the open ports
+// its record does not allow
+type CMDBEnricher struct {
+	Client CMDBClient
+}
+
+func (e CMDBEnricher) Name() string { return "cmdb" }
+
+// Enrich looks the host up; hosts missing from the CMDB are left as they are
+func (e CMDBEnricher) Enrich(host *HostResult) error {
+	asset, err := e.Client.Lookup(host.IP)
+	if errors.Is(err, ErrAssetNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	host.Asset = &asset
+	host.markUnexpectedPorts()
+	return nil
+}
+
+// Allows reports whether the asset's policy permits port/proto to be open
+func (a AssetInfo) Allows(port int, proto string) bool {
+	if a.ExpectedPorts == nil {
+		return true
+	}
+	for _, expected := range a.ExpectedPorts {
+		p, wantProto, _ := strings.Cut(strings.TrimSpace(expected), "/")
+		if n, err := strconv.Atoi(p); err == nil && n == port && (wantProto == "" || strings.EqualFold(wantProto, proto)) {
+			return true
+		}
+	}
+	return false
+}
+
+// markUnexpectedPorts flags open ports the host's asset record does not allow
+func (h *HostResult) markUnexpectedPorts() {
+	if h.Asset == nil {
+		return
+	}
+	for i, p := range h.Ports {
+		h.Ports[i].UnexpectedPort = p.State == "open" && !h.Asset.Allows(p.Port, p.Protocol)
+	}
+}
+
+// unauthorisedPortNote returns an annotation for a port entry of ip that the
+// host's CMDB record does not allow
+func unauthorisedPortNote(scan ScanResult, ip, entry string) string {
+	want := ParsePortEntry(entry)
+	for _, p := range scan.Hosts[ip].Ports {
+		if p.Port == want.Port && p.Protocol == want.Protocol && p.UnexpectedPort {
+			return "  [UNAUTHORISED PORT]"
+		}
+	}
+	return ""
+}
+
+// unauthorisedPorts returns every open port in scan that its host's CMDB
+// record does not allow, keyed by IP, whether or not it is new since the last
+// scan. Only hosts enriched by CMDBEnricher can have any.
+func unauthorisedPorts(scan ScanResult) map[string][]string {
+	var ports map[string][]string
+	for ip, host := range scan.Hosts {
+		for _, p := range host.Ports {
+			if !p.UnexpectedPort {
+				continue
+			}
+			if ports == nil {
+				ports = make(map[string][]string)
+			}
+			ports[ip] = append(ports[ip], p.String())
+		}
+	}
+	return ports
+}
+
+// writeUnauthorisedPorts lists the ports found by unauthorisedPorts, writing
+// nothing if there are none
+func writeUnauthorisedPorts(w io.Writer, ports map[string][]string) {
+	if len(ports) == 0 {
+		return
+	}
+	ips := make([]string, 0, len(ports))
+	total := 0
+	for ip, entries := range ports {
+		ips = append(ips, ip)
+		total += len(entries)
+	}
+	slices.SortFunc(ips, compareIPs)
+	fmt.Fprintf(w, "Unauthorised ports open: %d (not in the hosts' CMDB expected ports)\n", total)
+	for _, ip := range ips {
+		for _, entry := range ports[ip] {
+			fmt.Fprintf(w, "  %s: %s\n", ip, entry)
+		}
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ColourPalette holds the ANSI escape sequences used for diff and log output
+type ColourPalette struct {
+	Added   string `yaml:"added"`   // New ports, hosts coming up
+	Removed string `yaml:"removed"` // Closed ports, hosts going down
+	Changed string `yaml:"changed"` // Modified values such as script output
+	Warning string `yaml:"warning"` // Warnings and sensitive-port notes
+	Reset   string `yaml:"reset"`
+}
+
+// palettes are the built-in choices for -colour-palette
+var palettes = map[string]ColourPalette{
+	"default":     {Added: "\033[32m", Removed: "\033[31m", Changed: "\033[33m", Warning: "\033[1;33m", Reset: "\033[0m"},
+	"colourblind": {Added: "\033[34m", Removed: "\033[38;5;208m", Changed: "\033[35m", Warning: "\033[1m", Reset: "\033[0m"},
+	"none":        {},
+}
+
+// palette is the active palette, set from -colour-palette in main
+var palette = palettes["default"]
+
+// LookupPalette returns the named built-in palette with any non-empty fields
+// of custom (the colours: section of the config file) applied on top
+func LookupPalette(name string, custom ColourPalette) (ColourPalette, error) {
+	p, ok := palettes[name]
+	if !ok {
+		names := make([]string, 0, len(palettes))
+		for n := range palettes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return ColourPalette{}, fmt.Errorf("unknown colour palette %q (choose from %s)", name, strings.Join(names, ", "))
+	}
+	override := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+	override(&p.Added, custom.Added)
+	override(&p.Removed, custom.Removed)
+	override(&p.Changed, custom.Changed)
+	override(&p.Warning, custom.Warning)
+	override(&p.Reset, custom.Reset)
+	return p, nil
+}
+
+// Colours returns the active palette, or an empty one when colour is disabled
+func Colours() ColourPalette {
+	if !ColourEnabled() {
+		return ColourPalette{}
+	}
+	return palette
+}
+
+// colourOverride is set by -color / -no-color; nil means auto-detect
+var colourOverride *bool
+
+// ColourEnabled reports whether ANSI colour codes should be emitted. Colour is
+// off when NO_COLOR is set (https://no-color.org/) or stdout is not a terminal,
+// unless forced either way with -color / -no-color.
+func ColourEnabled() bool {
+	if colourOverride != nil {
+		return *colourOverride
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return IsTerminal(os.Stdout)
+}
+
+// SetColourOverride applies the -color / -no-color flags; -no-color wins if both are given
+func SetColourOverride(forceOn, forceOff bool) {
+	switch {
+	case forceOff:
+		off := false
+		colourOverride = &off
+	case forceOn:
+		on := true
+		colourOverride = &on
+	default:
+		colourOverride = nil
+	}
+}
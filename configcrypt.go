@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/pbkdf2"
+	"gopkg.in/yaml.v3"
+)
+
+// Encrypted config layout: magic | salt | nonce | AES-256-GCM ciphertext
+const (
+	encryptedConfigSuffix = ".enc"
+	encryptedConfigMagic  = "PHENC1"
+	configSaltSize        = 16
+	configKeyIterations   = 600000
+	configPassphraseEnv   = "PORTHUNTER_CONFIG_PASSPHRASE"
+)
+
+// ErrWrongPassphrase is returned when an encrypted config cannot be authenticated
+var ErrWrongPassphrase = errors.New("cannot decrypt config: wrong passphrase or corrupted file")
+
+// EncryptConfig serialises cfg as YAML and encrypts it with AES-256-GCM using
+// a key derived from passphrase with PBKDF2-SHA256
+func EncryptConfig(cfg Config, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("a passphrase is required to encrypt the config")
+	}
+	plain, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, configSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := configCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte(encryptedConfigMagic), salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plain, []byte(encryptedConfigMagic)), nil
+}
+
+// DecryptConfig reverses EncryptConfig, applying the decrypted settings on top of the defaults
+func DecryptConfig(data []byte, passphrase string) (Config, error) {
+	cfg := DefaultConfig()
+	if !bytes.HasPrefix(data, []byte(encryptedConfigMagic)) {
+		return cfg, errors.New("not an encrypted PortHunter config")
+	}
+	if passphrase == "" {
+		return cfg, fmt.Errorf("config is encrypted: pass -config-passphrase or set %s", configPassphraseEnv)
+	}
+	data = data[len(encryptedConfigMagic):]
+	if len(data) < configSaltSize {
+		return cfg, ErrWrongPassphrase
+	}
+
+	gcm, err := configCipher(passphrase, data[:configSaltSize])
+	if err != nil {
+		return cfg, err
+	}
+	data = data[configSaltSize:]
+	if len(data) < gcm.NonceSize() {
+		return cfg, ErrWrongPassphrase
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(encryptedConfigMagic))
+	if err != nil {
+		return cfg, ErrWrongPassphrase
+	}
+	if err := yaml.Unmarshal(plain, &cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// configCipher derives the AES-256 key for salt and returns a GCM instance
+func configCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := pbkdf2.Key([]byte(passphrase), salt, configKeyIterations, 32, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// configPassphraseFromArgs finds the -config-passphrase value before flags are
+// parsed, falling back to the PORTHUNTER_CONFIG_PASSPHRASE environment variable
+func configPassphraseFromArgs(args []string) string {
+	if value, ok := flagValueFromArgs(args, "config-passphrase"); ok {
+		return value
+	}
+	return os.Getenv(configPassphraseEnv)
+}
+
+// runEncryptConfig implements "porthunter encrypt-config": it writes an
+// encrypted copy of a plain config file next to it as <file>.enc
+func runEncryptConfig(args []string) error {
+	fs := flag.NewFlagSet("encrypt-config", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigFile, "Plain YAML config file to encrypt")
+	passphrase := fs.String("config-passphrase", os.Getenv(configPassphraseEnv), "Passphrase (default $"+configPassphraseEnv+")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Read the file directly rather than via LoadConfig so credentials taken
+	// from the environment are not baked into the encrypted copy
+	plain, err := os.ReadFile(*configPath)
+	if err != nil {
+		return err
+	}
+	cfg := DefaultConfig()
+	if err := yaml.Unmarshal(plain, &cfg); err != nil {
+		return err
+	}
+	data, err := EncryptConfig(cfg, *passphrase)
+	if err != nil {
+		return err
+	}
+	out := *configPath + encryptedConfigSuffix
+	if err := os.WriteFile(out, data, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("Encrypted config written to %s. Delete %s once you have checked it loads.\n", out, *configPath)
+	return nil
+}
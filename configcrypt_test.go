@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncryptConfigRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Target = "10.0.0.0/24"
+	cfg.Command = "nmap -sT --top-ports 100"
+	cfg.Email = EmailConfig{Host: "smtp.example.com", Port: 587, Password: "hunter2", To: []string{"soc@example.com"}}
+
+	data, err := EncryptConfig(cfg, "correct horse")
+	if err != nil {
+		t.Fatalf("EncryptConfig: %v", err)
+	}
+	if bytes.Contains(data, []byte("hunter2")) || bytes.Contains(data, []byte("smtp.example.com")) {
+		t.Error("encrypted config contains plain text settings")
+	}
+
+	got, err := DecryptConfig(data, "correct horse")
+	if err != nil {
+		t.Fatalf("DecryptConfig: %v", err)
+	}
+	if got.Target != cfg.Target || got.Command != cfg.Command || got.Email.Password != "hunter2" || got.Email.Port != 587 {
+		t.Errorf("decrypted config %+v does not match the original", got)
+	}
+
+	if _, err := EncryptConfig(cfg, ""); err == nil {
+		t.Error("EncryptConfig accepted an empty passphrase")
+	}
+}
+
+func TestDecryptConfigRejectsBadInput(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Target = "10.0.0.0/24"
+	data, err := EncryptConfig(cfg, "correct horse")
+	if err != nil {
+		t.Fatalf("EncryptConfig: %v", err)
+	}
+
+	flipped := append([]byte{}, data...)
+	flipped[len(flipped)-1] ^= 0xff
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+		want       error
+	}{
+		{"wrong passphrase", data, "battery staple", ErrWrongPassphrase},
+		{"truncated ciphertext", data[:len(data)-5], "correct horse", ErrWrongPassphrase},
+		{"truncated salt", data[:len(encryptedConfigMagic)+4], "correct horse", ErrWrongPassphrase},
+		{"truncated nonce", data[:len(encryptedConfigMagic)+configSaltSize+4], "correct horse", ErrWrongPassphrase},
+		{"corrupted ciphertext", flipped, "correct horse", ErrWrongPassphrase},
+		{"plain yaml", []byte("target: 10.0.0.0/24\n"), "correct horse", nil},
+		{"no passphrase", data, "", nil},
+	}
+	for _, tt := range tests {
+		got, err := DecryptConfig(tt.data, tt.passphrase)
+		if err == nil {
+			t.Errorf("%s: DecryptConfig succeeded", tt.name)
+			continue
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.want)
+		}
+		if got.Target != "" {
+			t.Errorf("%s: returned settings %q from a config it could not decrypt", tt.name, got.Target)
+		}
+	}
+}
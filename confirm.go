@@ -0,0 +1,111 @@
+package main
+
+import "fmt"
+
+// ConfirmChange re-scans host with command and returns what the second scan
+// saw. firstResult is the scan that showed the change; it supplies the
+// hostname if the second scan does not resolve one.
+func ConfirmChange(host string, firstResult HostResult, command string) (HostResult, error) {
+	scan, err := RunScanWithOptions(command, host, ScanOptions{Quiet: true})
+	if err != nil {
+		return HostResult{}, fmt.Errorf("confirming changes on %s: %w", host, err)
+	}
+
+	second, ok := scan.Hosts[host]
+	if !ok {
+		second = HostFromPortStrings(host, scan.Ports[host])
+		if _, up := scan.Ports[host]; !up {
+			second.State = HostDown
+		}
+	}
+	if second.Hostname == "" {
+		second.Hostname = firstResult.Hostname
+	}
+	return second, nil
+}
+
+// DoubleCheckChanges re-scans every host with port changes between prev and
+// scan, keeping only the changes the second scan agrees with. Where the two
+// scans disagree about a port its previous entry is restored, so the
+// transient change is neither reported nor saved.
+func DoubleCheckChanges(prev ScanResult, scan *ScanResult, command string) {
+	for _, diff := range BuildDiffReport(prev, *scan).Hosts {
+		first := HostFromPortStrings(diff.IP, scan.Ports[diff.IP])
+		if host, ok := scan.Hosts[diff.IP]; ok {
+			first = host
+		}
+
+		fmt.Printf("Double-checking %s...\n", diff.IP)
+		second, err := ConfirmChange(diff.IP, first, command)
+		if err != nil {
+			logger.Warn("%v, keeping the first result", err)
+			continue
+		}
+
+		confirmed := confirmedPorts(prev.Ports[diff.IP], scan.Ports[diff.IP], second.PortStrings())
+		added, removed := DiffPortsWithNormaliser(prev.Ports[diff.IP], confirmed, diffNormaliser)
+		if dropped := len(diff.Added) + len(diff.Removed) - len(added) - len(removed); dropped > 0 {
+			fmt.Printf("  %d unconfirmed change(s) on %s ignored\n", dropped, diff.IP)
+		}
+		scan.Ports[diff.IP] = confirmed
+		if host, ok := scan.Hosts[diff.IP]; ok {
+			scan.Hosts[diff.IP] = withPorts(host, confirmed)
+		}
+	}
+}
+
+// confirmedPorts merges a host's port lists: entries both scans agree on are
+// kept, and for any port they disagree about the previous entry (or its
+// absence) is used instead
+func confirmedPorts(prev, first, second []string) []string {
+	byKey := func(ports []string) map[string]string {
+		m := make(map[string]string, len(ports))
+		for _, entry := range ports {
+			m[portKey(entry)] = entry
+		}
+		return m
+	}
+	prevByKey, firstByKey, secondByKey := byKey(prev), byKey(first), byKey(second)
+
+	// Keep the first scan's ordering, followed by ports only the previous scan had
+	var keys []string
+	seen := make(map[string]bool)
+	for _, list := range [][]string{first, prev} {
+		for _, entry := range list {
+			if key := portKey(entry); !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	confirmed := []string{}
+	for _, key := range keys {
+		entry, present := firstByKey[key]
+		if again, inSecond := secondByKey[key]; present != inSecond || entry != again {
+			entry, present = prevByKey[key]
+		}
+		if present {
+			confirmed = append(confirmed, entry)
+		}
+	}
+	return confirmed
+}
+
+// withPorts replaces host's ports with entries, keeping the detail (such as
+// script output) of any port that is unchanged
+func withPorts(host HostResult, entries []string) HostResult {
+	detail := make(map[string]PortEntry, len(host.Ports))
+	for _, p := range host.Ports {
+		detail[p.String()] = p
+	}
+	host.Ports = make([]PortEntry, 0, len(entries))
+	for _, entry := range entries {
+		if p, ok := detail[entry]; ok {
+			host.Ports = append(host.Ports, p)
+		} else {
+			host.Ports = append(host.Ports, ParsePortEntry(entry))
+		}
+	}
+	return host
+}
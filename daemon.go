@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// daemonChildEnv marks the detached daemon process
+const daemonChildEnv = "PORTHUNTER_DAEMON_CHILD"
+
+// Daemon files
+func daemonPIDFile() string    { return filepath.Join(scanFolder, "porthunter.pid") }
+func daemonSocketFile() string { return filepath.Join(scanFolder, "porthunter.sock") }
+func daemonLogFile() string    { return filepath.Join(scanFolder, "porthunter.log") }
+
+// DaemonRequest is a command sent to the daemon's control socket
+type DaemonRequest struct {
+	Command string `json:"command"`          // scan, status or stop
+	Target  string `json:"target,omitempty"` // For scan; defaults to the configured target
+}
+
+// DaemonResponse is the daemon's reply to a DaemonRequest
+type DaemonResponse struct {
+	OK      bool          `json:"ok"`
+	Message string        `json:"message,omitempty"`
+	Status  *DaemonStatus `json:"status,omitempty"`
+}
+
+// DaemonStatus describes what the daemon is doing
+type DaemonStatus struct {
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	Scanning  string    `json:"scanning,omitempty"` // Target of the scan in progress
+	Queued    int       `json:"queued"`
+	ScansRun  int       `json:"scans_run"`
+	LastScan  time.Time `json:"last_scan,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// RunDaemon starts PortHunter in the background. The first call re-executes
+// the program detached from the terminal and returns; in that child it
+// serves the control socket (and the watch loop, with -watch) until stopped.
+func RunDaemon(cfg Config, reloader *configReloader) error {
+	if os.Getenv(daemonChildEnv) == "" {
+		if pid, err := readDaemonPID(); err == nil && processAlive(pid) {
+			return fmt.Errorf("daemon already running (pid %d)", pid)
+		}
+		if err := EnsureScanFolderExists(); err != nil {
+			return err
+		}
+		pid, err := detachDaemon(daemonLogFile())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("PortHunter daemon started (pid %d), logging to %s\n", pid, daemonLogFile())
+		return nil
+	}
+	return serveDaemon(cfg, reloader)
+}
+
+// daemon is the state shared by the control socket and the scan worker
+type daemon struct {
+	cfg      Config // Replaced between scans when the configuration is reloaded
+	reloader *configReloader
+	queue    chan string
+	stop     context.CancelFunc
+	scanMu   sync.Mutex // Scans share the stored baseline, so only one runs at a time
+
+	mu     sync.Mutex
+	status DaemonStatus
+}
+
+func serveDaemon(cfg Config, reloader *configReloader) error {
+	if pid, err := readDaemonPID(); err == nil && pid != os.Getpid() && processAlive(pid) {
+		return fmt.Errorf("daemon already running (pid %d)", pid)
+	}
+	if err := os.WriteFile(daemonPIDFile(), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return err
+	}
+	defer os.Remove(daemonPIDFile())
+
+	os.Remove(daemonSocketFile()) // Left behind if a previous daemon crashed
+	ln, err := net.Listen("unix", daemonSocketFile())
+	if err != nil {
+		return err
+	}
+	defer os.Remove(daemonSocketFile())
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, daemonStopSignal)
+	defer cancel()
+	d := &daemon{cfg: cfg, reloader: reloader, queue: make(chan string, 100), stop: cancel}
+	d.status = DaemonStatus{PID: os.Getpid(), StartedAt: time.Now()}
+	logger.Info("Daemon listening on %s", daemonSocketFile())
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	go d.worker(ctx)
+	go reloader.Watch(ctx)
+	if digest, ok := NewDigestNotifier(cfg); ok {
+		go digest.Run(ctx)
+	}
+	if notifiers := configuredNotifiers(cfg); cfg.Watch && len(notifiers) > 0 {
+		watchdog := &Watchdog{Threshold: cfg.WatchdogThreshold, Notifiers: notifiers}
+		go watchdog.Run(ctx)
+	}
+	if cfg.Watch {
+		watcher := &AdaptiveWatcher{MinInterval: cfg.Interval, MaxInterval: cfg.MaxInterval, BackoffFactor: cfg.BackoffFactor}
+		watcher.OnFailureAlert = func(failures int, err error) {
+			cfg := d.config()
+			notifyScanFailures(logger, configuredNotifiers(cfg), cfg.ChangeWindows, cfg.Target, failures, err)
+		}
+		reports := &ScheduledReports{Every: cfg.ReportEvery, Keep: cfg.ReportsKept}
+		go watcher.Run(ctx, func() (bool, error) {
+			changed, err := d.scan(ctx, "")
+			cfg := d.config()
+			watcher.MinInterval, watcher.MaxInterval, watcher.BackoffFactor = cfg.Interval, cfg.MaxInterval, cfg.BackoffFactor
+			reports.Every, reports.Keep = cfg.ReportEvery, cfg.ReportsKept
+			reports.AfterScan()
+			if ctx.Err() != nil {
+				err = nil // Stopping, not failing
+			}
+			return changed, err
+		})
+	}
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go d.handle(conn)
+	}
+}
+
+// worker runs scans requested over the socket, one at a time
+func (d *daemon) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case target := <-d.queue:
+			d.scan(ctx, target)
+		}
+	}
+}
+
+// config returns the daemon's current configuration
+func (d *daemon) config() Config {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cfg
+}
+
+// scan runs one scan of target ("" for the configured target), recording the
+// outcome in the status, and reports whether it found changes. A reloaded
+// configuration is picked up first.
+func (d *daemon) scan(ctx context.Context, target string) (bool, error) {
+	d.scanMu.Lock()
+	defer d.scanMu.Unlock()
+
+	cfg, _ := d.reloader.Next(d.config())
+	if target == "" {
+		target = cfg.Target
+	}
+
+	d.mu.Lock()
+	d.cfg = cfg
+	d.status.Scanning = target
+	d.status.Queued = len(d.queue)
+	d.mu.Unlock()
+
+	cfg.Target = target
+	changed, err := RunOnce(ctx, cfg)
+	if err != nil {
+		logScanError(err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status.Scanning = ""
+	d.status.Queued = len(d.queue)
+	d.status.ScansRun++
+	d.status.LastScan = time.Now()
+	d.status.LastError = ""
+	if err != nil {
+		d.status.LastError = err.Error()
+	}
+	return changed, err
+}
+
+// handle answers a single request on conn
+func (d *daemon) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	var req DaemonRequest
+	var resp DaemonResponse
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		resp.Message = "invalid request: " + err.Error()
+		json.NewEncoder(conn).Encode(resp)
+		return
+	}
+
+	switch req.Command {
+	case "scan":
+		target := req.Target
+		if target == "" {
+			target = d.config().Target
+		}
+		if err := ValidateTarget(target); err != nil {
+			resp.Message = err.Error()
+			break
+		}
+		select {
+		case d.queue <- target:
+			resp.OK, resp.Message = true, "scan of "+target+" queued"
+		default:
+			resp.Message = "scan queue is full"
+		}
+	case "status":
+		d.mu.Lock()
+		status := d.status
+		status.Queued = len(d.queue)
+		d.mu.Unlock()
+		resp.OK, resp.Status = true, &status
+	case "stop":
+		resp.OK, resp.Message = true, "stopping"
+		defer d.stop()
+	default:
+		resp.Message = fmt.Sprintf("unknown command %q (use scan, status or stop)", req.Command)
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// SendDaemonCommand sends a request to a running daemon and returns its reply
+func SendDaemonCommand(req DaemonRequest) (DaemonResponse, error) {
+	conn, err := net.DialTimeout("unix", daemonSocketFile(), 5*time.Second)
+	if err != nil {
+		return DaemonResponse{}, fmt.Errorf("cannot reach daemon (is it running?): %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return DaemonResponse{}, err
+	}
+	var resp DaemonResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return DaemonResponse{}, err
+	}
+	if !resp.OK {
+		return resp, errors.New(resp.Message)
+	}
+	return resp, nil
+}
+
+func readDaemonPID() (int, error) {
+	data, err := os.ReadFile(daemonPIDFile())
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// runCtl implements "porthunter ctl scan [target] | status | stop"
+func runCtl(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: porthunter ctl scan [target] | status | stop")
+	}
+	req := DaemonRequest{Command: args[0]}
+	if len(args) > 1 {
+		req.Target = args[1]
+	}
+
+	resp, err := SendDaemonCommand(req)
+	if err != nil {
+		return err
+	}
+	if s := resp.Status; s != nil {
+		fmt.Printf("PID:        %d\n", s.PID)
+		fmt.Printf("Running:    %s\n", formatElapsedTime(time.Since(s.StartedAt), false))
+		fmt.Printf("Scans run:  %d\n", s.ScansRun)
+		fmt.Printf("Queued:     %d\n", s.Queued)
+		if s.Scanning != "" {
+			fmt.Printf("Scanning:   %s\n", s.Scanning)
+		}
+		if !s.LastScan.IsZero() {
+			fmt.Printf("Last scan:  %s ago\n", formatElapsedTime(time.Since(s.LastScan), false))
+		}
+		if s.LastError != "" {
+			fmt.Printf("Last error: %s\n", s.LastError)
+		}
+		return nil
+	}
+	fmt.Println(resp.Message)
+	return nil
+}
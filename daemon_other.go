@@ -0,0 +1,23 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// daemonStopSignal is the signal that stops the daemon besides an interrupt
+var daemonStopSignal os.Signal = os.Kill
+
+// reloadSignal is nil where there is no SIGHUP, so configuration reloading is off
+var reloadSignal os.Signal
+
+// detachDaemon is only supported on Unix
+func detachDaemon(logPath string) (int, error) {
+	return 0, errors.New("-daemon is only supported on Unix")
+}
+
+func processAlive(pid int) bool {
+	return false
+}
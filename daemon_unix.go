@@ -0,0 +1,44 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// daemonStopSignal is the signal that stops the daemon besides an interrupt
+var daemonStopSignal os.Signal = syscall.SIGTERM
+
+// reloadSignal makes watch mode and the daemon re-read their configuration
+var reloadSignal os.Signal = syscall.SIGHUP
+
+// detachDaemon re-executes PortHunter in a new session with its output sent
+// to logPath, returning the child's PID
+func detachDaemon(logPath string) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), daemonChildEnv+"=1")
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	pid := cmd.Process.Pid
+	return pid, cmd.Process.Release()
+}
+
+// processAlive reports whether a process with pid exists
+func processAlive(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
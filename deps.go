@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// NmapNotFoundError is returned when the nmap executable is not on the PATH
+type NmapNotFoundError struct {
+	Executable string
+}
+
+func (e *NmapNotFoundError) Error() string {
+	return fmt.Sprintf("%s is not installed or not on your PATH", e.Executable)
+}
+
+// lookupNmap returns the path of executable, or a *NmapNotFoundError
+func lookupNmap(executable string) (string, error) {
+	path, err := exec.LookPath(executable)
+	if err != nil {
+		return "", &NmapNotFoundError{Executable: executable}
+	}
+	return path, nil
+}
+
+// NmapInstallGuide returns the commands to install nmap on this platform
+func NmapInstallGuide() string {
+	var sb strings.Builder
+	sb.WriteString("PortHunter needs nmap. Install it with:\n")
+	switch runtime.GOOS {
+	case "darwin":
+		sb.WriteString("  brew install nmap\n")
+	case "windows":
+		sb.WriteString("  choco install nmap\n")
+		sb.WriteString("  (or download the installer from https://nmap.org/download.html#windows)\n")
+	default:
+		sb.WriteString("  sudo apt-get install nmap    # Debian, Ubuntu, Kali\n")
+		sb.WriteString("  sudo dnf install nmap        # Fedora, RHEL\n")
+		sb.WriteString("  sudo pacman -S nmap          # Arch\n")
+	}
+	return sb.String()
+}
+
+// CheckDeps reports on each external tool the config needs, returning false
+// if any is missing
+func CheckDeps(cfg Config, w io.Writer) bool {
+	tools := []string{"nmap"}
+	if command, err := ResolveCommand(cfg); err == nil && strings.HasPrefix(strings.TrimSpace(command), "sudo ") {
+		tools = append(tools, "sudo")
+	}
+
+	ok, nmapFound := true, true
+	for _, tool := range tools {
+		path, err := exec.LookPath(tool)
+		if err != nil {
+			fmt.Fprintf(w, "%-6s missing\n", tool)
+			ok = false
+			nmapFound = nmapFound && tool != "nmap"
+			continue
+		}
+		if tool == "nmap" {
+			if v, err := DetectNmapVersion(); err == nil {
+				path += " (" + v + ")"
+			}
+		}
+		fmt.Fprintf(w, "%-6s %s\n", tool, path)
+	}
+	if !nmapFound {
+		fmt.Fprint(w, "\n"+NmapInstallGuide())
+	}
+	return ok
+}
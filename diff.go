@@ -0,0 +1,448 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// HostDiff is the set of port changes for a single host
+type HostDiff struct {
+	IP      string   `json:"ip"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// ScriptDiff is a change in an NSE script's output for one port. A script
+// that only ran in one of the scans has an empty OldOutput or NewOutput.
+type ScriptDiff struct {
+	Host       string `json:"host"`
+	Port       string `json:"port"` // e.g. "443/tcp"
+	ScriptName string `json:"script"`
+	OldOutput  string `json:"old_output"`
+	NewOutput  string `json:"new_output"`
+}
+
+// DiffReport is the structured result of comparing two scans
+type DiffReport struct {
+	Target           string              `json:"target,omitempty"`
+	OldDateTime      string              `json:"old_datetime"`
+	NewDateTime      string              `json:"new_datetime"`
+	Hosts            []HostDiff          `json:"hosts,omitempty"`
+	HostStateChanges []HostStateChange   `json:"host_state_changes,omitempty"`
+	ScriptChanges    []ScriptDiff        `json:"script_changes,omitempty"`
+	MACChanges       []MACChange         `json:"mac_changes,omitempty"`
+	RouteChanges     []RouteChange       `json:"route_changes,omitempty"`
+	OSChanges        []OSChange          `json:"os_changes,omitempty"`
+	DNSChanges       []DNSChange         `json:"dns_changes,omitempty"`
+	Alerts           []ExposureAlert     `json:"alerts,omitempty"`             // Newly-open remote-management services
+	Unauthorised     map[string][]string `json:"unauthorised_ports,omitempty"` // Every open port the CMDB doesn't allow, new or not
+	Score            int                 `json:"score"`
+}
+
+// BuildDiffReport compares two scans. Hosts that went down are reported as a
+// host state change only, not as a list of removed ports.
+func BuildDiffReport(old, new ScanResult) DiffReport {
+	report, _ := BuildDiffReportContext(context.Background(), old, new)
+	return report
+}
+
+// BuildDiffReportContext is BuildDiffReport, stopping with ctx's error if ctx
+// is cancelled before every host has been compared
+func BuildDiffReportContext(ctx context.Context, old, new ScanResult) (DiffReport, error) {
+	report := DiffReport{
+		OldDateTime:      old.DateTime,
+		NewDateTime:      new.DateTime,
+		HostStateChanges: DiffHostStates(old, new),
+		ScriptChanges:    DiffScripts(old, new),
+		MACChanges:       DiffMACs(old, new),
+		RouteChanges:     DiffRoutes(old, new),
+		OSChanges:        DiffOSFingerprints(old, new),
+		DNSChanges:       DiffDNS(old, new),
+	}
+
+	for ip, newPorts := range new.Ports {
+		if err := ctx.Err(); err != nil {
+			return DiffReport{}, err
+		}
+		added, removed := DiffPortsWithNormaliser(old.Ports[ip], newPorts, diffNormaliser)
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		report.Hosts = append(report.Hosts, HostDiff{IP: ip, Added: added, Removed: removed})
+		report.Score += ScoreDiff(added, removed)
+	}
+	// Hosts that went down are host state changes rather than port diffs,
+	// but their open ports closing still counts towards the score
+	for _, c := range report.HostStateChanges {
+		if c.NewState == HostDown {
+			report.Score += ScoreDiff(nil, old.Ports[c.IP])
+		}
+	}
+	report = SortDiffBySeverity(report)
+	report.Alerts = DiffExposures(report.Hosts)
+	report.Unauthorised = unauthorisedPorts(new)
+	return report, nil
+}
+
+// SortDiffBySeverity orders the hosts of diff by their number of
+// high-sensitivity port changes, most first, then by medium and then low
+// ones, so the most important findings lead the report. Hosts with equal
+// counts are ordered by IP address, compared numerically.
+func SortDiffBySeverity(diff DiffReport) DiffReport {
+	counts := make(map[string][3]int, len(diff.Hosts))
+	for _, h := range diff.Hosts {
+		counts[h.IP] = severityCounts(append(append([]string{}, h.Added...), h.Removed...))
+	}
+	hosts := slices.Clone(diff.Hosts)
+	slices.SortStableFunc(hosts, func(a, b HostDiff) int {
+		ca, cb := counts[a.IP], counts[b.IP]
+		return cmp.Or(cmp.Compare(cb[0], ca[0]), cmp.Compare(cb[1], ca[1]), cmp.Compare(cb[2], ca[2]), compareIPs(a.IP, b.IP))
+	})
+	diff.Hosts = hosts
+	return diff
+}
+
+// severityCounts counts the high, medium and low sensitivity port changes in
+// entries. As with ScoreDiff, only open ports are an exposure: other states
+// and ports missing from the port database count as low.
+func severityCounts(entries []string) [3]int {
+	var counts [3]int
+	for _, entry := range entries {
+		sig, ok := portDB.Lookup(entry)
+		_, _, state, _ := splitPortEntry(entry)
+		switch {
+		case ok && strings.HasPrefix(state, "open") && sig.Sensitivity == "high":
+			counts[0]++
+		case ok && strings.HasPrefix(state, "open") && sig.Sensitivity == "medium":
+			counts[1]++
+		default:
+			counts[2]++
+		}
+	}
+	return counts
+}
+
+// compareIPs orders addresses numerically, IPv4 before IPv6, with anything
+// that isn't an IP (e.g. a hostname) last in string order
+func compareIPs(a, b string) int {
+	ipA, errA := netip.ParseAddr(a)
+	ipB, errB := netip.ParseAddr(b)
+	switch {
+	case errA == nil && errB == nil:
+		return ipA.Compare(ipB)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// DiffScripts compares NSE script output for ports present in both scans.
+// Only XML scans record script output, so text scans never produce changes.
+func DiffScripts(old, new ScanResult) []ScriptDiff {
+	var diffs []ScriptDiff
+	for _, ip := range sortedHostKeys(new.Hosts) {
+		oldHost, ok := old.Hosts[ip]
+		if !ok {
+			continue
+		}
+		oldPorts := make(map[string]PortEntry, len(oldHost.Ports))
+		for _, p := range oldHost.Ports {
+			oldPorts[fmt.Sprintf("%d/%s", p.Port, p.Protocol)] = p
+		}
+
+		for _, newPort := range new.Hosts[ip].Ports {
+			port := fmt.Sprintf("%d/%s", newPort.Port, newPort.Protocol)
+			oldPort, ok := oldPorts[port]
+			if !ok {
+				continue // New port, already reported as added
+			}
+
+			names := make(map[string]bool)
+			for name := range oldPort.Scripts {
+				names[name] = true
+			}
+			for name := range newPort.Scripts {
+				names[name] = true
+			}
+			for _, name := range sortedHostKeys(names) {
+				if oldPort.Scripts[name] != newPort.Scripts[name] {
+					diffs = append(diffs, ScriptDiff{Host: ip, Port: port, ScriptName: name, OldOutput: oldPort.Scripts[name], NewOutput: newPort.Scripts[name]})
+				}
+			}
+		}
+	}
+	return diffs
+}
+
+// HasChanges reports whether the diff contains any change at all
+func (d DiffReport) HasChanges() bool {
+	return len(d.Hosts) > 0 || len(d.HostStateChanges) > 0 || len(d.ScriptChanges) > 0 || len(d.MACChanges) > 0 || len(d.RouteChanges) > 0 || len(d.OSChanges) > 0 || len(d.DNSChanges) > 0
+}
+
+// Totals returns the number of added and removed ports across all hosts
+func (d DiffReport) Totals() (added, removed int) {
+	for _, h := range d.Hosts {
+		added += len(h.Added)
+		removed += len(h.Removed)
+	}
+	return added, removed
+}
+
+// ChangeCount returns the total number of changes: ports added and removed,
+// hosts coming up or going down, and changed scripts, MACs, routes, OSes and DNS records
+func (d DiffReport) ChangeCount() int {
+	added, removed := d.Totals()
+	return added + removed + len(d.HostStateChanges) + len(d.ScriptChanges) + len(d.MACChanges) + len(d.RouteChanges) + len(d.OSChanges) + len(d.DNSChanges)
+}
+
+// HostCounts returns how many hosts came up and went down
+func (d DiffReport) HostCounts() (up, down int) {
+	for _, c := range d.HostStateChanges {
+		if c.NewState == HostUp {
+			up++
+		} else {
+			down++
+		}
+	}
+	return up, down
+}
+
+// Text renders the diff as plain text (no colour), for notifications and tickets
+func (d DiffReport) Text() string {
+	var sb strings.Builder
+
+	for _, a := range d.Alerts {
+		fmt.Fprintf(&sb, "[!] %s: %s\n", strings.ToUpper(a.Severity), a)
+	}
+	if len(d.Alerts) > 0 {
+		sb.WriteString("\n")
+	}
+
+	for _, h := range d.Hosts {
+		fmt.Fprintf(&sb, "Changes for %s:\n", h.IP)
+		if len(h.Added) > 0 {
+			sb.WriteString("  [+] Added Ports:\n")
+			for _, port := range h.Added {
+				fmt.Fprintf(&sb, "    - %s%s\n", port, unexpectedServiceNote(port))
+			}
+		}
+		if len(h.Removed) > 0 {
+			sb.WriteString("  [-] Removed Ports:\n")
+			for _, port := range h.Removed {
+				fmt.Fprintf(&sb, "    - %s\n", port)
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, c := range d.HostStateChanges {
+		fmt.Fprintf(&sb, "Host %s went %s\n", c.IP, strings.ToUpper(c.NewState))
+	}
+	if len(d.HostStateChanges) > 0 {
+		sb.WriteString("\n")
+	}
+
+	for _, c := range d.MACChanges {
+		fmt.Fprintf(&sb, "%s\n", c)
+	}
+	if len(d.MACChanges) > 0 {
+		sb.WriteString("\n")
+	}
+
+	for _, c := range d.RouteChanges {
+		fmt.Fprintf(&sb, "%s\n", c)
+	}
+	if len(d.RouteChanges) > 0 {
+		sb.WriteString("\n")
+	}
+
+	for _, c := range d.OSChanges {
+		fmt.Fprintf(&sb, "%s\n", c)
+	}
+	if len(d.OSChanges) > 0 {
+		sb.WriteString("\n")
+	}
+
+	for _, c := range d.DNSChanges {
+		fmt.Fprintf(&sb, "%s\n", c)
+	}
+	if len(d.DNSChanges) > 0 {
+		sb.WriteString("\n")
+	}
+
+	if len(d.ScriptChanges) > 0 {
+		sb.WriteString("Script Changes:\n")
+		for _, c := range d.ScriptChanges {
+			fmt.Fprintf(&sb, "  %s %s %s:\n", c.Host, c.Port, c.ScriptName)
+			sb.WriteString(indentScriptOutput("    - ", c.OldOutput))
+			sb.WriteString(indentScriptOutput("    + ", c.NewOutput))
+		}
+		sb.WriteString("\n")
+	}
+
+	writeUnauthorisedPorts(&sb, d.Unauthorised)
+	if !d.HasChanges() {
+		sb.WriteString("No changes detected.\n")
+		return sb.String()
+	}
+
+	added, removed := d.Totals()
+	fmt.Fprintf(&sb, "Summary: %d new ports added, %d removed.\n", added, removed)
+	if up, down := d.HostCounts(); up > 0 || down > 0 {
+		fmt.Fprintf(&sb, "Hosts: %d came up, %d went down.\n", up, down)
+	}
+	if len(d.ScriptChanges) > 0 {
+		fmt.Fprintf(&sb, "Script output changed: %d\n", len(d.ScriptChanges))
+	}
+	if len(d.MACChanges) > 0 {
+		fmt.Fprintf(&sb, "MAC addresses changed: %d\n", len(d.MACChanges))
+	}
+	if len(d.RouteChanges) > 0 {
+		fmt.Fprintf(&sb, "Routes changed: %d\n", len(d.RouteChanges))
+	}
+	if len(d.OSChanges) > 0 {
+		fmt.Fprintf(&sb, "OS fingerprints changed: %d\n", len(d.OSChanges))
+	}
+	if len(d.DNSChanges) > 0 {
+		fmt.Fprintf(&sb, "DNS records changed: %d\n", len(d.DNSChanges))
+	}
+	fmt.Fprintf(&sb, "Risk score: %d\n", d.Score)
+	return sb.String()
+}
+
+// HighRisk reports whether any high-sensitivity port was newly opened
+func (d DiffReport) HighRisk() bool {
+	for _, a := range d.Alerts {
+		if a.Severity == SeverityHigh {
+			return true
+		}
+	}
+	for _, h := range d.Hosts {
+		for _, port := range h.Added {
+			_, _, state, _ := splitPortEntry(port)
+			if strings.HasPrefix(state, "open") && portDB.IsSensitive(port) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Markdown renders the diff as a Markdown document
+func (d DiffReport) Markdown() string {
+	var sb strings.Builder
+
+	title := "PortHunter scan changes"
+	if d.Target != "" {
+		title += " for " + d.Target
+	}
+	fmt.Fprintf(&sb, "# %s\n\n", title)
+	fmt.Fprintf(&sb, "Compared scan **%s** with **%s**.\n\n", d.NewDateTime, d.OldDateTime)
+
+	if !d.HasChanges() {
+		sb.WriteString("No changes detected.\n")
+		return sb.String()
+	}
+
+	added, removed := d.Totals()
+	fmt.Fprintf(&sb, "**Summary:** %d ports added, %d removed. Risk score: %d.\n\n", added, removed, d.Score)
+
+	if len(d.Alerts) > 0 {
+		sb.WriteString("## Alerts\n\n")
+		for _, a := range d.Alerts {
+			fmt.Fprintf(&sb, "- **%s:** %s\n", strings.ToUpper(a.Severity), a)
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.HostStateChanges) > 0 {
+		sb.WriteString("## Host state changes\n\n")
+		for _, c := range d.HostStateChanges {
+			fmt.Fprintf(&sb, "- `%s` went **%s**\n", c.IP, strings.ToUpper(c.NewState))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.MACChanges) > 0 {
+		sb.WriteString("## MAC address changes\n\n")
+		for _, c := range d.MACChanges {
+			fmt.Fprintf(&sb, "- %s\n", c)
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.RouteChanges) > 0 {
+		sb.WriteString("## Route changes\n\n")
+		for _, c := range d.RouteChanges {
+			fmt.Fprintf(&sb, "- %s\n", c)
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.OSChanges) > 0 {
+		sb.WriteString("## OS fingerprint changes\n\n")
+		for _, c := range d.OSChanges {
+			fmt.Fprintf(&sb, "- %s\n", c)
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.DNSChanges) > 0 {
+		sb.WriteString("## DNS changes\n\n")
+		for _, c := range d.DNSChanges {
+			fmt.Fprintf(&sb, "- %s\n", c)
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, h := range d.Hosts {
+		fmt.Fprintf(&sb, "## %s\n\n", h.IP)
+		for _, port := range h.Added {
+			fmt.Fprintf(&sb, "- [+] `%s`%s%s\n", port, markdownSensitiveNote(port), unexpectedServiceNote(port))
+		}
+		for _, port := range h.Removed {
+			fmt.Fprintf(&sb, "- [-] `%s`%s\n", port, markdownSensitiveNote(port))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.ScriptChanges) > 0 {
+		sb.WriteString("## Script changes\n\n")
+		for _, c := range d.ScriptChanges {
+			fmt.Fprintf(&sb, "### %s %s `%s`\n\n", c.Host, c.Port, c.ScriptName)
+			sb.WriteString("```diff\n")
+			sb.WriteString(indentScriptOutput("- ", c.OldOutput))
+			sb.WriteString(indentScriptOutput("+ ", c.NewOutput))
+			sb.WriteString("```\n\n")
+		}
+	}
+	return sb.String()
+}
+
+// indentScriptOutput prefixes every line of a script's output, returning
+// nothing for a script that did not run
+func indentScriptOutput(prefix, output string) string {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return ""
+	}
+	var sb strings.Builder
+	for _, line := range strings.Split(output, "\n") {
+		sb.WriteString(prefix + line + "\n")
+	}
+	return sb.String()
+}
+
+// markdownSensitiveNote flags high-sensitivity ports in Markdown output
+func markdownSensitiveNote(entry string) string {
+	if sig, ok := portDB.Lookup(entry); ok && sig.Sensitivity == "high" {
+		return " **(sensitive: " + sig.Description + ")**"
+	}
+	return ""
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuildDiffReportScoresDownHosts(t *testing.T) {
+	rdp := []string{"22/tcp [open] (ssh)", "3389/tcp [open] (ms-wbt-server)", "445/tcp [filtered] (microsoft-ds)"}
+	up := ScanResult{DateTime: "2024-05-01T10:00:00Z", Ports: map[string][]string{
+		"10.0.0.1": {"80/tcp [open] (http)"},
+		"10.0.0.2": rdp,
+	}}
+	down := ScanResult{DateTime: "2024-05-02T10:00:00Z", Ports: map[string][]string{
+		"10.0.0.1": {"80/tcp [open] (http)"},
+	}}
+
+	want := ScoreDiff(nil, rdp)
+	if want == 0 {
+		t.Fatal("the test's ports score nothing")
+	}
+	report := BuildDiffReport(up, down)
+	if len(report.HostStateChanges) != 1 || report.HostStateChanges[0].NewState != HostDown {
+		t.Fatalf("host state changes %+v, want 10.0.0.2 down", report.HostStateChanges)
+	}
+	if report.Score != want {
+		t.Errorf("host going down scored %d, want %d for its open ports", report.Score, want)
+	}
+	if back := BuildDiffReport(down, up); back.Score != want {
+		t.Errorf("host coming up scored %d, want the same %d", back.Score, want)
+	}
+}
+
+func TestConfirmedPorts(t *testing.T) {
+	prev := []string{"22/tcp [open] (ssh)", "80/tcp [open] (http)"}
+	tests := []struct {
+		name          string
+		first, second []string
+		want          []string
+	}{
+		{
+			name:   "both scans agree",
+			first:  []string{"22/tcp [open] (ssh)", "443/tcp [open] (https)"},
+			second: []string{"22/tcp [open] (ssh)", "443/tcp [open] (https)"},
+			want:   []string{"22/tcp [open] (ssh)", "443/tcp [open] (https)"},
+		},
+		{
+			name:   "transient new port",
+			first:  []string{"22/tcp [open] (ssh)", "80/tcp [open] (http)", "8080/tcp [open] (http-proxy)"},
+			second: prev,
+			want:   prev,
+		},
+		{
+			name:   "transient missing port",
+			first:  []string{"22/tcp [open] (ssh)"},
+			second: prev,
+			want:   prev,
+		},
+		{
+			name:   "transient state change",
+			first:  []string{"22/tcp [filtered] (ssh)", "80/tcp [open] (http)"},
+			second: prev,
+			want:   prev,
+		},
+		{
+			name:   "scans disagree with each other",
+			first:  []string{"22/tcp [closed] (ssh)", "80/tcp [open] (http)"},
+			second: []string{"22/tcp [filtered] (ssh)", "80/tcp [open] (http)"},
+			want:   prev,
+		},
+		{
+			name:   "only the removal confirmed",
+			first:  []string{"22/tcp [open] (ssh)", "3389/tcp [open] (ms-wbt-server)"},
+			second: nil,
+			want:   []string{"22/tcp [open] (ssh)"},
+		},
+	}
+	for _, tt := range tests {
+		if got := confirmedPorts(prev, tt.first, tt.second); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfirmChange(t *testing.T) {
+	fakeNmap(t, "tcp_default.txt")
+	first := HostResult{IP: "192.168.1.10", Hostname: "web01.example.com"}
+
+	second, err := ConfirmChange("192.168.1.10", first, "nmap -sT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"22/tcp [open] (ssh)", "80/tcp [open] (http)", "443/tcp [open] (https)", "8080/tcp [open] (http-proxy)"}
+	if !slices.Equal(second.PortStrings(), want) || second.Hostname != "web01.example.com" {
+		t.Errorf("second scan %v as %q, want %v with the first scan's hostname", second.PortStrings(), second.Hostname, want)
+	}
+
+	// A host missing from the second scan is down
+	gone, err := ConfirmChange("192.168.1.11", HostResult{IP: "192.168.1.11"}, "nmap -sT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gone.State != HostDown || len(gone.Ports) != 0 {
+		t.Errorf("host missing from the second scan is %q with %v", gone.State, gone.PortStrings())
+	}
+}
+
+func TestDiffChain(t *testing.T) {
+	scans := []ScanResult{
+		{DateTime: "2024-05-01T10:00:00Z", Ports: map[string][]string{
+			"10.0.0.1": {"22/tcp [open] (ssh)"},
+			"10.0.0.2": {"80/tcp [open] (http)"},
+		}},
+		{DateTime: "2024-05-02T10:00:00Z", Ports: map[string][]string{
+			"10.0.0.1": {"22/tcp [open] (ssh)", "3389/tcp [open] (ms-wbt-server)"},
+			"10.0.0.2": {"80/tcp [open] (http)"},
+		}},
+		{DateTime: "2024-05-03T10:00:00Z", Ports: map[string][]string{
+			"10.0.0.1": {"22/tcp [open] (ssh)"},
+		}},
+	}
+
+	reports := DiffChain(scans)
+	if len(reports) != 2 {
+		t.Fatalf("%d reports for 3 scans, want 2", len(reports))
+	}
+	// Each report compares a scan with the one before it, not with the first
+	if r := reports[0]; r.OldDateTime != scans[0].DateTime || r.NewDateTime != scans[1].DateTime ||
+		len(r.Hosts) != 1 || !slices.Equal(r.Hosts[0].Added, []string{"3389/tcp [open] (ms-wbt-server)"}) {
+		t.Errorf("1→2: %+v", r)
+	}
+	if r := reports[1]; len(r.Hosts) != 1 || !slices.Equal(r.Hosts[0].Removed, []string{"3389/tcp [open] (ms-wbt-server)"}) ||
+		len(r.HostStateChanges) != 1 || r.HostStateChanges[0].IP != "10.0.0.2" {
+		t.Errorf("2→3: %+v", r)
+	}
+	for _, n := range []int{0, 1} {
+		if got := DiffChain(scans[:n]); len(got) != 0 {
+			t.Errorf("%d reports for %d scans", len(got), n)
+		}
+	}
+
+	var out strings.Builder
+	PrintDiffTimeline([]string{"mon.json", "tue.json", "wed.json"}, scans, reports, &out)
+	for _, want := range []string{
+		"10.0.0.1             3389/tcp   +      -",
+		"10.0.0.2             (host)     .      down",
+		"2→3: 0 added, 1 removed",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("timeline missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestSortDiffBySeverity(t *testing.T) {
+	diff := DiffReport{Hosts: []HostDiff{
+		{IP: "10.0.0.10", Added: []string{"80/tcp [open] (http)"}},
+		{IP: "10.0.0.9", Added: []string{"80/tcp [open] (http)"}},
+		{IP: "10.0.0.3", Added: []string{"8080/tcp [open] (http-proxy)", "80/tcp [open] (http)"}},
+		{IP: "10.0.0.4", Removed: []string{"3389/tcp [open] (ms-wbt-server)"}},
+		{IP: "10.0.0.5", Added: []string{"22/tcp [open] (ssh)", "445/tcp [open] (microsoft-ds)"}},
+		{IP: "10.0.0.6", Added: []string{"3389/tcp [filtered] (ms-wbt-server)", "23/tcp [filtered] (telnet)"}}, // Not open, so low
+		{IP: "10.0.0.7", Added: []string{"3306/tcp [open] (mysql)", "8080/tcp [open] (http-proxy)"}},
+	}}
+
+	// Most high changes first, then medium, then low, then by address
+	sorted := SortDiffBySeverity(diff)
+	var got []string
+	for _, h := range sorted.Hosts {
+		got = append(got, h.IP)
+	}
+	want := []string{"10.0.0.5", "10.0.0.7", "10.0.0.4", "10.0.0.3", "10.0.0.6", "10.0.0.9", "10.0.0.10"}
+	if !slices.Equal(got, want) {
+		t.Errorf("sorted %v, want %v", got, want)
+	}
+	if diff.Hosts[0].IP != "10.0.0.10" {
+		t.Error("SortDiffBySeverity reordered the report it was given")
+	}
+}
+
+func TestBuildDiffReportUnauthorisedPorts(t *testing.T) {
+	asset := &AssetInfo{Owner: "web team", ExpectedPorts: []string{"22", "443/tcp"}}
+	host := func(ports ...string) HostResult {
+		h := HostFromPortStrings("10.0.0.5", ports)
+		h.Asset = asset
+		h.markUnexpectedPorts()
+		return h
+	}
+	old := ScanResult{DateTime: "2024-05-01T10:00:00Z", Ports: map[string][]string{
+		"10.0.0.5": {"22/tcp [open] (ssh)", "3389/tcp [open] (ms-wbt-server)"},
+	}}
+	new := ScanResult{DateTime: "2024-05-02T10:00:00Z", Ports: map[string][]string{
+		"10.0.0.5": {"22/tcp [open] (ssh)", "443/tcp [open] (https)", "3389/tcp [open] (ms-wbt-server)", "8080/tcp [filtered] (http-proxy)"},
+	}}
+	new.Hosts = map[string]HostResult{"10.0.0.5": host(new.Ports["10.0.0.5"]...)}
+
+	// 3389 was already open, so only the full port set shows it
+	report := BuildDiffReport(old, new)
+	if want := []string{"3389/tcp [open] (ms-wbt-server)"}; !slices.Equal(report.Unauthorised["10.0.0.5"], want) {
+		t.Errorf("unauthorised ports %v, want %v", report.Unauthorised, want)
+	}
+	if text := report.Text(); !strings.Contains(text, "Unauthorised ports open: 1") || !strings.Contains(text, "  10.0.0.5: 3389/tcp [open] (ms-wbt-server)\n") {
+		t.Errorf("report text does not list the unauthorised port:\n%s", text)
+	}
+
+	// Without any changes the summary still lists them
+	same := BuildDiffReport(new, new)
+	if text := same.Text(); same.HasChanges() || !strings.Contains(text, "Unauthorised ports open: 1") {
+		t.Errorf("unchanged scan's report:\n%s", text)
+	}
+	if got := unauthorisedPorts(old); got != nil {
+		t.Errorf("scan without CMDB records has unauthorised ports %v", got)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffChain compares each scan with the next, returning one DiffReport per transition
+func DiffChain(scans []ScanResult) []DiffReport {
+	reports := make([]DiffReport, 0, max(0, len(scans)-1))
+	for i := 1; i < len(scans); i++ {
+		reports = append(reports, BuildDiffReport(scans[i-1], scans[i]))
+	}
+	return reports
+}
+
+// timelineRow is one host/port line of the DiffChain timeline
+type timelineRow struct {
+	host  string
+	port  string // "80/tcp", or "(host)" for a host going up or down
+	cells []string
+}
+
+// PrintDiffTimeline writes the changes in reports as a table with a column
+// per transition: "+" added, "-" removed, "~" state or service changed
+func PrintDiffTimeline(names []string, scans []ScanResult, reports []DiffReport, w io.Writer) {
+	fmt.Fprintln(w, "Scans:")
+	for i, scan := range scans {
+		fmt.Fprintf(w, "  %d  %-30s %s\n", i+1, names[i], scan.DateTime)
+	}
+	fmt.Fprintln(w)
+
+	rows := make(map[string]*timelineRow)
+	row := func(host, port string) *timelineRow {
+		key := host + " " + port
+		if rows[key] == nil {
+			rows[key] = &timelineRow{host: host, port: port, cells: make([]string, len(reports))}
+		}
+		return rows[key]
+	}
+	for col, report := range reports {
+		for _, h := range report.Hosts {
+			for _, entry := range h.Added {
+				port, proto, _, _ := splitPortEntry(entry)
+				row(h.IP, port+"/"+proto).cells[col] += "+"
+			}
+			for _, entry := range h.Removed {
+				port, proto, _, _ := splitPortEntry(entry)
+				row(h.IP, port+"/"+proto).cells[col] += "-"
+			}
+		}
+		for _, c := range report.HostStateChanges {
+			row(c.IP, "(host)").cells[col] = c.NewState
+		}
+	}
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "No changes detected.")
+		return
+	}
+
+	sorted := make([]*timelineRow, 0, len(rows))
+	for _, r := range rows {
+		sorted = append(sorted, r)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].host != sorted[j].host {
+			return sorted[i].host < sorted[j].host
+		}
+		return portNumber(sorted[i].port) < portNumber(sorted[j].port)
+	})
+
+	fmt.Fprintf(w, "%-20s %-10s", "HOST", "PORT")
+	for col := range reports {
+		fmt.Fprintf(w, " %-6s", fmt.Sprintf("%d→%d", col+1, col+2))
+	}
+	fmt.Fprintln(w)
+	for _, r := range sorted {
+		fmt.Fprintf(w, "%-20s %-10s", r.host, r.port)
+		for _, cell := range r.cells {
+			switch cell {
+			case "":
+				cell = "."
+			case "+-", "-+":
+				cell = "~"
+			}
+			fmt.Fprintf(w, " %-6s", cell)
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w)
+	for col, report := range reports {
+		added, removed := report.Totals()
+		fmt.Fprintf(w, "%d→%d: %d added, %d removed, risk score %d\n", col+1, col+2, added, removed, report.Score)
+	}
+}
+
+// portNumber returns the number of a "80/tcp" key, or -1 for host rows so they sort first
+func portNumber(key string) int {
+	port, _, _ := strings.Cut(key, "/")
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// runDiff implements "porthunter diff scan1.json scan2.json [scan3.json ...]"
+func runDiff(args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: porthunter diff <scan1> <scan2> [scan3 ...] (JSON, .pb, .nessus or GVM .xml files, oldest first)")
+	}
+	scans := make([]ScanResult, 0, len(args))
+	names := make([]string, 0, len(args))
+	for _, path := range args {
+		scan, err := LoadScanFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		scans = append(scans, scan)
+		names = append(names, filepath.Base(path))
+	}
+	PrintDiffTimeline(names, scans, DiffChain(scans), os.Stdout)
+	return nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// digestTopN is how many hosts and services each digest ranking lists
+const digestTopN = 5
+
+// DigestNotifier collects diff reports and sends them to Backend as a single
+// summary each time Schedule (a cron expression) fires, instead of one
+// notification per scan. Pending reports are kept on disk, so they survive
+// restarts and accumulate across separate runs.
+type DigestNotifier struct {
+	Schedule string
+	Backend  Notifier
+}
+
+// NewDigestNotifier returns the digest for cfg, if a digest schedule and email are configured
+func NewDigestNotifier(cfg Config) (DigestNotifier, bool) {
+	return DigestNotifier{Schedule: cfg.Digest, Backend: cfg.Email}, cfg.Digest != "" && cfg.Email.Enabled()
+}
+
+// digestFile holds the reports waiting for the next digest
+func digestFile() string { return filepath.Join(scanFolder, "digest.json") }
+
+// digestMu serialises access to digestFile between scans and the digest timer
+var digestMu sync.Mutex
+
+// pendingDigest is the content of digestFile
+type pendingDigest struct {
+	Since   time.Time    `json:"since"` // Start of the current digest period
+	Reports []DiffReport `json:"reports"`
+}
+
+func loadPendingDigest(now time.Time) (pendingDigest, error) {
+	pending := pendingDigest{Since: now}
+	data, err := os.ReadFile(digestFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return pending, nil
+	}
+	if err != nil {
+		return pending, err
+	}
+	return pending, json.Unmarshal(data, &pending)
+}
+
+func savePendingDigest(pending pendingDigest) error {
+	if err := EnsureScanFolderExists(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(digestFile(), data, 0644)
+}
+
+// Add queues report for the next digest
+func (d DigestNotifier) Add(report DiffReport) error {
+	digestMu.Lock()
+	defer digestMu.Unlock()
+
+	pending, err := loadPendingDigest(time.Now())
+	if err != nil {
+		return err
+	}
+	pending.Reports = append(pending.Reports, report)
+	return savePendingDigest(pending)
+}
+
+// SendIfDue sends the digest if the schedule has fired since the current
+// period began. A period without changes ends without sending anything.
+func (d DigestNotifier) SendIfDue(now time.Time) error {
+	schedule, err := cron.ParseStandard(d.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid digest schedule %q: %v", d.Schedule, err)
+	}
+
+	digestMu.Lock()
+	defer digestMu.Unlock()
+
+	pending, err := loadPendingDigest(now)
+	if err != nil {
+		return err
+	}
+	if schedule.Next(pending.Since).After(now) {
+		if _, err := os.Stat(digestFile()); errors.Is(err, os.ErrNotExist) {
+			return savePendingDigest(pending) // Start the first period now
+		}
+		return nil
+	}
+
+	if len(pending.Reports) > 0 {
+		subject, body := BuildDigest(pending.Reports, pending.Since, now)
+		if err := d.Backend.Send(subject, body); err != nil {
+			return err // Keep the reports for the next attempt
+		}
+		logger.Info("Digest of %d scans sent", len(pending.Reports))
+	}
+	return savePendingDigest(pendingDigest{Since: now})
+}
+
+// Run sends the digest each time the schedule fires, until ctx is cancelled
+func (d DigestNotifier) Run(ctx context.Context) {
+	schedule, err := cron.ParseStandard(d.Schedule)
+	if err != nil {
+		logger.Error("invalid digest schedule %q: %v", d.Schedule, err)
+		return
+	}
+	for {
+		timer := time.NewTimer(time.Until(schedule.Next(time.Now())))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case now := <-timer.C:
+			if err := d.SendIfDue(now); err != nil {
+				logger.Error("sending digest: %v", err)
+			}
+		}
+	}
+}
+
+// BuildDigest summarises the reports of one digest period: total changes,
+// the hosts with the most changes and the services most often added and removed
+func BuildDigest(reports []DiffReport, since, until time.Time) (subject, body string) {
+	total, added, removed := 0, 0, 0
+	hostChanges := make(map[string]int)
+	addedServices := make(map[string]int)
+	removedServices := make(map[string]int)
+	for _, r := range reports {
+		total += r.ChangeCount()
+		a, rm := r.Totals()
+		added, removed = added+a, removed+rm
+		for ip, n := range hostChangeCounts(r) {
+			hostChanges[ip] += n
+		}
+		for _, h := range r.Hosts {
+			for _, entry := range h.Added {
+				_, _, _, service := splitPortEntry(entry)
+				addedServices[service]++
+			}
+			for _, entry := range h.Removed {
+				_, _, _, service := splitPortEntry(entry)
+				removedServices[service]++
+			}
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "PortHunter digest for %s to %s\n\n", since.Format(time.RFC3339), until.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "Scans with changes: %d\n", len(reports))
+	fmt.Fprintf(&sb, "Total changes:      %d (%d ports added, %d removed)\n", total, added, removed)
+	writeDigestRanking(&sb, "Hosts with the most changes", hostChanges)
+	writeDigestRanking(&sb, "Most added services", addedServices)
+	writeDigestRanking(&sb, "Most removed services", removedServices)
+
+	subject = fmt.Sprintf("PortHunter digest: %d changes in %d scans", total, len(reports))
+	return subject, sb.String()
+}
+
+// hostChangeCounts returns the number of changes per host in report, as counted by ChangeCount
+func hostChangeCounts(report DiffReport) map[string]int {
+	counts := make(map[string]int)
+	for _, h := range report.Hosts {
+		counts[h.IP] += len(h.Added) + len(h.Removed)
+	}
+	for _, c := range report.HostStateChanges {
+		counts[c.IP]++
+	}
+	for _, c := range report.MACChanges {
+		counts[c.IP]++
+	}
+	for _, c := range report.RouteChanges {
+		counts[c.IP]++
+	}
+	for _, c := range report.OSChanges {
+		counts[c.IP]++
+	}
+	for _, c := range report.DNSChanges {
+		counts[c.NewIP]++
+	}
+	for _, c := range report.ScriptChanges {
+		counts[c.Host]++
+	}
+	return counts
+}
+
+// writeDigestRanking lists the digestTopN largest counts, highest first
+func writeDigestRanking(sb *strings.Builder, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	fmt.Fprintf(sb, "\n%s:\n", title)
+	for _, k := range keys[:min(len(keys), digestTopN)] {
+		fmt.Fprintf(sb, "  %-20s %d\n", k, counts[k])
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildDigest(t *testing.T) {
+	reports := []DiffReport{
+		{Hosts: []HostDiff{
+			{IP: "10.0.0.1", Added: []string{"23/tcp [open] (telnet)", "8080/tcp [open] (http-proxy)"}},
+			{IP: "10.0.0.2", Removed: []string{"443/tcp [open] (https)"}},
+		}},
+		{
+			Hosts:            []HostDiff{{IP: "10.0.0.1", Added: []string{"2323/tcp [open] (telnet)"}}},
+			HostStateChanges: []HostStateChange{{IP: "10.0.0.3"}},
+		},
+	}
+	since := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	subject, body := BuildDigest(reports, since, since.Add(24*time.Hour))
+
+	if subject != "PortHunter digest: 5 changes in 2 scans" {
+		t.Errorf("subject %q", subject)
+	}
+	for _, want := range []string{
+		"2024-05-01T09:00:00Z to 2024-05-02T09:00:00Z",
+		"Total changes:      5 (3 ports added, 1 removed)",
+		"Hosts with the most changes:\n  10.0.0.1             3\n  10.0.0.2             1\n  10.0.0.3             1\n",
+		"Most added services:\n  telnet               2\n  http-proxy           1\n",
+		"Most removed services:\n  https                1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("digest does not contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestDigestNotifierSchedule(t *testing.T) {
+	useTempScanFolder(t)
+	backend := &recordingNotifier{}
+	digest := DigestNotifier{Schedule: "0 9 * * *", Backend: backend}
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.Local)
+
+	// The first check starts the period
+	if err := digest.SendIfDue(start); err != nil {
+		t.Fatal(err)
+	}
+	report := DiffReport{Hosts: []HostDiff{{IP: "10.0.0.1", Added: []string{"23/tcp [open] (telnet)"}}}}
+	for range 2 {
+		if err := digest.Add(report); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := digest.SendIfDue(start.Add(30 * time.Minute)); err != nil || len(backend.subjects) != 0 {
+		t.Fatalf("before 9:00: sent %v, %v", backend.subjects, err)
+	}
+	if err := digest.SendIfDue(start.Add(61 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.subjects) != 1 || backend.subjects[0] != "PortHunter digest: 2 changes in 2 scans" {
+		t.Fatalf("after 9:00: sent %v", backend.subjects)
+	}
+
+	// The next period has no reports, so nothing is sent when it ends
+	if err := digest.SendIfDue(start.Add(25 * time.Hour)); err != nil || len(backend.subjects) != 1 {
+		t.Errorf("empty period: sent %v, %v", backend.subjects, err)
+	}
+}
+
+func TestDigestNotifierInvalidSchedule(t *testing.T) {
+	useTempScanFolder(t)
+	digest := DigestNotifier{Schedule: "every day", Backend: &recordingNotifier{}}
+	if err := digest.SendIfDue(time.Now()); err == nil {
+		t.Error("invalid schedule accepted")
+	}
+}
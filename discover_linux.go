@@ -0,0 +1,169 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// DiscoverLocalHosts broadcasts an ARP request for every address on iface's
+// IPv4 subnet and returns the IPs that replied within timeout. It uses an
+// AF_PACKET socket, so it needs root or CAP_NET_RAW. An empty iface picks the
+// first interface that is up, not loopback and has an IPv4 address.
+func DiscoverLocalHosts(iface string, timeout time.Duration) ([]string, error) {
+	ifi, local, err := discoveryInterface(iface)
+	if err != nil {
+		return nil, err
+	}
+	ones, bits := local.Mask.Size()
+	if bits-ones > maxCIDRHostBits {
+		return nil, fmt.Errorf("subnet %s on %s is too large to discover, use /%d or smaller", local, ifi.Name, bits-maxCIDRHostBits)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(syscall.ETH_P_ARP)))
+	if err != nil {
+		return nil, fmt.Errorf("opening ARP socket (needs root or CAP_NET_RAW): %w", err)
+	}
+	defer syscall.Close(fd)
+
+	addr := &syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_ARP), Ifindex: ifi.Index}
+	if err := syscall.Bind(fd, addr); err != nil {
+		return nil, fmt.Errorf("binding ARP socket to %s: %w", ifi.Name, err)
+	}
+	// Short read timeout so the receive loop can notice the overall deadline
+	tv := syscall.NsecToTimeval((100 * time.Millisecond).Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		return nil, err
+	}
+
+	// Collect replies while the requests are going out
+	found := make(chan []string, 1)
+	deadline := time.Now().Add(timeout)
+	go func() { found <- readARPReplies(fd, local, deadline) }()
+
+	broadcast := &syscall.SockaddrLinklayer{Ifindex: ifi.Index, Halen: 6}
+	copy(broadcast.Addr[:], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	src := local.IP.To4()
+	network := src.Mask(local.Mask)
+	var sendErr error
+	for ip := nextIP(network); local.Contains(ip); ip = nextIP(ip) {
+		if ip.Equal(src) {
+			continue
+		}
+		frame := arpRequest(ifi.HardwareAddr, src, ip)
+		if err := syscall.Sendto(fd, frame, 0, broadcast); err != nil {
+			sendErr = fmt.Errorf("sending ARP request for %s: %w", ip, err)
+			break
+		}
+	}
+
+	// Wait for the reader before the deferred close releases the socket
+	hosts := <-found
+	if sendErr != nil {
+		return nil, sendErr
+	}
+	return hosts, nil
+}
+
+// discoveryInterface resolves the interface to scan and its IPv4 network
+func discoveryInterface(name string) (*net.Interface, *net.IPNet, error) {
+	var candidates []net.Interface
+	if name != "" {
+		ifi, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		candidates = []net.Interface{*ifi}
+	} else {
+		all, err := net.Interfaces()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, ifi := range all {
+			if ifi.Flags&net.FlagUp != 0 && ifi.Flags&net.FlagLoopback == 0 {
+				candidates = append(candidates, ifi)
+			}
+		}
+	}
+
+	for i := range candidates {
+		addrs, err := candidates[i].Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() != nil && len(candidates[i].HardwareAddr) == 6 {
+				return &candidates[i], ipnet, nil
+			}
+		}
+	}
+	if name != "" {
+		return nil, nil, fmt.Errorf("interface %s has no IPv4 address", name)
+	}
+	return nil, nil, errors.New("no suitable network interface found for discovery")
+}
+
+// arpRequest builds an Ethernet frame carrying an ARP who-has for target
+func arpRequest(srcMAC net.HardwareAddr, srcIP, target net.IP) []byte {
+	frame := make([]byte, 42)
+	copy(frame[0:6], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(frame[6:12], srcMAC)
+	binary.BigEndian.PutUint16(frame[12:14], syscall.ETH_P_ARP)
+
+	arp := frame[14:]
+	binary.BigEndian.PutUint16(arp[0:2], 1)      // Hardware type: Ethernet
+	binary.BigEndian.PutUint16(arp[2:4], 0x0800) // Protocol type: IPv4
+	arp[4], arp[5] = 6, 4                        // Address lengths
+	binary.BigEndian.PutUint16(arp[6:8], 1)      // Operation: request
+	copy(arp[8:14], srcMAC)
+	copy(arp[14:18], srcIP.To4())
+	copy(arp[24:28], target.To4())
+	return frame
+}
+
+// readARPReplies returns the sender IPs of ARP replies from within local until deadline
+func readARPReplies(fd int, local *net.IPNet, deadline time.Time) []string {
+	seen := make(map[string]bool)
+	buf := make([]byte, 1500)
+	for time.Now().Before(deadline) {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil || n < 42 {
+			continue
+		}
+		arp := buf[14:n]
+		if binary.BigEndian.Uint16(arp[6:8]) != 2 {
+			continue // Not a reply
+		}
+		sender := net.IP(append([]byte(nil), arp[14:18]...))
+		if local.Contains(sender) {
+			seen[sender.String()] = true
+		}
+	}
+
+	hosts := make([]string, 0, len(seen))
+	for ip := range seen {
+		hosts = append(hosts, ip)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return binary.BigEndian.Uint32(net.ParseIP(hosts[i]).To4()) < binary.BigEndian.Uint32(net.ParseIP(hosts[j]).To4())
+	})
+	return hosts
+}
+
+// nextIP returns the IPv4 address following ip
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, 4)
+	binary.BigEndian.PutUint32(next, binary.BigEndian.Uint32(ip.To4())+1)
+	return next
+}
+
+// htons converts a uint16 to network byte order
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
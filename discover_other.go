@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// DiscoverLocalHosts needs AF_PACKET sockets and is only supported on Linux
+func DiscoverLocalHosts(iface string, timeout time.Duration) ([]string, error) {
+	return nil, errors.New("ARP discovery is only supported on Linux")
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsServer is the resolver ResolveWithTTL asks, host or host:port; set from
+// -dns-server. Empty means the first nameserver in /etc/resolv.conf.
+var dnsServer string
+
+// dnsLowTTL is the TTL (seconds) at or below which a record is about to
+// change: operators lower TTLs ahead of a planned move so caches expire quickly
+const dnsLowTTL = 60
+
+// dnsMaxCNAMEs limits how many CNAMEs ResolveWithTTL follows before giving up
+const dnsMaxCNAMEs = 8
+
+// ResolveWithTTL looks hostname's IPv4 address up, following CNAMEs, and
+// returns it with its TTL in seconds: the lowest TTL along the CNAME chain,
+// which is how long the answer may be cached. A recursive resolver returns
+// the time left in its cache; point -dns-server at the zone's authoritative
+// server to read the configured TTL.
+//
+// net.Resolver doesn't expose TTLs, so the query is made directly.
+// A trailing dot ("example.com.") is allowed and ignored.
+func ResolveWithTTL(hostname string) (ip string, ttl int, err error) {
+	server, err := dnsServerAddr()
+	if err != nil {
+		return "", 0, err
+	}
+
+	name := strings.TrimSuffix(hostname, ".")
+	ttl = -1
+	var answers []dnsRecord
+	queried := ""
+	// A response usually carries the whole chain, so the server is only
+	// asked again when it stops short
+	for cnames := 0; cnames <= dnsMaxCNAMEs; {
+		rr, ok := findDNSRecord(answers, name)
+		if !ok {
+			if name == queried {
+				return "", 0, fmt.Errorf("resolving %s: no IPv4 address", hostname)
+			}
+			if answers, err = dnsQuery(server, name); err != nil {
+				return "", 0, fmt.Errorf("resolving %s: %v", hostname, err)
+			}
+			queried = name
+			continue
+		}
+		if ttl < 0 || int(rr.ttl) < ttl {
+			ttl = int(rr.ttl)
+		}
+		if rr.rrType == dnsmessage.TypeA {
+			return rr.data, ttl, nil
+		}
+		name = rr.data
+		cnames++
+	}
+	return "", 0, fmt.Errorf("resolving %s: no A record after %d CNAMEs", hostname, dnsMaxCNAMEs)
+}
+
+// findDNSRecord returns the first answer for name, ignoring case
+func findDNSRecord(answers []dnsRecord, name string) (dnsRecord, bool) {
+	for _, rr := range answers {
+		if strings.EqualFold(rr.name, name) {
+			return rr, true
+		}
+	}
+	return dnsRecord{}, false
+}
+
+// dnsServerAddr returns dnsServer, or the system's first nameserver, as host:port
+func dnsServerAddr() (string, error) {
+	server := dnsServer
+	if server == "" {
+		var err error
+		if server, err = systemNameserver(); err != nil {
+			return "", err
+		}
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	return server, nil
+}
+
+// systemNameserver returns the first nameserver in /etc/resolv.conf
+func systemNameserver() (string, error) {
+	f, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return "", fmt.Errorf("no DNS server configured (use -dns-server): %v", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return fields[1], nil
+		}
+	}
+	return "", errors.New("no nameserver in /etc/resolv.conf (use -dns-server)")
+}
+
+// dnsRecord is one answer of a DNS response: an A record's address or a
+// CNAME's target in data. Names have no trailing dot.
+type dnsRecord struct {
+	name   string
+	rrType dnsmessage.Type
+	ttl    uint32
+	data   string
+}
+
+// dnsQuery asks server for name's A records over UDP and returns the answers
+func dnsQuery(server, name string) ([]dnsRecord, error) {
+	qname, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return nil, fmt.Errorf("invalid hostname %q: %v", name, err)
+	}
+	id := uint16(rand.N(1 << 16))
+	query, err := (&dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}).Pack()
+	if err != nil {
+		return nil, fmt.Errorf("invalid hostname %q: %v", name, err)
+	}
+
+	conn, err := net.DialTimeout("udp", server, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, 4096)
+	for {
+		n, err := conn.Read(resp)
+		if err != nil {
+			return nil, err
+		}
+		var p dnsmessage.Parser
+		if header, err := p.Start(resp[:n]); err == nil && header.Response && header.ID == id {
+			return parseDNSAnswers(&p, header)
+		}
+		// A stray or late reply to an earlier query; keep waiting
+	}
+}
+
+// parseDNSAnswers returns the A and CNAME records in a response's answer
+// section, p having read its header
+func parseDNSAnswers(p *dnsmessage.Parser, header dnsmessage.Header) ([]dnsRecord, error) {
+	switch header.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, errors.New("no such host")
+	default:
+		return nil, fmt.Errorf("DNS server returned %v", header.RCode)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, err
+	}
+
+	var records []dnsRecord
+	for {
+		h, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rr := dnsRecord{name: strings.TrimSuffix(h.Name.String(), "."), rrType: h.Type, ttl: h.TTL}
+		switch {
+		case h.Class != dnsmessage.ClassINET:
+			err = p.SkipAnswer()
+		case h.Type == dnsmessage.TypeA:
+			var a dnsmessage.AResource
+			if a, err = p.AResource(); err == nil {
+				rr.data = netip.AddrFrom4(a.A).String()
+				records = append(records, rr)
+			}
+		case h.Type == dnsmessage.TypeCNAME:
+			var cname dnsmessage.CNAMEResource
+			if cname, err = p.CNAMEResource(); err == nil {
+				rr.data = strings.TrimSuffix(cname.CNAME.String(), ".")
+				records = append(records, rr)
+			}
+		default:
+			err = p.SkipAnswer()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// resolveTargetTTLs resolves the hostnames among a scan's targets and records
+// each one's TTL on the host it resolved to. Names that fail to resolve, or
+// resolve to an address nmap didn't report, are left out.
+func resolveTargetTTLs(scan *ScanResult, target string) {
+	for _, name := range strings.Fields(target) {
+		if !isHostnameTarget(name) {
+			continue
+		}
+		ip, ttl, err := ResolveWithTTL(name)
+		if err != nil {
+			logger.Debug("%v", err)
+			continue
+		}
+		host, ok := scan.Hosts[ip]
+		if !ok {
+			continue
+		}
+		host.Hostname = strings.TrimSuffix(name, ".")
+		host.DNSTTL = ttl
+		scan.Hosts[ip] = host
+	}
+}
+
+// isHostnameTarget reports whether an nmap target is a hostname rather than
+// an address, CIDR block or octet range
+func isHostnameTarget(target string) bool {
+	if net.ParseIP(target) != nil || strings.ContainsAny(target, "/:*,") {
+		return false
+	}
+	return strings.ContainsFunc(target, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-'
+	})
+}
+
+// DNSChange is a scanned hostname whose DNS record changed: it resolves to a
+// different address, or its TTL dropped to dnsLowTTL or below, which often
+// precedes a planned IP change
+type DNSChange struct {
+	Hostname string `json:"hostname"`
+	OldIP    string `json:"old_ip"`
+	NewIP    string `json:"new_ip"`
+	OldTTL   int    `json:"old_ttl"` // Seconds
+	NewTTL   int    `json:"new_ttl"`
+}
+
+func (c DNSChange) String() string {
+	if c.IPChanged() {
+		return fmt.Sprintf("DNS for %s changed from %s to %s (TTL %ds, was %ds)", c.Hostname, c.OldIP, c.NewIP, c.NewTTL, c.OldTTL)
+	}
+	return fmt.Sprintf("DNS TTL for %s (%s) dropped from %ds to %ds, an IP change may be planned", c.Hostname, c.NewIP, c.OldTTL, c.NewTTL)
+}
+
+// IPChanged reports whether the hostname now resolves to a different address
+func (c DNSChange) IPChanged() bool {
+	return c.OldIP != c.NewIP
+}
+
+// DiffDNS lists hostnames resolved in both scans (hosts with a DNSTTL) that
+// now resolve elsewhere or whose TTL dropped to near zero. TTL increases are
+// not reported: a resolver's cached TTL counts down and is reset on expiry.
+func DiffDNS(old, new ScanResult) []DNSChange {
+	oldByName := make(map[string]HostResult)
+	for _, h := range old.Hosts {
+		if h.DNSTTL > 0 && h.Hostname != "" {
+			oldByName[strings.ToLower(h.Hostname)] = h
+		}
+	}
+
+	var changes []DNSChange
+	for _, ip := range sortedHostKeys(new.Hosts) {
+		n := new.Hosts[ip]
+		o, ok := oldByName[strings.ToLower(n.Hostname)]
+		if n.DNSTTL <= 0 || !ok {
+			continue
+		}
+		if o.IP != n.IP || (n.DNSTTL <= dnsLowTTL && o.DNSTTL > dnsLowTTL) {
+			changes = append(changes, DNSChange{Hostname: n.Hostname, OldIP: o.IP, NewIP: n.IP, OldTTL: o.DNSTTL, NewTTL: n.DNSTTL})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeDNSRecord is an answer fakeDNSServer gives: an A record if target is an
+// address, otherwise a CNAME
+type fakeDNSRecord struct {
+	target string
+	ttl    uint32
+}
+
+// fakeDNSServer answers A queries on a local UDP port from records, keyed by
+// lower-case name without the trailing dot, following CNAMEs as a recursive
+// resolver would. It returns the server's address.
+func fakeDNSServer(t *testing.T, records map[string]fakeDNSRecord) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no UDP on loopback: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			q := query.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, RecursionAvailable: true},
+				Questions: query.Questions,
+			}
+			name := strings.ToLower(strings.TrimSuffix(q.Name.String(), "."))
+			if _, ok := records[name]; !ok {
+				resp.RCode = dnsmessage.RCodeNameError
+			}
+			for rr, ok := records[name]; ok && len(resp.Answers) < 20; rr, ok = records[name] {
+				header := dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name + "."), Class: dnsmessage.ClassINET, TTL: rr.ttl}
+				if addr, err := netip.ParseAddr(rr.target); err == nil {
+					resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AResource{A: addr.As4()}})
+					break
+				}
+				resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(rr.target + ".")}})
+				name = rr.target
+			}
+			// Compressed, as real servers send it
+			packed, err := resp.AppendPack(make([]byte, 0, 512))
+			if err == nil {
+				conn.WriteTo(packed, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestResolveWithTTL(t *testing.T) {
+	server := fakeDNSServer(t, map[string]fakeDNSRecord{
+		"www.example.com":   {"10.0.0.5", 300},
+		"app.example.com":   {"lb.example.net", 3600},
+		"lb.example.net":    {"10.0.0.6", 30},
+		"loop.example.com":  {"loop2.example.com", 60},
+		"loop2.example.com": {"loop.example.com", 60},
+	})
+	defer func(saved string) { dnsServer = saved }(dnsServer)
+	dnsServer = server
+
+	tests := []struct {
+		name    string
+		ip      string
+		ttl     int
+		wantErr string
+	}{
+		{name: "www.example.com", ip: "10.0.0.5", ttl: 300},
+		{name: "www.example.com.", ip: "10.0.0.5", ttl: 300}, // Fully qualified
+		{name: "WWW.Example.COM", ip: "10.0.0.5", ttl: 300},
+		{name: "app.example.com", ip: "10.0.0.6", ttl: 30}, // Lowest TTL along the CNAME chain
+		{name: "missing.example.com", wantErr: "no such host"},
+		{name: "loop.example.com", wantErr: "CNAMEs"},
+		{name: "bad..example.com", wantErr: "invalid hostname"},
+	}
+	for _, tt := range tests {
+		ip, ttl, err := ResolveWithTTL(tt.name)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ResolveWithTTL(%q) error %v, want one containing %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || ip != tt.ip || ttl != tt.ttl {
+			t.Errorf("ResolveWithTTL(%q) = %q, %d, %v; want %q, %d", tt.name, ip, ttl, err, tt.ip, tt.ttl)
+		}
+	}
+}
+
+func TestResolveTargetTTLs(t *testing.T) {
+	defer func(saved string) { dnsServer = saved }(dnsServer)
+	dnsServer = fakeDNSServer(t, map[string]fakeDNSRecord{"www.example.com": {"10.0.0.5", 45}})
+
+	scan := ScanResult{Hosts: map[string]HostResult{"10.0.0.5": {IP: "10.0.0.5"}}}
+	resolveTargetTTLs(&scan, "www.example.com. 10.0.0.0/24")
+	if host := scan.Hosts["10.0.0.5"]; host.Hostname != "www.example.com" || host.DNSTTL != 45 {
+		t.Errorf("host %+v, want hostname www.example.com with TTL 45", host)
+	}
+}
+
+func TestDiffDNS(t *testing.T) {
+	scan := func(ip, name string, ttl int) ScanResult {
+		return ScanResult{Hosts: map[string]HostResult{ip: {IP: ip, Hostname: name, DNSTTL: ttl}}}
+	}
+	tests := []struct {
+		name     string
+		old, new ScanResult
+		want     int
+	}{
+		{"unchanged", scan("10.0.0.5", "www.example.com", 300), scan("10.0.0.5", "www.example.com", 280), 0},
+		{"moved", scan("10.0.0.5", "www.example.com", 300), scan("10.0.0.9", "WWW.example.com", 300), 1},
+		{"TTL lowered", scan("10.0.0.5", "www.example.com", 3600), scan("10.0.0.5", "www.example.com", 60), 1},
+		{"already low", scan("10.0.0.5", "www.example.com", 30), scan("10.0.0.5", "www.example.com", 20), 0},
+		{"not resolved before", scan("10.0.0.5", "www.example.com", 0), scan("10.0.0.9", "www.example.com", 300), 0},
+	}
+	for _, tt := range tests {
+		if got := DiffDNS(tt.old, tt.new); len(got) != tt.want {
+			t.Errorf("%s: %d changes %v, want %d", tt.name, len(got), got, tt.want)
+		}
+	}
+}
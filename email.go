@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailConfig holds the SMTP settings for diff notifications
+type EmailConfig struct {
+	Host       string   `yaml:"host"`
+	Port       int      `yaml:"port"`
+	Username   string   `yaml:"username"`
+	Password   string   `yaml:"password"`
+	From       string   `yaml:"from"`
+	To         []string `yaml:"to"`
+	AttachScan bool     `yaml:"attach_scan"` // Attach the full new scan as JSON
+}
+
+// Enabled reports whether enough settings are present to send mail
+func (c EmailConfig) Enabled() bool {
+	return c.Host != "" && c.From != "" && len(c.To) > 0
+}
+
+// SendDiffEmail emails the diff report, optionally attaching the new scan
+func SendDiffEmail(cfg EmailConfig, diff DiffReport, scan ScanResult) error {
+	msg, err := buildDiffEmail(cfg, diff, scan)
+	if err != nil {
+		return err
+	}
+	return sendEmail(cfg, msg)
+}
+
+// Send emails a plain text message, making EmailConfig a Notifier
+func (c EmailConfig) Send(subject, body string) error {
+	if !c.Enabled() {
+		return errors.New("email is not configured (host, from and to are required)")
+	}
+	var buf bytes.Buffer
+	writeEmailHeaders(&buf, c, subject)
+	buf.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return sendEmail(c, buf.Bytes())
+}
+
+// sendEmail connects to the SMTP server, upgrading to TLS when offered, and delivers msg
+func sendEmail(cfg EmailConfig, msg []byte) error {
+	port := cfg.Port
+	if port == 0 {
+		port = 25
+	}
+
+	client, err := smtp.Dial(net.JoinHostPort(cfg.Host, strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("connecting to SMTP server: %v", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+			return fmt.Errorf("starting TLS: %v", err)
+		}
+	}
+
+	return deliverEmail(client, cfg, msg)
+}
+
+// deliverEmail runs the SMTP transaction on an established connection
+func deliverEmail(client *smtp.Client, cfg EmailConfig, msg []byte) error {
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP auth: %v", err)
+		}
+	}
+
+	if err := client.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, to := range cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// writeEmailHeaders writes the headers common to every message
+func writeEmailHeaders(buf *bytes.Buffer, cfg EmailConfig, subject string) {
+	fmt.Fprintf(buf, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(buf, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(buf, "Subject: %s\r\n", subject)
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+}
+
+// buildDiffEmail renders the RFC 5322 message. With AttachScan set it is a
+// multipart/mixed message with a text/plain body and an application/json attachment.
+func buildDiffEmail(cfg EmailConfig, diff DiffReport, scan ScanResult) ([]byte, error) {
+	if !cfg.Enabled() {
+		return nil, errors.New("email is not configured (host, from and to are required)")
+	}
+
+	added, removed := diff.Totals()
+	var buf bytes.Buffer
+	writeEmailHeaders(&buf, cfg, fmt.Sprintf("PortHunter: %d ports added, %d removed", added, removed))
+
+	body := strings.ReplaceAll(diff.Text(), "\n", "\r\n")
+	if !cfg.AttachScan {
+		buf.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+		buf.WriteString(body)
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mw.Boundary())
+
+	text, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=\"utf-8\""},
+	})
+	if err != nil {
+		return nil, err
+	}
+	text.Write([]byte(body))
+
+	data, err := json.MarshalIndent(scan, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	attachment, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"application/json"},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", "scan_"+fileTimestamp(scan.DateTime)+".json")},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Base64 with the 76-character line limit from RFC 2045
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		attachment.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	attachment.Write([]byte(encoded + "\r\n"))
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer answers one SMTP session on conn, recording the envelope and
+// the message sent with DATA
+type fakeSMTPServer struct {
+	from string
+	to   []string
+	data string
+}
+
+func (s *fakeSMTPServer) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP test")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250 localhost")
+		case "MAIL":
+			s.from = arg
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			s.to = append(s.to, arg)
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 Go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				t.Errorf("reading DATA: %v", err)
+				return
+			}
+			s.data = string(data)
+			tp.PrintfLine("250 Queued")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Unknown command %s", verb)
+		}
+	}
+}
+
+func TestDeliverDiffEmailWithAttachment(t *testing.T) {
+	cfg := EmailConfig{Host: "localhost", From: "porthunter@example.com", To: []string{"a@example.com", "b@example.com"}, AttachScan: true}
+	scan := ScanResult{Version: currentScanVersion, DateTime: "2024-05-01T10:00:00Z", Ports: map[string][]string{"10.0.0.1": {"22/tcp [open] (ssh)"}}}
+	diff := DiffReport{Hosts: []HostDiff{{IP: "10.0.0.1", Added: []string{"22/tcp [open] (ssh)"}}}}
+
+	msg, err := buildDiffEmail(cfg, diff, scan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverConn, clientConn := net.Pipe()
+	server := &fakeSMTPServer{}
+	done := make(chan struct{})
+	go func() {
+		server.serve(t, serverConn)
+		close(done)
+	}()
+	client, err := smtp.NewClient(clientConn, "localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deliverEmail(client, cfg, msg); err != nil {
+		t.Fatalf("deliverEmail: %v", err)
+	}
+	<-done
+
+	if server.from != "FROM:<porthunter@example.com>" {
+		t.Errorf("MAIL %s", server.from)
+	}
+	if len(server.to) != 2 {
+		t.Errorf("RCPT %v, want both recipients", server.to)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(server.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Header.Get("Subject"); got != "PortHunter: 1 ports added, 0 removed" {
+		t.Errorf("Subject %q", got)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" || params["boundary"] == "" {
+		t.Fatalf("Content-Type %q: %v", parsed.Header.Get("Content-Type"), err)
+	}
+
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	body, err := parts.NextPart()
+	if err != nil {
+		t.Fatalf("reading the text part with the header's boundary: %v", err)
+	}
+	if ct := body.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("first part is %q, want text/plain", ct)
+	}
+	text, _ := io.ReadAll(body)
+	if !strings.Contains(string(text), "22/tcp [open] (ssh)") {
+		t.Errorf("body does not contain the diff:\n%s", text)
+	}
+
+	attachment, err := parts.NextPart()
+	if err != nil {
+		t.Fatalf("reading the attachment: %v", err)
+	}
+	disposition, dparams, err := mime.ParseMediaType(attachment.Header.Get("Content-Disposition"))
+	if err != nil || disposition != "attachment" || dparams["filename"] != "scan_20240501T100000.json" {
+		t.Errorf("Content-Disposition %q: %v", attachment.Header.Get("Content-Disposition"), err)
+	}
+	if ct := attachment.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("attachment Content-Type %q", ct)
+	}
+	encoded, _ := io.ReadAll(attachment)
+	for _, line := range strings.Fields(string(encoded)) {
+		if len(line) > 76 {
+			t.Errorf("base64 line of %d characters, RFC 2045 allows 76", len(line))
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(encoded)), ""))
+	if err != nil {
+		t.Fatalf("decoding the attachment: %v", err)
+	}
+	var attached ScanResult
+	if err := json.Unmarshal(data, &attached); err != nil || attached.DateTime != scan.DateTime {
+		t.Errorf("attachment is not the scan: %v\n%s", err, data)
+	}
+
+	if _, err := parts.NextPart(); err != io.EOF {
+		t.Errorf("expected two parts, got more (%v)", err)
+	}
+}
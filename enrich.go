@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Enricher adds external context (CVE, GeoIP, ASN, ...) to a host
+type Enricher interface {
+	Name() string
+	Enrich(host *HostResult) error
+}
+
+// RateLimitedEnricher is an Enricher whose upstream API allows at most one
+// request per MinInterval
+type RateLimitedEnricher interface {
+	Enricher
+	MinInterval() time.Duration
+}
+
+// Circuit breaker defaults for enrichers that don't set their own: after
+// this many failures in a row an enricher is skipped for a minute
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerTimeout   = time.Minute
+)
+
+// CircuitBreakingEnricher is an Enricher that chooses its own circuit breaker
+// settings; a threshold of 0 disables the breaker
+type CircuitBreakingEnricher interface {
+	Enricher
+	BreakerSettings() (threshold int, timeout time.Duration)
+}
+
+// ErrCircuitOpen is returned by CircuitBreaker.Call while the breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitBreaker stops calling a failing API: after Threshold consecutive
+// failures it opens, and calls fail immediately with ErrCircuitOpen until
+// Timeout has passed. It is then half-open: a single probe call is let
+// through while the others are still refused, and the probe's success closes
+// the breaker again while its failure reopens it. Safe for concurrent use.
+type CircuitBreaker struct {
+	Threshold int // 0 disables the breaker
+	Timeout   time.Duration
+
+	failures  atomic.Int64 // Consecutive failures
+	openUntil atomic.Int64 // Unix nanoseconds; 0 while closed, calls are refused before it
+	probing   atomic.Bool  // A half-open probe call is in flight
+}
+
+// Allow reports whether a call may go ahead. A caller that is allowed must
+// Record the outcome, or a half-open breaker stays closed to everyone else.
+func (b *CircuitBreaker) Allow() bool {
+	if b.Threshold <= 0 {
+		return true
+	}
+	until := b.openUntil.Load()
+	if until == 0 {
+		return true
+	}
+	if time.Now().UnixNano() < until {
+		return false
+	}
+	return b.probing.CompareAndSwap(false, true)
+}
+
+// Record counts the outcome of a call: a success closes the breaker, and a
+// failure opens it once Threshold calls in a row have failed
+func (b *CircuitBreaker) Record(err error) {
+	if err == nil {
+		b.failures.Store(0)
+		b.openUntil.Store(0)
+		b.probing.Store(false)
+		return
+	}
+	if b.Threshold > 0 && b.failures.Add(1) >= int64(b.Threshold) {
+		b.openUntil.Store(time.Now().Add(b.Timeout).UnixNano())
+		b.probing.Store(false)
+	}
+}
+
+// Call runs fn unless the breaker is open, and records its outcome
+func (b *CircuitBreaker) Call(fn func() error) error {
+	if !b.Allow() {
+		return ErrCircuitOpen
+	}
+	err := fn()
+	b.Record(err)
+	return err
+}
+
+// rateLimiter spaces calls at least interval apart across all workers
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (r *rateLimiter) wait() {
+	r.mu.Lock()
+	now := time.Now()
+	slot := r.next
+	if slot.Before(now) {
+		slot = now
+	}
+	r.next = slot.Add(r.interval)
+	r.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
+
+// EnrichParallel runs every enricher over every host using a pool of at most
+// concurrency workers. Hosts are enriched in place; an enricher failing for
+// one host does not stop the others, and all errors are returned together.
+// Hosts only present as port strings are converted to HostResults first.
+// Each enricher has a circuit breaker, so an API that is down is skipped for
+// the remaining hosts instead of stalling the run on every one of them.
+func EnrichParallel(result *ScanResult, enrichers []Enricher, concurrency int) error {
+	if len(enrichers) == 0 {
+		return nil
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if result.Hosts == nil {
+		result.Hosts = make(map[string]HostResult)
+	}
+
+	// Work on copies; map values are not addressable
+	ips := make([]string, 0, len(result.Ports))
+	for ip := range result.Ports {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	hosts := make([]HostResult, len(ips))
+	for i, ip := range ips {
+		host, ok := result.Hosts[ip]
+		if !ok {
+			host = HostFromPortStrings(ip, result.Ports[ip])
+		}
+		hosts[i] = host
+	}
+
+	limiters := make(map[string]*rateLimiter)
+	breakers := make(map[string]*CircuitBreaker)
+	skipped := make(map[string]*atomic.Int64) // Hosts not enriched because the breaker was open
+	for _, e := range enrichers {
+		if rl, ok := e.(RateLimitedEnricher); ok && rl.MinInterval() > 0 {
+			limiters[e.Name()] = &rateLimiter{interval: rl.MinInterval()}
+		}
+		breaker := &CircuitBreaker{Threshold: defaultBreakerThreshold, Timeout: defaultBreakerTimeout}
+		if cb, ok := e.(CircuitBreakingEnricher); ok {
+			breaker.Threshold, breaker.Timeout = cb.BreakerSettings()
+		}
+		breakers[e.Name()] = breaker
+		skipped[e.Name()] = new(atomic.Int64)
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+		jobs = make(chan int)
+	)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				for _, e := range enrichers {
+					breaker := breakers[e.Name()]
+					if !breaker.Allow() {
+						skipped[e.Name()].Add(1)
+						continue
+					}
+					if limiter := limiters[e.Name()]; limiter != nil {
+						limiter.wait()
+					}
+					err := e.Enrich(&hosts[i])
+					breaker.Record(err)
+					if err != nil {
+						mu.Lock()
+						errs = append(errs, fmt.Errorf("%s: %s: %v", e.Name(), hosts[i].IP, err))
+						mu.Unlock()
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range hosts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, ip := range ips {
+		result.Hosts[ip] = hosts[i]
+	}
+	for _, e := range enrichers {
+		if n := skipped[e.Name()].Load(); n > 0 {
+			errs = append(errs, fmt.Errorf("%s: %w, skipped %d hosts", e.Name(), ErrCircuitOpen, n))
+		}
+	}
+	return errors.Join(errs...)
+}
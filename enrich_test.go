@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := &CircuitBreaker{Threshold: 3, Timeout: 20 * time.Millisecond}
+	failure := errors.New("API down")
+
+	for range 2 {
+		b.Record(failure)
+	}
+	if !b.Allow() {
+		t.Fatal("breaker opened before Threshold failures")
+	}
+	b.Record(failure)
+	if b.Allow() {
+		t.Fatal("breaker still closed after Threshold failures")
+	}
+	if err := b.Call(func() error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Call on an open breaker returned %v, want ErrCircuitOpen", err)
+	}
+
+	// Half-open after Timeout: exactly one of many concurrent callers probes
+	time.Sleep(30 * time.Millisecond)
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if b.Allow() {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed.Load() != 1 {
+		t.Fatalf("%d callers let through while half-open, want 1", allowed.Load())
+	}
+
+	// A failed probe reopens the breaker for another Timeout
+	b.Record(failure)
+	if b.Allow() {
+		t.Error("breaker closed after the probe failed")
+	}
+
+	// A successful probe closes it for everyone
+	time.Sleep(30 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("no probe let through after Timeout")
+	}
+	b.Record(nil)
+	for range 3 {
+		if !b.Allow() {
+			t.Fatal("breaker still refusing calls after a successful probe")
+		}
+	}
+
+	disabled := &CircuitBreaker{}
+	for range 10 {
+		disabled.Record(failure)
+	}
+	if !disabled.Allow() {
+		t.Error("a breaker with Threshold 0 opened")
+	}
+}
+
+// stubEnricher tags each host with its name, failing for the hosts in fail
+type stubEnricher struct {
+	name      string
+	fail      map[string]bool
+	threshold int // Circuit breaker threshold
+	calls     atomic.Int64
+	inFlight  atomic.Int64
+	maxFlight atomic.Int64
+}
+
+func (e *stubEnricher) Name() string { return e.name }
+
+func (e *stubEnricher) BreakerSettings() (int, time.Duration) { return e.threshold, time.Hour }
+
+func (e *stubEnricher) Enrich(host *HostResult) error {
+	e.calls.Add(1)
+	n := e.inFlight.Add(1)
+	defer e.inFlight.Add(-1)
+	for {
+		if m := e.maxFlight.Load(); n <= m || e.maxFlight.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+
+	if e.fail[host.IP] {
+		return errors.New("lookup failed")
+	}
+	host.OS += e.name + ";"
+	return nil
+}
+
+func enrichTestScan(hosts int) ScanResult {
+	scan := ScanResult{Ports: make(map[string][]string)}
+	for i := range hosts {
+		scan.Ports["10.0.0."+string(rune('1'+i))] = []string{"22/tcp [open] (ssh)"}
+	}
+	return scan
+}
+
+func TestEnrichParallel(t *testing.T) {
+	scan := enrichTestScan(8)
+	geo := &stubEnricher{name: "geo", fail: map[string]bool{"10.0.0.3": true}}
+	asn := &stubEnricher{name: "asn"}
+
+	err := EnrichParallel(&scan, []Enricher{geo, asn}, 3)
+	if err == nil || !strings.Contains(err.Error(), "geo: 10.0.0.3: lookup failed") {
+		t.Errorf("error %v, want the failed geo lookup reported", err)
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		t.Error("one failure opened the circuit breaker")
+	}
+	for ip := range scan.Ports {
+		host := scan.Hosts[ip]
+		want := "geo;asn;"
+		if ip == "10.0.0.3" {
+			want = "asn;"
+		}
+		if host.OS != want || host.IP != ip || len(host.Ports) != 1 {
+			t.Errorf("%s enriched as %+v, want tags %q on the converted host", ip, host, want)
+		}
+	}
+	if geo.maxFlight.Load() > 3 || asn.maxFlight.Load() > 3 {
+		t.Errorf("%d and %d concurrent calls, want at most 3", geo.maxFlight.Load(), asn.maxFlight.Load())
+	}
+}
+
+func TestEnrichParallelSkipsOpenBreaker(t *testing.T) {
+	scan := enrichTestScan(6)
+	down := &stubEnricher{name: "down", threshold: 2, fail: make(map[string]bool)}
+	for ip := range scan.Ports {
+		down.fail[ip] = true
+	}
+
+	err := EnrichParallel(&scan, []Enricher{down}, 1)
+	if !errors.Is(err, ErrCircuitOpen) || !strings.Contains(err.Error(), "skipped 4 hosts") {
+		t.Errorf("error %v, want the breaker to skip the 4 hosts after 2 failures", err)
+	}
+	if down.calls.Load() != 2 {
+		t.Errorf("failing API called %d times, want 2", down.calls.Load())
+	}
+	if len(scan.Hosts) != 6 {
+		t.Errorf("%d hosts kept, want all 6 even when not enriched", len(scan.Hosts))
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strings"
+)
+
+// excludeList holds targets that must never be scanned, e.g. honeypots or
+// fragile devices. Entries are IPs, CIDRs or hostname globs such as
+// "*.scada.example.com". Set from -exclude-file.
+var excludeList []string
+
+// ExcludedTargetError is returned by ValidateTarget for a target on the exclusion list
+type ExcludedTargetError struct {
+	Target string
+	Rule   string
+}
+
+func (e *ExcludedTargetError) Error() string {
+	return fmt.Sprintf("target %s is excluded by %q in the exclusion list", e.Target, e.Rule)
+}
+
+// LoadExcludeFile reads an exclusion list: one entry per line, with blank
+// lines and # comments ignored
+func LoadExcludeFile(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		rule, _, _ := strings.Cut(scanner.Text(), "#")
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		if strings.Contains(rule, "/") {
+			if _, _, err := net.ParseCIDR(rule); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", filename, line, err)
+			}
+		} else if _, err := path.Match(rule, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: bad pattern %q", filename, line, rule)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// loadExclusions reads the exclusion list cfg names, if any
+func loadExclusions(cfg Config) ([]string, error) {
+	if cfg.ExcludeFile == "" {
+		return nil, nil
+	}
+	rules, err := LoadExcludeFile(cfg.ExcludeFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading exclusion list: %v", err)
+	}
+	return rules, nil
+}
+
+// matchExclusion returns the exclusion rule in rules covering target, if any. An IP
+// matches an equal IP or a CIDR containing it, a CIDR matches a CIDR that
+// contains it, and a hostname matches a glob. CIDR targets that merely
+// contain an excluded address are not matched; nmapExcludeArgs keeps those
+// addresses out of the scan instead.
+func matchExclusion(rules []string, target string) (string, bool) {
+	target = strings.TrimSpace(target)
+	ip := net.ParseIP(target)
+	_, network, cidrErr := net.ParseCIDR(target)
+
+	for _, rule := range rules {
+		switch _, ruleNet, err := net.ParseCIDR(rule); {
+		case err == nil && ip != nil:
+			if ruleNet.Contains(ip) {
+				return rule, true
+			}
+		case err == nil && cidrErr == nil:
+			ruleOnes, _ := ruleNet.Mask.Size()
+			ones, _ := network.Mask.Size()
+			if ruleNet.Contains(network.IP) && ones >= ruleOnes {
+				return rule, true
+			}
+		case err != nil:
+			if ruleIP := net.ParseIP(rule); ruleIP != nil {
+				if ip != nil && ruleIP.Equal(ip) {
+					return rule, true
+				}
+			} else if ok, _ := path.Match(strings.ToLower(rule), strings.ToLower(target)); ok {
+				return rule, true
+			}
+		}
+	}
+	return "", false
+}
+
+// nmapExcludeArgs returns an nmap --exclude argument for the address rules
+// in an exclusion list, so excluded hosts inside a scanned range are skipped
+func nmapExcludeArgs(rules []string) []string {
+	var addrs []string
+	for _, rule := range rules {
+		if strings.Contains(rule, "/") || net.ParseIP(rule) != nil {
+			addrs = append(addrs, rule)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil
+	}
+	return []string{"--exclude", strings.Join(addrs, ",")}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+// lockScanFile is a no-op where there is no flock; reads and writes of the
+// scan files are not serialised
+func lockScanFile(path string, exclusive bool) (unlock func(), err error) {
+	return func() {}, nil
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// lockScanFile takes an flock(2) lock on the lock file at path: exclusive for
+// a writer, which creates the file if needed and waits for readers to finish,
+// or shared for a reader, which fails straight away with EAGAIN while a write
+// is in progress so LoadPreviousScanWithRetry can back off and try again.
+// Readers never create the file, so read-only data folders can be loaded
+// from; with no lock file nothing has been saved under the lock, and the
+// reader goes ahead unlocked.
+func lockScanFile(path string, exclusive bool) (unlock func(), err error) {
+	flag, how := os.O_RDONLY, syscall.LOCK_SH|syscall.LOCK_NB
+	if exclusive {
+		flag, how = os.O_RDONLY|os.O_CREATE, syscall.LOCK_EX
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	if !exclusive && errors.Is(err, fs.ErrNotExist) {
+		return func() {}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		return nil, &os.PathError{Op: "flock", Path: path, Err: err}
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
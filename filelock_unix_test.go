@@ -0,0 +1,66 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestLockScanFileReaderWithoutLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "previous_scan.lock")
+	unlock, err := lockScanFile(path, false)
+	if err != nil {
+		t.Fatalf("reader without a lock file: %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("reader created the lock file (%v); read-only data folders would fail", err)
+	}
+}
+
+func TestLockScanFileReaderDuringWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "previous_scan.lock")
+	unlockWriter, err := lockScanFile(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("writer did not create the lock file: %v", err)
+	}
+
+	_, err = lockScanFile(path, false)
+	if !errors.Is(err, syscall.EAGAIN) || !isTransientIOError(err) {
+		t.Errorf("reader during a write got %v, want a transient EAGAIN", err)
+	}
+
+	unlockWriter()
+	unlock, err := lockScanFile(path, false)
+	if err != nil {
+		t.Fatalf("reader after the write: %v", err)
+	}
+	unlock()
+}
+
+func TestLocalStoreLatestCreatesNoLockFile(t *testing.T) {
+	useTempScanFolder(t)
+	scan := ScanResult{Version: currentScanVersion, DateTime: "2024-05-01T10:00:00Z", Ports: map[string][]string{"10.0.0.1": {"22/tcp [open] (ssh)"}}}
+	data, err := encodeScan(scan, scanFile(scanFolder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(scanFile(scanFolder), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LocalStore{}.Latest()
+	if err != nil || got.DateTime != scan.DateTime {
+		t.Fatalf("Latest() = %+v, %v", got, err)
+	}
+	if _, err := os.Stat(LocalStore{}.lockPath()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Latest created the lock file (%v)", err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseNmapGrepable extracts port states from nmap's grepable (-oG) output,
+// returning the same structure as ParseNmapOutput. Hosts appear on lines like
+//
+//	Host: 10.0.0.1 (web.local)	Ports: 22/open/tcp//ssh///, 80/closed/tcp//http///
+//	Host: 10.0.0.2 ()	Status: Up
+//
+// A host is recorded once it is reported up or has ports listed.
+func ParseNmapGrepable(output string) (map[string][]string, error) {
+	results := make(map[string][]string)
+
+	for n, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasPrefix(line, "Host: ") {
+			continue // Comments ("# Nmap ...") and blank lines
+		}
+
+		fields := strings.Split(line, "\t")
+		ip, _, _ := strings.Cut(strings.TrimPrefix(fields[0], "Host: "), " ")
+		if ip == "" {
+			return nil, fmt.Errorf("grepable output line %d: missing host address", n+1)
+		}
+
+		for _, field := range fields[1:] {
+			name, value, _ := strings.Cut(field, ": ")
+			switch name {
+			case "Status":
+				if value == "Up" {
+					if _, ok := results[ip]; !ok {
+						results[ip] = []string{}
+					}
+				}
+			case "Ports":
+				for _, tuple := range strings.Split(value, ",") {
+					entry, err := parseGrepablePort(strings.TrimSpace(tuple))
+					if err != nil {
+						return nil, fmt.Errorf("grepable output line %d: %v", n+1, err)
+					}
+					results[ip] = append(results[ip], entry)
+				}
+			}
+		}
+	}
+	for _, entries := range results {
+		sortPortEntries(entries)
+	}
+	return results, nil
+}
+
+// parseGrepablePort converts a -oG port tuple such as "80/open/tcp//http///"
+// (port/state/protocol/owner/service/rpc info/version) into a port entry
+func parseGrepablePort(tuple string) (string, error) {
+	parts := strings.Split(tuple, "/")
+	if len(parts) < 5 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", fmt.Errorf("malformed port %q", tuple)
+	}
+	service := strings.ReplaceAll(parts[4], "|", "/") // "ssl|http" is "ssl/http" in normal output
+	if service == "" {
+		service = "unknown"
+	}
+	return fmt.Sprintf("%s/%s [%s] (%s)", parts[0], parts[2], parts[1], service), nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseNmapGrepableFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    map[string][]string
+	}{
+		{"grepable_multiple_hosts.gnmap", map[string][]string{
+			"10.0.0.1":  {"53/tcp [open] (domain)", "80/tcp [open] (http)", "443/tcp [open] (ssl/http)"},
+			"10.0.0.12": {"3306/tcp [open] (mysql)"},
+			"10.0.0.40": {"80/tcp [open] (http)", "515/tcp [open] (printer)", "9100/tcp [open] (jetdirect)"},
+		}},
+		{"grepable_closed_filtered.gnmap", map[string][]string{
+			"192.168.1.1": {
+				"22/tcp [closed] (ssh)", "25/tcp [closed] (smtp)", "53/udp [open] (domain)",
+				"67/udp [open|filtered] (dhcps)", "80/tcp [open] (http)", "111/tcp [closed] (rpcbind)",
+				"139/tcp [closed] (netbios-ssn)", "161/udp [open|filtered] (snmp)",
+				"445/tcp [closed] (microsoft-ds)", "3389/tcp [closed] (ms-wbt-server)",
+			},
+			"192.168.1.2": {
+				"22/tcp [open] (ssh)", "25/tcp [filtered] (smtp)", "53/udp [closed] (domain)",
+				"67/udp [closed] (dhcps)", "80/tcp [filtered] (http)", "111/tcp [filtered] (rpcbind)",
+				"139/tcp [open] (netbios-ssn)", "161/udp [closed] (snmp)",
+				"445/tcp [open] (microsoft-ds)", "3389/tcp [open] (ms-wbt-server)",
+			},
+			"192.168.1.3": {}, // Up, every port in the ignored state
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseNmapGrepable(string(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v\nwant %v", got, tt.want)
+			}
+
+			if format, err := DetectOutputFormat(string(data)); err != nil || format != FormatGrepable {
+				t.Errorf("detected as %v (%v), want grepable", format, err)
+			}
+		})
+	}
+}
+
+func TestParseNmapGrepable(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{
+			name:   "CRLF line endings",
+			output: "Host: 10.0.0.1 ()\tPorts: 22/open/tcp//ssh///\r\n",
+			want:   map[string][]string{"10.0.0.1": {"22/tcp [open] (ssh)"}},
+		},
+		{
+			name:   "no service name",
+			output: "Host: 10.0.0.1 ()\tPorts: 60000/open/tcp/////\n",
+			want:   map[string][]string{"10.0.0.1": {"60000/tcp [open] (unknown)"}},
+		},
+		{
+			name:   "down hosts are left out",
+			output: "Host: 10.0.0.1 ()\tStatus: Down\n",
+			want:   map[string][]string{},
+		},
+		{
+			name:    "malformed port tuple",
+			output:  "Host: 10.0.0.1 ()\tPorts: 22/open\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNmapGrepable(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
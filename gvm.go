@@ -0,0 +1,196 @@
+package main
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gvmMinQoD is the lowest quality of detection (0-100) a GVM finding needs
+// to be imported. 70 is Greenbone's own default report filter; below it are
+// mostly guesses such as version checks on banners that can't be confirmed.
+const gvmMinQoD = 70
+
+// gvmResult mirrors the parts of a GVM report <result> element that PortHunter uses
+type gvmResult struct {
+	Name string `xml:"name"`
+	Host struct {
+		IP       string `xml:",chardata"`
+		Hostname string `xml:"hostname"`
+	} `xml:"host"`
+	Port string `xml:"port"` // "22/tcp", or "general/tcp" for host-level findings
+	NVT  struct {
+		OID  string `xml:"oid,attr"`
+		Name string `xml:"name"`
+		CVSS string `xml:"cvss_base"`
+		Refs []struct {
+			Type string `xml:"type,attr"`
+			ID   string `xml:"id,attr"`
+		} `xml:"refs>ref"`
+	} `xml:"nvt"`
+	Severity string `xml:"severity"`
+	QoD      int    `xml:"qod>value"`
+}
+
+// gvmHost mirrors a report-level <host> element, which has the host's details
+type gvmHost struct {
+	IP      string `xml:"ip"`
+	Details []struct {
+		Name  string `xml:"name"`
+		Value string `xml:"value"`
+	} `xml:"detail"`
+}
+
+// ParseGVMXML reads an OpenVAS/Greenbone (GVM) XML report so its results can
+// be diffed like PortHunter's own scans. Findings with a quality of detection
+// of at least gvmMinQoD on a TCP or UDP port become open PortEntries, with
+// each finding stored as a script result keyed "gvm-<NVT OID>" (its CVSS
+// score, name and CVEs), so findings can be correlated with CVE enrichment.
+func ParseGVMXML(path string) (ScanResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer f.Close()
+
+	scan := ScanResult{
+		Version:        currentScanVersion,
+		Ports:          make(map[string][]string),
+		Hosts:          make(map[string]HostResult),
+		ScannerVersion: "GVM",
+	}
+	ports := make(map[string]map[string]*PortEntry) // IP -> "22/tcp" -> entry
+	hostFor := func(ip string) HostResult {
+		host, ok := scan.Hosts[ip]
+		if !ok {
+			host = HostResult{IP: ip, State: HostUp}
+		}
+		return host
+	}
+
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ScanResult{}, fmt.Errorf("parsing GVM XML: %v", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "task":
+			var task struct {
+				Name string `xml:"name"`
+			}
+			if err := dec.DecodeElement(&task, &start); err != nil {
+				return ScanResult{}, fmt.Errorf("parsing GVM XML: %v", err)
+			}
+			scan.Target = task.Name
+		case "scan_start":
+			var value string
+			if err := dec.DecodeElement(&value, &start); err != nil {
+				return ScanResult{}, fmt.Errorf("parsing GVM XML: %v", err)
+			}
+			if t, err := time.Parse(time.RFC3339, strings.TrimSpace(value)); err == nil {
+				scan.DateTime = t.UTC().Format(time.RFC3339)
+			}
+		case "ports", "filters", "result_count", "errors":
+			// Summaries and settings; ports are taken from the results
+			if err := dec.Skip(); err != nil {
+				return ScanResult{}, fmt.Errorf("parsing GVM XML: %v", err)
+			}
+		case "result":
+			var r gvmResult
+			if err := dec.DecodeElement(&r, &start); err != nil {
+				return ScanResult{}, fmt.Errorf("parsing GVM XML: %v", err)
+			}
+			ip := strings.TrimSpace(r.Host.IP)
+			host := hostFor(ip)
+			if host.Hostname == "" {
+				host.Hostname = r.Host.Hostname
+			}
+			scan.Hosts[ip] = host
+
+			port, proto, ok := strings.Cut(r.Port, "/")
+			n, err := strconv.Atoi(port)
+			if !ok || err != nil || (proto != "tcp" && proto != "udp") || r.QoD < gvmMinQoD {
+				continue
+			}
+			if ports[ip] == nil {
+				ports[ip] = make(map[string]*PortEntry)
+			}
+			entry, ok := ports[ip][r.Port]
+			if !ok {
+				entry = &PortEntry{Port: n, Protocol: proto, State: "open", Service: "unknown", Scripts: make(map[string]string)}
+				ports[ip][r.Port] = entry
+			}
+			entry.Scripts["gvm-"+r.NVT.OID] = gvmFinding(r)
+		case "host":
+			var h gvmHost
+			if err := dec.DecodeElement(&h, &start); err != nil {
+				return ScanResult{}, fmt.Errorf("parsing GVM XML: %v", err)
+			}
+			host := hostFor(h.IP)
+			for _, d := range h.Details {
+				switch d.Name {
+				case "hostname":
+					host.Hostname = d.Value
+				case "best_os_txt":
+					host.OS = d.Value
+				}
+			}
+			scan.Hosts[h.IP] = host
+		}
+	}
+
+	for ip, host := range scan.Hosts {
+		host.Ports = make([]PortEntry, 0, len(ports[ip]))
+		for _, entry := range ports[ip] {
+			entry.Service = nameUnknownService(entry.Port, entry.Protocol, entry.Service)
+			host.Ports = append(host.Ports, *entry)
+		}
+		sort.Slice(host.Ports, func(i, j int) bool {
+			a, b := host.Ports[i], host.Ports[j]
+			return a.Port < b.Port || (a.Port == b.Port && a.Protocol < b.Protocol)
+		})
+		host.setExposureFlags()
+		scan.Hosts[ip] = host
+		scan.Ports[ip] = host.PortStrings()
+	}
+
+	if scan.DateTime == "" {
+		info, err := f.Stat()
+		if err != nil {
+			return ScanResult{}, err
+		}
+		scan.DateTime = info.ModTime().UTC().Format(time.RFC3339)
+	}
+	return scan, nil
+}
+
+// gvmFinding summarises a result for PortEntry.Scripts, e.g.
+// "[CVSS 4.3, QoD 95] SSH Weak Encryption Algorithms Supported (CVE-2008-5161)"
+func gvmFinding(r gvmResult) string {
+	name := cmp.Or(r.NVT.Name, r.Name)
+	finding := fmt.Sprintf("[CVSS %s, QoD %d] %s", cmp.Or(r.NVT.CVSS, r.Severity), r.QoD, name)
+	var cves []string
+	for _, ref := range r.NVT.Refs {
+		if ref.Type == "cve" {
+			cves = append(cves, ref.ID)
+		}
+	}
+	if len(cves) > 0 {
+		finding += " (" + strings.Join(cves, ", ") + ")"
+	}
+	return finding
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseGVMXMLFixture(t *testing.T) {
+	scan, err := ParseGVMXML(filepath.Join("testdata", "gvm_report.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Host-level (general/tcp) and low-QoD findings are left out, and
+	// services are named from the IANA registry
+	want := map[string][]string{
+		"10.0.5.10": {"22/tcp [open] (ssh)", "25/tcp [open] (smtp)"},
+		"10.0.5.20": {"161/udp [open] (snmp)"},
+		"10.0.5.30": {},
+	}
+	if !reflect.DeepEqual(scan.Ports, want) {
+		t.Errorf("got %v\nwant %v", scan.Ports, want)
+	}
+	if scan.Target != "DMZ weekly" || scan.DateTime != "2025-03-04T10:00:00Z" || scan.ScannerVersion != "GVM" {
+		t.Errorf("target %q, time %q, scanner %q", scan.Target, scan.DateTime, scan.ScannerVersion)
+	}
+
+	mail := scan.Hosts["10.0.5.10"]
+	if mail.Hostname != "mail.example.com" || mail.OS != "Debian GNU/Linux 10" {
+		t.Errorf("10.0.5.10: hostname %q, OS %q", mail.Hostname, mail.OS)
+	}
+	if got := mail.Ports[0].Scripts["gvm-1.3.6.1.4.1.25623.1.0.105611"]; got != "[CVSS 4.3, QoD 95] Weak Encryption Algorithm(s) Supported (SSH) (CVE-2008-5161)" {
+		t.Errorf("22/tcp finding %q", got)
+	}
+	if snmp := scan.Hosts["10.0.5.20"].Ports[0]; len(snmp.Scripts) != 1 {
+		t.Errorf("161/udp findings %q, want only the SNMP one", snmp.Scripts)
+	}
+	if got := scan.Hosts["10.0.5.30"].OS; got != "Cisco IOS" {
+		t.Errorf("10.0.5.30: OS %q from the report's host details", got)
+	}
+}
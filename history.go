@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// indexFile tracks per-host state across the scans saved in the data folder dir
+func indexFile(dir string) string { return filepath.Join(dir, "index.json") }
+
+// Host states
+const (
+	HostUp   = "up"
+	HostDown = "down"
+)
+
+// HostRecord is the last known state of a host
+type HostRecord struct {
+	State    string      `json:"state"`
+	LastSeen string      `json:"last_seen"`
+	MACs     []MACRecord `json:"macs,omitempty"` // Every MAC address the host has answered from
+}
+
+// HistoryIndex is the persisted history store
+type HistoryIndex struct {
+	LastScan string                `json:"last_scan,omitempty"` // DateTime of the most recent scan
+	Hosts    map[string]HostRecord `json:"hosts"`
+}
+
+// HostStateChange records a host going up or down between two scans
+type HostStateChange struct {
+	IP       string `json:"ip"`
+	OldState string `json:"old_state"`
+	NewState string `json:"new_state"`
+}
+
+// DiffHostStates lists hosts that appeared in or disappeared from the new scan
+func DiffHostStates(old, new ScanResult) []HostStateChange {
+	var changes []HostStateChange
+	for ip := range new.Ports {
+		if _, ok := old.Ports[ip]; !ok {
+			changes = append(changes, HostStateChange{IP: ip, OldState: HostDown, NewState: HostUp})
+		}
+	}
+	for ip := range old.Ports {
+		if _, ok := new.Ports[ip]; !ok {
+			changes = append(changes, HostStateChange{IP: ip, OldState: HostUp, NewState: HostDown})
+		}
+	}
+	return changes
+}
+
+// fileTimestamp turns an RFC 3339 scan time into a filename-safe form such as
+// "20240115T120000"
+func fileTimestamp(datetime string) string {
+	t, err := time.Parse(time.RFC3339, datetime)
+	if err != nil {
+		return strings.NewReplacer(":", "", "-", "").Replace(datetime)
+	}
+	return t.Format("20060102T150405")
+}
+
+// LoadHistoryIndex reads the history store in dir, returning an empty index if none exists
+func LoadHistoryIndex(dir string) (HistoryIndex, error) {
+	index := HistoryIndex{Hosts: make(map[string]HostRecord)}
+
+	data, err := os.ReadFile(indexFile(dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return index, err
+	}
+
+	if err := json.Unmarshal(data, &index); err != nil {
+		return index, err
+	}
+	if index.Hosts == nil {
+		index.Hosts = make(map[string]HostRecord)
+	}
+	return index, nil
+}
+
+// SaveHistoryIndex writes the history store in dir
+func SaveHistoryIndex(dir string, index HistoryIndex) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(indexFile(dir), data, 0644)
+}
+
+// UpdateHostIndex marks every host in the scan as up (last seen at the scan time)
+// and every previously known host missing from it as down, in the index in dir
+func UpdateHostIndex(dir string, scan ScanResult) error {
+	index, err := LoadHistoryIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	for ip, record := range index.Hosts {
+		if _, ok := scan.Ports[ip]; !ok {
+			record.State = HostDown
+			index.Hosts[ip] = record
+		}
+	}
+	for ip := range scan.Ports {
+		record := index.Hosts[ip]
+		record.State, record.LastSeen = HostUp, scan.DateTime
+		if host := scan.Hosts[ip]; host.MAC != "" {
+			record.MACs = recordMAC(record.MACs, host.MAC, host.Vendor, scan.DateTime)
+		}
+		index.Hosts[ip] = record
+	}
+	index.LastScan = scan.DateTime
+
+	return SaveHistoryIndex(dir, index)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HostMetrics are nmap's network measurements for a host
+type HostMetrics struct {
+	Latency time.Duration `json:"latency,omitempty"` // From "Host is up (0.0012s latency)."
+	Hops    int           `json:"hops,omitempty"`    // From "Network Distance: 2 hops" (needs -O or --traceroute)
+}
+
+var (
+	// "Host is up (0.0012s latency)."
+	hostLatencyRe = regexp.MustCompile(`^Host is up \(([\d.]+)s latency\)\.?$`)
+	// "Network Distance: 2 hops" ("1 hop" for a neighbour)
+	hostDistanceRe = regexp.MustCompile(`^(?:Network )?Distance: (\d+) hops?\.?$`)
+)
+
+// latencyChangeFactor is how many times slower than in the previous scan a
+// host must answer for DiffRoutes to report a routing change
+const latencyChangeFactor = 10
+
+// latencyChangeFloor keeps jitter on fast local links, where latencies are
+// measured in microseconds, from being reported as a routing change
+const latencyChangeFloor = 10 * time.Millisecond
+
+// ParseHostMetrics extracts the latency and network distance reported for ip
+// from nmap's normal output. Values nmap did not print are left zero.
+func ParseHostMetrics(output string, ip string) HostMetrics {
+	return parseAllHostMetrics(output)[ip]
+}
+
+// parseAllHostMetrics reads the metrics of every host in one pass over the output
+func parseAllHostMetrics(output string) map[string]HostMetrics {
+	all := make(map[string]HostMetrics)
+	var currentIP string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if m := scanReportRe.FindStringSubmatch(line); m != nil {
+			currentIP = m[1]
+			continue
+		}
+		if currentIP == "" {
+			continue
+		}
+		metrics := all[currentIP]
+		if m := hostLatencyRe.FindStringSubmatch(line); m != nil {
+			seconds, _ := strconv.ParseFloat(m[1], 64)
+			metrics.Latency = time.Duration(seconds * float64(time.Second))
+		} else if m := hostDistanceRe.FindStringSubmatch(line); m != nil {
+			metrics.Hops, _ = strconv.Atoi(m[1])
+		} else {
+			continue
+		}
+		all[currentIP] = metrics
+	}
+	return all
+}
+
+// applyHostMetrics copies the latencies and distances in nmap's text output onto the scan's hosts
+func applyHostMetrics(scan *ScanResult, output string) {
+	for ip, metrics := range parseAllHostMetrics(output) {
+		if host, ok := scan.Hosts[ip]; ok {
+			host.HostMetrics = metrics
+			scan.Hosts[ip] = host
+		}
+	}
+}
+
+// latencyJumped reports whether a host answers latencyChangeFactor times
+// slower than before, which suggests it is now reached over a longer path
+func latencyJumped(old, new time.Duration) bool {
+	return old > 0 && new >= latencyChangeFloor && new > old*latencyChangeFactor
+}
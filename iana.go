@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ianaServicesData is a service name registry in the IANA CSV layout
+// (https://www.iana.org/assignments/service-names-port-numbers). The bundled
+// copy covers the commonly used assignments; the full IANA CSV can be dropped
+// in unchanged, since columns are found by their header.
+//
+//go:embed iana-services.csv
+var ianaServicesData []byte
+
+// wellKnownPortMax is the last IANA well-known (system) port. Only these are
+// checked for unexpected services: above it nmap's names often differ from
+// IANA's (e.g. 8080 is "http-proxy" to nmap and "http-alt" to IANA).
+const wellKnownPortMax = 1023
+
+// ianaServices maps "port/proto" to the service names registered for it, and
+// ianaDescriptions to the description of the first of them
+var ianaServices, ianaDescriptions = mustLoadIANAServices()
+
+func mustLoadIANAServices() (map[string][]string, map[string]string) {
+	services, descriptions, err := parseIANAServices(bytes.NewReader(ianaServicesData))
+	if err != nil {
+		panic("invalid embedded IANA service registry: " + err.Error())
+	}
+	return services, descriptions
+}
+
+// parseIANAServices reads an IANA service-names-port-numbers CSV, returning
+// the service names and the first service's description for each port/proto
+func parseIANAServices(r io.Reader) (map[string][]string, map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[name] = i
+	}
+	nameCol, portCol, protoCol := col["Service Name"], col["Port Number"], col["Transport Protocol"]
+	descCol, hasDesc := col["Description"]
+
+	services := make(map[string][]string)
+	descriptions := make(map[string]string)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return services, descriptions, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		name, port, proto := record[nameCol], record[portCol], record[protoCol]
+		if name == "" || proto == "" {
+			continue
+		}
+		// Ranges such as "6000-6063" are left out; single ports are all that's needed
+		if _, err := strconv.Atoi(port); err != nil {
+			continue
+		}
+		key := port + "/" + strings.ToLower(proto)
+		if _, seen := services[key]; !seen && hasDesc && descCol < len(record) {
+			descriptions[key] = record[descCol]
+		}
+		services[key] = append(services[key], strings.ToLower(name))
+	}
+}
+
+// IsUnexpectedService reports whether nmap's service name for an open
+// well-known port differs from every name IANA registers for it, e.g.
+// "unknown" on 80/tcp. That can mean a service masquerading on another's port.
+func IsUnexpectedService(port int, proto, service string) bool {
+	if port > wellKnownPortMax {
+		return false
+	}
+	registered, ok := ianaServices[strconv.Itoa(port)+"/"+proto]
+	if !ok {
+		return false
+	}
+
+	// "http?" (a guess) is still http, and "ssl/http" is http or https
+	service, tls := strings.CutPrefix(strings.ToLower(service), "ssl/")
+	service = NormaliseServiceName(service)
+	if service == "tcpwrapped" {
+		return false // Behind TCP wrappers; nmap could not see the service at all
+	}
+	for _, name := range registered {
+		name = NormaliseServiceName(name)
+		if name == service || (tls && name == service+"s") {
+			return false
+		}
+	}
+	return true
+}
+
+// LookupIANAService returns the first service name IANA registers for port/proto
+func LookupIANAService(port int, proto string) (string, bool) {
+	names := ianaServices[strconv.Itoa(port)+"/"+strings.ToLower(proto)]
+	if len(names) == 0 {
+		return "", false
+	}
+	return names[0], true
+}
+
+// nameUnknownService returns the IANA name for the port when nmap could not
+// identify its service ("unknown" or "tcpwrapped"), and service otherwise
+func nameUnknownService(port int, proto, service string) string {
+	if service != "unknown" && service != "tcpwrapped" {
+		return service
+	}
+	if name, ok := LookupIANAService(port, proto); ok {
+		return name
+	}
+	return service
+}
+
+// markUnexpectedServices flags open ports whose service is not the one registered for them
+func (h *HostResult) markUnexpectedServices() {
+	for i, p := range h.Ports {
+		h.Ports[i].UnexpectedService = p.State == "open" && IsUnexpectedService(p.Port, p.Protocol, p.Service)
+	}
+}
+
+// unexpectedServiceNote returns a short annotation for a port entry whose
+// service is unusual for its port
+func unexpectedServiceNote(entry string) string {
+	p := ParsePortEntry(entry)
+	if p.State != "open" || !IsUnexpectedService(p.Port, p.Protocol, p.Service) {
+		return ""
+	}
+	return "  [unexpected service for this port, possible masquerading]"
+}
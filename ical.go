@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maxICalEvents guards against schedules that would expand to an unusable calendar
+const maxICalEvents = 10000
+
+// icalEventDuration is the nominal length given to each scan event
+const icalEventDuration = time.Hour
+
+// ExportICalSchedule expands a standard 5-field cron expression into an
+// iCalendar document with one VEVENT per scan occurrence within [from, to]
+func ExportICalSchedule(cronExpr string, from, to time.Time, title string) (string, error) {
+	if strings.TrimSpace(cronExpr) == "" {
+		return "", errors.New("no scan schedule configured (set -schedule or schedule: in the config file)")
+	}
+	if to.Before(from) {
+		return "", errors.New("schedule range ends before it starts")
+	}
+
+	schedule, err := cron.ParseStandard(cronExpr)
+	if err != nil {
+		return "", fmt.Errorf("invalid cron expression %q: %v", cronExpr, err)
+	}
+
+	const stampFormat = "20060102T150405Z"
+	now := time.Now().UTC().Format(stampFormat)
+
+	var sb strings.Builder
+	writeLine := func(line string) {
+		sb.WriteString(line)
+		sb.WriteString("\r\n")
+	}
+
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//PortHunter//Scan Schedule//EN")
+	writeLine("CALSCALE:GREGORIAN")
+
+	count := 0
+	// Next returns the first activation strictly after its argument
+	for t := schedule.Next(from.Add(-time.Second)); !t.After(to); t = schedule.Next(t) {
+		count++
+		if count > maxICalEvents {
+			return "", fmt.Errorf("schedule expands to more than %d events, narrow the date range", maxICalEvents)
+		}
+
+		start := t.UTC()
+		writeLine("BEGIN:VEVENT")
+		writeLine(fmt.Sprintf("UID:%s-%d@porthunter", start.Format(stampFormat), count))
+		writeLine("DTSTAMP:" + now)
+		writeLine("DTSTART:" + start.Format(stampFormat))
+		writeLine("DTEND:" + start.Add(icalEventDuration).Format(stampFormat))
+		writeLine("SUMMARY:" + icalEscape(title))
+		writeLine("DESCRIPTION:" + icalEscape("Scheduled PortHunter scan ("+cronExpr+")"))
+		writeLine("END:VEVENT")
+	}
+
+	writeLine("END:VCALENDAR")
+	return sb.String(), nil
+}
+
+// icalEscape escapes text values per RFC 5545 section 3.3.11
+func icalEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// parseICalRange reads "from=YYYY-MM-DD" and "to=YYYY-MM-DD" arguments. The
+// range defaults to the next 30 days; "to" is inclusive of the whole day.
+func parseICalRange(args []string) (from, to time.Time, err error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	from, to = today, today.AddDate(0, 0, 30)
+
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return from, to, fmt.Errorf("expected from=<date> or to=<date>, got %q", arg)
+		}
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid date %q (use YYYY-MM-DD)", value)
+		}
+		switch key {
+		case "from":
+			from = date
+		case "to":
+			to = date.Add(24*time.Hour - time.Second)
+		default:
+			return from, to, fmt.Errorf("unknown range key %q", key)
+		}
+	}
+	return from, to, nil
+}
+
+// RunICalExport prints the iCalendar schedule for the configured cron expression
+func RunICalExport(cfg Config, args []string) error {
+	from, to, err := parseICalRange(args)
+	if err != nil {
+		return err
+	}
+
+	title := "PortHunter scan"
+	if cfg.Target != "" {
+		title += " of " + cfg.Target
+	}
+
+	ics, err := ExportICalSchedule(cfg.Schedule, from, to, title)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.WriteString(ics)
+	return err
+}
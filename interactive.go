@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultDeepCommand is the follow-up scan used for interactively selected hosts
+const defaultDeepCommand = "nmap -A -p-"
+
+// SelectiveRescan runs the deep scan command against each selected target
+func SelectiveRescan(targets []string, deepCommand string) ([]ScanResult, error) {
+	var results []ScanResult
+	for _, target := range targets {
+		fmt.Printf("Deep scanning %s...\n", target)
+		result, err := RunScan(deepCommand, target)
+		if err != nil {
+			return results, fmt.Errorf("deep scan of %s: %v", target, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// PromptHostSelection lists the hosts and reads the user's choice, e.g. "1,3",
+// "2-4" or "all". An empty answer selects nothing.
+func PromptHostSelection(hosts []string, in io.Reader, out io.Writer) ([]string, error) {
+	fmt.Fprintln(out, "\nSelect hosts for a deep re-scan:")
+	for i, host := range hosts {
+		fmt.Fprintf(out, "  [%d] %s\n", i+1, host)
+	}
+	fmt.Fprint(out, "Hosts (e.g. 1,3 or 2-4 or all, Enter to skip): ")
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(line, "all") {
+		return hosts, nil
+	}
+
+	var selected []string
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(line, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q", part)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+				return nil, fmt.Errorf("invalid selection %q", part)
+			}
+		}
+		for n := start; n <= end; n++ {
+			if n < 1 || n > len(hosts) {
+				return nil, fmt.Errorf("selection %d is out of range", n)
+			}
+			if !seen[n] {
+				seen[n] = true
+				selected = append(selected, hosts[n-1])
+			}
+		}
+	}
+	return selected, nil
+}
+
+// RunInteractiveRescan lets the user pick hosts from the scan, deep scans them,
+// prints what the deep scan found compared to the initial scan and merges the
+// deep results into session. The saved baseline is not touched, so later
+// comparisons stay like-for-like with the regular scan command.
+func RunInteractiveRescan(session *ScanResult, deepCommand string, in io.Reader, out io.Writer) error {
+	hosts := make([]string, 0, len(session.Ports))
+	for ip := range session.Ports {
+		hosts = append(hosts, ip)
+	}
+	if len(hosts) == 0 {
+		return nil
+	}
+	sort.Strings(hosts)
+
+	selected, err := PromptHostSelection(hosts, in, out)
+	if err != nil || len(selected) == 0 {
+		return err
+	}
+
+	results, err := SelectiveRescan(selected, deepCommand)
+	for _, deep := range results {
+		for ip, ports := range deep.Ports {
+			initial := ScanResult{DateTime: session.DateTime, Ports: map[string][]string{ip: session.Ports[ip]}}
+			fmt.Fprintf(out, "\n--- Deep scan of %s compared to initial scan ---\n\n", ip)
+			fmt.Fprint(out, BuildDiffReport(initial, deep).Text())
+
+			session.Ports[ip] = ports
+			if host, ok := deep.Hosts[ip]; ok {
+				if session.Hosts == nil {
+					session.Hosts = make(map[string]HostResult)
+				}
+				session.Hosts[ip] = host
+			}
+		}
+	}
+	return err
+}
+
+// errDetailsRequested is returned by ReadUserConfirmation for a "d" answer
+var errDetailsRequested = errors.New("details requested")
+
+// ReadUserConfirmation prints prompt and reads a yes/no answer from reader.
+// "d" returns errDetailsRequested, so callers can show more before asking again.
+func ReadUserConfirmation(prompt string, reader io.Reader) (bool, error) {
+	fmt.Print(prompt)
+	in, ok := reader.(*bufio.Reader)
+	if !ok {
+		in = bufio.NewReader(reader)
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	case "d", "details":
+		return false, errDetailsRequested
+	}
+	return false, fmt.Errorf("unrecognised answer %q", strings.TrimSpace(line))
+}
+
+// ConfirmSaveScan asks whether scan should become the new baseline, for
+// -interactive-save. "d" prints the full diff and the scan's ports first.
+func ConfirmSaveScan(scan ScanResult, report DiffReport, in io.Reader, out io.Writer) (bool, error) {
+	reader := bufio.NewReader(in)
+	for {
+		save, err := ReadUserConfirmation("\nSave this scan? (y/n/d for details): ", reader)
+		switch {
+		case errors.Is(err, errDetailsRequested):
+			printSaveDetails(scan, report, out)
+		case err == io.EOF:
+			return false, nil // Input closed; keep the baseline as it is
+		case err != nil:
+			fmt.Fprintln(out, err)
+		default:
+			return save, nil
+		}
+	}
+}
+
+// printSaveDetails writes the full diff followed by every port in scan
+func printSaveDetails(scan ScanResult, report DiffReport, out io.Writer) {
+	fmt.Fprintln(out, "\n--- Changes since the saved scan ---")
+	if text := report.Text(); text != "" {
+		fmt.Fprintf(out, "\n%s", text)
+	} else {
+		fmt.Fprintln(out, "\nNo changes.")
+	}
+
+	fmt.Fprintf(out, "\n--- Scan of %s ---\n", scan.DateTime)
+	hosts := make([]string, 0, len(scan.Ports))
+	for ip := range scan.Ports {
+		hosts = append(hosts, ip)
+	}
+	sort.Strings(hosts)
+	for _, ip := range hosts {
+		fmt.Fprintf(out, "\n%s:\n", ip)
+		for _, port := range scan.Ports[ip] {
+			fmt.Fprintf(out, "  %s\n", port)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadUserConfirmation(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    bool
+		wantErr error
+	}{
+		{"y\n", true, nil},
+		{" YES \n", true, nil},
+		{"n\n", false, nil},
+		{"no", false, nil}, // No newline before EOF
+		{"d\n", false, errDetailsRequested},
+		{"", false, io.EOF},
+	}
+	for _, tt := range tests {
+		got, err := ReadUserConfirmation("", strings.NewReader(tt.input))
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q: got %v, %v; want %v, %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if _, err := ReadUserConfirmation("", strings.NewReader("maybe\n")); err == nil {
+		t.Error(`"maybe" was accepted`)
+	}
+}
+
+func TestConfirmSaveScan(t *testing.T) {
+	scan := ScanResult{DateTime: "2024-05-01T10:00:00Z", Ports: map[string][]string{"10.0.0.1": {"22/tcp [open] (ssh)"}}}
+	report := DiffReport{Hosts: []HostDiff{{IP: "10.0.0.1", Added: []string{"22/tcp [open] (ssh)"}}}}
+
+	tests := []struct {
+		input       string
+		want        bool
+		wantDetails bool
+	}{
+		{"y\n", true, false},
+		{"n\n", false, false},
+		{"d\nmaybe\ny\n", true, true}, // Details, then an unrecognised answer, then yes
+		{"d\n", false, true},          // Input closed before an answer keeps the baseline
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		got, err := ConfirmSaveScan(scan, report, strings.NewReader(tt.input), &out)
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %v, %v; want %v", tt.input, got, err, tt.want)
+		}
+		if details := strings.Contains(out.String(), "--- Scan of 2024-05-01T10:00:00Z ---"); details != tt.wantDetails {
+			t.Errorf("%q: details printed %v, want %v:\n%s", tt.input, details, tt.wantDetails, out.String())
+		}
+	}
+}
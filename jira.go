@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// jiraTicketsFile remembers when a ticket was last opened for each target (or
+// subject, for alerts sent without one)
+func jiraTicketsFile() string { return filepath.Join(scanFolder, "jira_tickets.json") }
+
+// jiraTicketInterval is the minimum time between tickets for the same target
+const jiraTicketInterval = 24 * time.Hour
+
+// ErrTicketRateLimited is returned when a ticket was already opened for the target recently
+var ErrTicketRateLimited = errors.New("a ticket was already opened for this target in the last 24 hours")
+
+// JIRAClient opens JIRA issues for high-risk scan changes
+type JIRAClient struct {
+	URL        string `yaml:"url"`
+	Username   string `yaml:"username"`
+	APIToken   string `yaml:"api_token"`
+	ProjectKey string `yaml:"project"`
+	IssueType  string `yaml:"issue_type"` // Defaults to "Task"
+}
+
+// Enabled reports whether the client has enough settings to open tickets
+func (c JIRAClient) Enabled() bool {
+	return c.URL != "" && c.ProjectKey != ""
+}
+
+// jiraTicketRecord is the last ticket opened for a target
+type jiraTicketRecord struct {
+	Key     string `json:"key"`
+	Created string `json:"created"`
+}
+
+// CreateTicketForDiff opens an issue describing the diff and returns its key
+// (e.g. "SEC-1234"). At most one ticket is opened per target per 24 hours.
+func (c JIRAClient) CreateTicketForDiff(diff DiffReport) (string, error) {
+	return c.openTicket(diff.Target, "PortHunter: unexpected port changes on "+diff.Target, diff.Markdown())
+}
+
+// Send opens an issue with subject as its summary, so JIRA can be used as a
+// Notifier. Without a target to key on, the 24 hour limit applies per subject.
+func (c JIRAClient) Send(subject, body string) error {
+	return c.SendForTarget(subject, subject, body)
+}
+
+// SendForTarget is Send rate limited per target, so repeated alerts about
+// one target open a single ticket per 24 hours
+func (c JIRAClient) SendForTarget(target, subject, body string) error {
+	_, err := c.openTicket(target, subject, body)
+	return err
+}
+
+// openTicket opens an issue unless one was opened for target in the last
+// jiraTicketInterval, in which case it returns that issue's key and
+// ErrTicketRateLimited
+func (c JIRAClient) openTicket(target, summary, description string) (string, error) {
+	if !c.Enabled() {
+		return "", errors.New("JIRA is not configured (url and project are required)")
+	}
+
+	records := loadJiraTickets()
+	if rec, ok := records[target]; ok {
+		if created, err := time.Parse(time.RFC3339, rec.Created); err == nil && time.Since(created) < jiraTicketInterval {
+			return rec.Key, ErrTicketRateLimited
+		}
+	}
+
+	key, err := c.createIssue(summary, description)
+	if err != nil {
+		return "", err
+	}
+
+	records[target] = jiraTicketRecord{Key: key, Created: time.Now().Format(time.RFC3339)}
+	if err := saveJiraTickets(records); err != nil {
+		logger.Warn("recording JIRA ticket: %v", err)
+	}
+	return key, nil
+}
+
+// createIssue opens an issue and returns its key
+func (c JIRAClient) createIssue(summary, description string) (string, error) {
+	issueType := c.IssueType
+	if issueType == "" {
+		issueType = "Task"
+	}
+	payload := map[string]any{
+		"fields": map[string]any{
+			"project":     map[string]string{"key": c.ProjectKey},
+			"summary":     summary,
+			"description": description,
+			"issuetype":   map[string]string{"name": issueType},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(c.URL, "/")+"/rest/api/2/issue", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(c.Username, c.APIToken)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("JIRA returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return "", fmt.Errorf("decoding JIRA response: %v", err)
+	}
+	return created.Key, nil
+}
+
+func loadJiraTickets() map[string]jiraTicketRecord {
+	records := make(map[string]jiraTicketRecord)
+	if data, err := os.ReadFile(jiraTicketsFile()); err == nil {
+		json.Unmarshal(data, &records)
+	}
+	return records
+}
+
+func saveJiraTickets(records map[string]jiraTicketRecord) error {
+	if err := EnsureScanFolderExists(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(jiraTicketsFile(), data, 0644)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeJIRA counts the issues created through it
+func fakeJIRA(t *testing.T) (JIRAClient, *int) {
+	created := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/rest/api/2/issue" {
+			http.NotFound(w, r)
+			return
+		}
+		created++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"key": "SEC-%d"}`, created)
+	}))
+	t.Cleanup(server.Close)
+	return JIRAClient{URL: server.URL, ProjectKey: "SEC"}, &created
+}
+
+func TestJIRASendRateLimitedPerTarget(t *testing.T) {
+	useTempScanFolder(t)
+	jira, created := fakeJIRA(t)
+
+	if err := jira.SendForTarget("10.0.0.0/24", "PortHunter: 3 scans of 10.0.0.0/24 failed in a row", "first"); err != nil {
+		t.Fatalf("first Send: %v", err)
+	}
+	err := jira.SendForTarget("10.0.0.0/24", "PortHunter: 4 scans of 10.0.0.0/24 failed in a row", "second")
+	if !errors.Is(err, ErrTicketRateLimited) {
+		t.Errorf("second Send for the same target returned %v, want ErrTicketRateLimited", err)
+	}
+	if err := jira.SendForTarget("10.0.1.0/24", "PortHunter: 3 scans of 10.0.1.0/24 failed in a row", "other"); err != nil {
+		t.Errorf("Send for another target: %v", err)
+	}
+	if *created != 2 {
+		t.Errorf("%d tickets opened, want one per target", *created)
+	}
+
+	// A diff ticket shares the limit with alerts for its target
+	if _, err := jira.CreateTicketForDiff(DiffReport{Target: "10.0.0.0/24"}); !errors.Is(err, ErrTicketRateLimited) {
+		t.Errorf("CreateTicketForDiff returned %v, want ErrTicketRateLimited", err)
+	}
+
+	// Plain Sends, like the watchdog's, are limited per subject
+	for range 2 {
+		jira.Send("PortHunter scan overdue", "no scan")
+	}
+	if *created != 3 {
+		t.Errorf("%d tickets opened after repeated watchdog alerts, want 3", *created)
+	}
+}
+
+func TestNotifyScanFailuresOpensOneTicket(t *testing.T) {
+	useTempScanFolder(t)
+	jira, created := fakeJIRA(t)
+
+	for failures := 3; failures <= 5; failures++ {
+		notifyScanFailures(logger, []Notifier{jira}, nil, "10.0.0.0/24", failures, errors.New("nmap exited 1"))
+	}
+	if *created != 1 {
+		t.Errorf("%d tickets opened for repeated failures of one target, want 1", *created)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// patchOp is a single RFC 6902 operation
+type patchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+}
+
+// WriteDiffAsJSONPatch writes an RFC 6902 JSON Patch that turns the stored
+// JSON of old into that of new: new ports are "add" operations, ports that
+// disappeared are "remove" operations and ports whose state or service changed
+// are "replace" operations. Operations for one host are ordered so array
+// indexes stay valid while the patch is applied. Changes to the scan's own
+// fields, such as its command and nmap version, are patched too.
+func WriteDiffAsJSONPatch(old, new ScanResult, w io.Writer) error {
+	ops := []patchOp{}
+	if old.Version != new.Version {
+		ops = append(ops, patchOp{Op: "replace", Path: "/version", Value: new.Version})
+	}
+	if old.DateTime != new.DateTime {
+		ops = append(ops, patchOp{Op: "replace", Path: "/datetime", Value: new.DateTime})
+	}
+	ops = appendOptionalFieldOp(ops, "/target", old.Target, new.Target, old.Target == "", new.Target == "")
+	ops = appendOptionalFieldOp(ops, "/command", old.Command, new.Command, old.Command == "", new.Command == "")
+	ops = appendOptionalFieldOp(ops, "/scanner_version", old.ScannerVersion, new.ScannerVersion, old.ScannerVersion == "", new.ScannerVersion == "")
+	ops = appendOptionalFieldOp(ops, "/per_host_timing", old.PerHostTiming, new.PerHostTiming, len(old.PerHostTiming) == 0, len(new.PerHostTiming) == 0)
+	ops = appendOptionalFieldOp(ops, "/exclude_rules", old.ExcludeRules, new.ExcludeRules, len(old.ExcludeRules) == 0, len(new.ExcludeRules) == 0)
+	ops = appendOptionalFieldOp(ops, "/excluded_targets", old.ExcludedTargets, new.ExcludedTargets, len(old.ExcludedTargets) == 0, len(new.ExcludedTargets) == 0)
+
+	for _, ip := range sortedHostKeys(unionKeys(old.Ports, new.Ports)) {
+		path := "/ports/" + jsonPointerEscape(ip)
+		oldPorts, inOld := old.Ports[ip]
+		newPorts, inNew := new.Ports[ip]
+		switch {
+		case !inOld:
+			ops = append(ops, patchOp{Op: "add", Path: path, Value: newPorts})
+		case !inNew:
+			ops = append(ops, patchOp{Op: "remove", Path: path})
+		default:
+			ops = append(ops, diffPortPatch(path, oldPorts, newPorts)...)
+		}
+	}
+
+	// Full host detail is replaced wholesale when anything about the host changed
+	switch {
+	case len(old.Hosts) == 0 && len(new.Hosts) > 0:
+		ops = append(ops, patchOp{Op: "add", Path: "/hosts", Value: new.Hosts})
+	case len(old.Hosts) > 0 && len(new.Hosts) == 0:
+		ops = append(ops, patchOp{Op: "remove", Path: "/hosts"})
+	default:
+		for _, ip := range sortedHostKeys(unionKeys(old.Hosts, new.Hosts)) {
+			path := "/hosts/" + jsonPointerEscape(ip)
+			oldHost, inOld := old.Hosts[ip]
+			newHost, inNew := new.Hosts[ip]
+			switch {
+			case !inOld:
+				ops = append(ops, patchOp{Op: "add", Path: path, Value: newHost})
+			case !inNew:
+				ops = append(ops, patchOp{Op: "remove", Path: path})
+			case !reflect.DeepEqual(oldHost, newHost):
+				ops = append(ops, patchOp{Op: "replace", Path: path, Value: newHost})
+			}
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ops)
+}
+
+// appendOptionalFieldOp adds the operation for an omitempty field, if it changed
+func appendOptionalFieldOp(ops []patchOp, path string, oldValue, newValue any, oldEmpty, newEmpty bool) []patchOp {
+	switch {
+	case oldEmpty && newEmpty, reflect.DeepEqual(oldValue, newValue):
+		return ops
+	case oldEmpty:
+		return append(ops, patchOp{Op: "add", Path: path, Value: newValue})
+	case newEmpty:
+		return append(ops, patchOp{Op: "remove", Path: path})
+	default:
+		return append(ops, patchOp{Op: "replace", Path: path, Value: newValue})
+	}
+}
+
+// diffPortPatch compares one host's port list. Replacements use the original
+// indexes, removals then run from the highest index down and additions are
+// appended, so each operation's path is valid when it is applied.
+func diffPortPatch(path string, oldPorts, newPorts []string) []patchOp {
+	newByPort := make(map[string]string, len(newPorts))
+	for _, entry := range newPorts {
+		newByPort[portKey(entry)] = entry
+	}
+
+	var replaces, removes []patchOp
+	seen := make(map[string]bool, len(oldPorts))
+	for i, entry := range oldPorts {
+		key := portKey(entry)
+		seen[key] = true
+		next, ok := newByPort[key]
+		switch {
+		case !ok:
+			removes = append(removes, patchOp{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+		case next != entry:
+			replaces = append(replaces, patchOp{Op: "replace", Path: path + "/" + strconv.Itoa(i), Value: next})
+		}
+	}
+
+	ops := replaces
+	for i := len(removes) - 1; i >= 0; i-- {
+		ops = append(ops, removes[i])
+	}
+	for _, entry := range newPorts {
+		if !seen[portKey(entry)] {
+			ops = append(ops, patchOp{Op: "add", Path: path + "/-", Value: entry})
+		}
+	}
+	return ops
+}
+
+// portKey identifies a port entry by number and protocol, e.g. "80/tcp"
+func portKey(entry string) string {
+	port, proto, _, _ := splitPortEntry(entry)
+	return port + "/" + proto
+}
+
+// jsonPointerEscape escapes a map key for use in an RFC 6901 JSON Pointer
+func jsonPointerEscape(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// unionKeys returns the set of keys present in either map
+func unionKeys[V any](a, b map[string]V) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	return keys
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// applyJSONPatch applies the add, remove and replace operations of an RFC
+// 6902 patch to a decoded JSON document
+func applyJSONPatch(doc any, ops []patchOp) (any, error) {
+	for _, op := range ops {
+		// Round-trip the value so it has the same types as the decoded document
+		var value any
+		if op.Op != "remove" {
+			data, err := json.Marshal(op.Value)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal(data, &value)
+		}
+		var err error
+		if doc, err = applyPatchOp(doc, strings.Split(op.Path, "/")[1:], op.Op, value); err != nil {
+			return nil, fmt.Errorf("%s %s: %v", op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func applyPatchOp(node any, path []string, op string, value any) (any, error) {
+	key := strings.NewReplacer("~1", "/", "~0", "~").Replace(path[0])
+	switch n := node.(type) {
+	case map[string]any:
+		if len(path) > 1 {
+			child, ok := n[key]
+			if !ok {
+				return nil, fmt.Errorf("no member %q", key)
+			}
+			updated, err := applyPatchOp(child, path[1:], op, value)
+			n[key] = updated
+			return n, err
+		}
+		_, exists := n[key]
+		switch {
+		case op == "remove" && exists:
+			delete(n, key)
+		case op == "replace" && exists, op == "add":
+			n[key] = value
+		default:
+			return nil, fmt.Errorf("no member %q", key)
+		}
+		return n, nil
+	case []any:
+		if key == "-" && op == "add" && len(path) == 1 {
+			return append(n, value), nil
+		}
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(n) {
+			return nil, fmt.Errorf("bad index %q", key)
+		}
+		if len(path) > 1 {
+			n[i], err = applyPatchOp(n[i], path[1:], op, value)
+			return n, err
+		}
+		switch op {
+		case "remove":
+			return append(n[:i], n[i+1:]...), nil
+		case "replace":
+			n[i] = value
+			return n, nil
+		}
+		return nil, fmt.Errorf("unsupported %s at an index", op)
+	}
+	return nil, fmt.Errorf("%q is not in an object or array", key)
+}
+
+// decodeJSON returns the stored JSON of a scan as generic values
+func decodeJSON(t *testing.T, scan ScanResult) any {
+	t.Helper()
+	data, err := json.Marshal(scan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestWriteDiffAsJSONPatch(t *testing.T) {
+	old := ScanResult{
+		Version:         1,
+		DateTime:        "2024-05-01T10:00:00Z",
+		Target:          "10.0.0.0/24",
+		Command:         "nmap -sT 10.0.0.0/24",
+		ScannerVersion:  "7.93",
+		ExcludeRules:    []string{"10.0.0.254"},
+		ExcludedTargets: []string{"10.0.0.254"},
+		Ports: map[string][]string{
+			"10.0.0.1": {"22/tcp [open] (ssh)", "23/tcp [open] (telnet)", "80/tcp [open] (http)", "443/tcp [open] (https)"},
+			"10.0.0.2": {"3306/tcp [open] (mysql)"},
+		},
+	}
+	new := ScanResult{
+		Version:        currentScanVersion,
+		DateTime:       "2024-05-02T10:00:00Z",
+		Target:         "10.0.0.0/24",
+		Command:        "nmap -sT -p- 10.0.0.0/24",
+		ScannerVersion: "7.94",
+		PerHostTiming:  map[string]time.Duration{"10.0.0.1": time.Second},
+		ExcludeRules:   []string{"10.0.0.254", "10.0.0.253"},
+		Ports: map[string][]string{
+			"10.0.0.1": {"22/tcp [filtered] (ssh)", "443/tcp [open] (https)", "8080/tcp [open] (http-proxy)"},
+			"10.0.0.3": {"80/tcp [open] (http)"},
+		},
+		Hosts: map[string]HostResult{"10.0.0.3": {IP: "10.0.0.3", Ports: []PortEntry{{Port: 80, Protocol: "tcp", State: "open", Service: "http"}}}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDiffAsJSONPatch(old, new, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var ops []patchOp
+	if err := json.Unmarshal(buf.Bytes(), &ops); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := make(map[string]string)
+	for _, op := range ops {
+		paths[op.Path] = op.Op
+	}
+	for path, op := range map[string]string{
+		"/version":          "replace",
+		"/command":          "replace",
+		"/scanner_version":  "replace",
+		"/per_host_timing":  "add",
+		"/exclude_rules":    "replace",
+		"/excluded_targets": "remove",
+	} {
+		if paths[path] != op {
+			t.Errorf("%s: got op %q, want %q", path, paths[path], op)
+		}
+	}
+	if _, ok := paths["/target"]; ok {
+		t.Error("patch replaces the unchanged target")
+	}
+
+	patched, err := applyJSONPatch(decodeJSON(t, old), ops)
+	if err != nil {
+		t.Fatalf("applying the patch: %v\n%s", err, buf.String())
+	}
+	if want := decodeJSON(t, new); !reflect.DeepEqual(patched, want) {
+		got, _ := json.MarshalIndent(patched, "", "  ")
+		t.Errorf("patched old scan differs from the new one:\n%s\npatch:\n%s", got, buf.String())
+	}
+}
+
+func TestWriteDiffAsJSONPatchUnchanged(t *testing.T) {
+	scan := ScanResult{Version: currentScanVersion, DateTime: "2024-05-01T10:00:00Z", Command: "nmap 10.0.0.1",
+		Ports: map[string][]string{"10.0.0.1": {"22/tcp [open] (ssh)"}}}
+	var buf bytes.Buffer
+	if err := WriteDiffAsJSONPatch(scan, scan, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != "[]" {
+		t.Errorf("got %s, want an empty patch", got)
+	}
+}
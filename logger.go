@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Verbosity levels set with -v, -vv and -vvv
+const (
+	VerbosityQuiet   = 0 // Warnings, errors and the diff summary only
+	VerbositySummary = 1 // -v: elapsed times in full, scan summaries
+	VerbosityHost    = 2 // -vv: per-host progress (Info)
+	VerbosityPort    = 3 // -vvv: per-port detail and executed commands (Debug)
+)
+
+// Logger writes levelled log messages
+type Logger interface {
+	Debug(format string, args ...any) // Shown at verbosity 3
+	Info(format string, args ...any)  // Shown at verbosity 2 and above
+	Warn(format string, args ...any)  // Always shown
+	Error(format string, args ...any) // Always shown
+}
+
+// verbosity is the active level, for output that is not a log message
+var verbosity = VerbosityQuiet
+
+// logger is the process-wide logger, replaced in main once flags are parsed
+var logger Logger = NewLogger(os.Stdout, VerbosityQuiet)
+
+// levelLogger is the standard Logger implementation
+type levelLogger struct {
+	out   io.Writer
+	level int
+}
+
+// NewLogger returns a Logger writing to out at the given verbosity
+func NewLogger(out io.Writer, level int) Logger {
+	return &levelLogger{out: out, level: level}
+}
+
+func (l *levelLogger) Debug(format string, args ...any) {
+	if l.level >= VerbosityPort {
+		fmt.Fprintf(l.out, "[debug] "+format+"\n", args...)
+	}
+}
+
+func (l *levelLogger) Info(format string, args ...any) {
+	if l.level >= VerbosityHost {
+		fmt.Fprintf(l.out, format+"\n", args...)
+	}
+}
+
+func (l *levelLogger) Warn(format string, args ...any) {
+	c := Colours()
+	fmt.Fprintf(l.out, c.Warning+"Warning:"+c.Reset+" "+format+"\n", args...)
+}
+
+func (l *levelLogger) Error(format string, args ...any) {
+	c := Colours()
+	fmt.Fprintf(l.out, c.Removed+"Error:"+c.Reset+" "+format+"\n", args...)
+}
+
+// verbosityFlag is a counting flag: each -v adds one, -v=N sets the level
+type verbosityFlag struct {
+	level *int
+	step  int // Levels added by a bare occurrence (-vv registers with step 2)
+}
+
+func (v verbosityFlag) String() string {
+	if v.level == nil {
+		return "0"
+	}
+	return strconv.Itoa(*v.level)
+}
+
+func (v verbosityFlag) Set(s string) error {
+	if s == "true" {
+		*v.level += v.step
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("verbosity must be a number")
+	}
+	*v.level = n
+	return nil
+}
+
+func (v verbosityFlag) IsBoolFlag() bool { return true }
+
+// registerVerbosityFlags adds -v/-verbose, -vv and -vvv
+func registerVerbosityFlags(fs *flag.FlagSet, level *int) {
+	usage := "Verbosity: -v summary, -vv per-host, -vvv per-port detail (or -v=N)"
+	fs.Var(verbosityFlag{level, 1}, "v", usage)
+	fs.Var(verbosityFlag{level, 1}, "verbose", usage)
+	fs.Var(verbosityFlag{level, 2}, "vv", "Same as -v=2")
+	fs.Var(verbosityFlag{level, 3}, "vvv", "Same as -v=3")
+}
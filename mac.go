@@ -0,0 +1,106 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// "MAC Address: 00:11:22:33:44:55 (Dell)", printed for hosts on the local network
+var macAddressRe = regexp.MustCompile(`^MAC Address: ([0-9A-Fa-f]{2}(?::[0-9A-Fa-f]{2}){5})(?: \((.*)\))?$`)
+
+// parseMACAddress extracts the MAC address and vendor from an nmap "MAC Address:" line
+func parseMACAddress(line string) (mac, vendor string, ok bool) {
+	m := macAddressRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return "", "", false
+	}
+	vendor = m[2]
+	if vendor == "Unknown" {
+		vendor = ""
+	}
+	return strings.ToUpper(m[1]), vendor, true
+}
+
+// applyMACAddresses copies the MAC addresses in nmap's text output onto the
+// scan's hosts. The MAC line follows the host's "Nmap scan report for" line.
+func applyMACAddresses(scan *ScanResult, output string) {
+	var currentIP string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if m := scanReportRe.FindStringSubmatch(line); m != nil {
+			currentIP = m[1]
+		} else if mac, vendor, ok := parseMACAddress(line); ok && currentIP != "" {
+			if host, ok := scan.Hosts[currentIP]; ok {
+				host.MAC, host.Vendor = mac, vendor
+				scan.Hosts[currentIP] = host
+			}
+		}
+	}
+}
+
+// MACRecord is a MAC address seen for a host, kept in the history index
+type MACRecord struct {
+	MAC       string `json:"mac"`
+	Vendor    string `json:"vendor,omitempty"`
+	FirstSeen string `json:"first_seen"`
+	LastSeen  string `json:"last_seen"`
+}
+
+// recordMAC adds a sighting of mac to a host's MAC history
+func recordMAC(history []MACRecord, mac, vendor, seen string) []MACRecord {
+	for i, rec := range history {
+		if rec.MAC == mac {
+			history[i].LastSeen = seen
+			if vendor != "" {
+				history[i].Vendor = vendor
+			}
+			return history
+		}
+	}
+	return append(history, MACRecord{MAC: mac, Vendor: vendor, FirstSeen: seen, LastSeen: seen})
+}
+
+// MACChange is a host whose IP now answers from a different MAC address,
+// e.g. replaced hardware or an ARP spoofing attempt
+type MACChange struct {
+	IP        string `json:"ip"`
+	OldMAC    string `json:"old_mac"`
+	OldVendor string `json:"old_vendor,omitempty"`
+	NewMAC    string `json:"new_mac"`
+	NewVendor string `json:"new_vendor,omitempty"`
+}
+
+func (c MACChange) String() string {
+	s := "MAC address of " + c.IP + " changed from " + macWithVendor(c.OldMAC, c.OldVendor) + " to " + macWithVendor(c.NewMAC, c.NewVendor)
+	if c.VendorChanged() {
+		s += ", a different vendor"
+	}
+	return s
+}
+
+// VendorChanged reports whether the new MAC belongs to a different manufacturer
+func (c MACChange) VendorChanged() bool {
+	return c.OldVendor != c.NewVendor
+}
+
+// DiffMACs lists hosts whose MAC address differs between the scans. Hosts
+// without a MAC in either scan (e.g. beyond the local network) are skipped.
+func DiffMACs(old, new ScanResult) []MACChange {
+	var changes []MACChange
+	for _, ip := range sortedHostKeys(new.Hosts) {
+		n, o := new.Hosts[ip], old.Hosts[ip]
+		if n.MAC == "" || o.MAC == "" || n.MAC == o.MAC {
+			continue
+		}
+		changes = append(changes, MACChange{IP: ip, OldMAC: o.MAC, OldVendor: o.Vendor, NewMAC: n.MAC, NewVendor: n.Vendor})
+	}
+	return changes
+}
+
+// macWithVendor formats a MAC as "00:11:22:33:44:55 (Dell)"
+func macWithVendor(mac, vendor string) string {
+	if vendor == "" {
+		return mac
+	}
+	return mac + " (" + vendor + ")"
+}
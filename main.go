@@ -2,45 +2,121 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
+	"cmp"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
+
+	"golang.org/x/term"
 )
 
 // ScanResult stores discovered open/closed/filtered ports and services
 type ScanResult struct {
-	DateTime string              `json:"datetime"`
-	Ports    map[string][]string `json:"ports"`
+	Version  int                   `json:"version"` // Schema version, see currentScanVersion
+	DateTime string                `json:"datetime"`
+	Ports    map[string][]string   `json:"ports"`
+	Hosts    map[string]HostResult `json:"hosts,omitempty"`  // Full per-host detail (XML scans)
+	Target   string                `json:"target,omitempty"` // What was scanned; set by RunOnce and RunScanBatch
+
+	// How the scan was run: the command line as executed, targets and
+	// exclusions included, and the version of nmap that ran it
+	Command        string `json:"command,omitempty"`
+	ScannerVersion string `json:"scanner_version,omitempty"`
+
+	// Scan time per host in nanoseconds, recorded by batch scans
+	PerHostTiming map[string]time.Duration `json:"per_host_timing,omitempty"`
+
+	// Exclusion list in force for the scan, and the targets it skipped
+	ExcludeRules    []string `json:"exclude_rules,omitempty"`
+	ExcludedTargets []string `json:"excluded_targets,omitempty"`
 }
 
-// File paths
-const scanFolder = "scan_data"
-const scanFile = scanFolder + "/previous_scan.json"
-const backupScanFile = scanFolder + "/previous_previous_scan.json"
+// dataDirEnv sets the default scan data folder, so subcommands such as "ctl"
+// find it without the -data-dir flag
+const dataDirEnv = "PORTHUNTER_DATA_DIR"
+
+// scanFolder holds everything PortHunter stores; set from -data-dir
+var scanFolder = cmp.Or(os.Getenv(dataDirEnv), "scan_data")
+
+// File paths within the data folder dir
+func scanFile(dir string) string       { return filepath.Join(dir, "previous_scan.json") }
+func backupScanFile(dir string) string { return filepath.Join(dir, "previous_previous_scan.json") }
 
-// EnsureScanFolderExists creates the scan_data folder if it doesn't exist
+// discoverTimeout is how long -discover-first waits for ARP replies
+const discoverTimeout = 2 * time.Second
+
+// EnsureScanFolderExists creates the scan data folder, and any missing
+// parents, if it doesn't exist
 func EnsureScanFolderExists() error {
-	if _, err := os.Stat(scanFolder); os.IsNotExist(err) {
-		return os.Mkdir(scanFolder, 0755)
+	return os.MkdirAll(scanFolder, 0755)
+}
+
+// ScanOptions controls optional behaviour of RunScanWithOptions
+type ScanOptions struct {
+	RawOutput  io.Writer // If set, receives a copy of nmap's raw stdout
+	Quiet      bool      // Suppress the spinner (e.g. for concurrent scans)
+	Tail       bool      // Print nmap's output as it arrives instead of the spinner
+	Exclusions []string  // Targets never to scan (nil: the -exclude-file list)
+	Logger     Logger    // Where skipped targets are reported (nil: the package logger)
+}
+
+// exclusions returns the exclusion list in force for the scan
+func (o ScanOptions) exclusions() []string {
+	if o.Exclusions == nil {
+		return excludeList
 	}
-	return nil
+	return o.Exclusions
 }
 
+// logger returns the Logger for the scan
+func (o ScanOptions) logger() Logger { return cmp.Or(o.Logger, logger) }
+
 // RunScan executes the user-supplied Nmap command and returns the results
 func RunScan(command string, target string) (ScanResult, error) {
+	return RunScanWithOptions(command, target, ScanOptions{Tail: verbosity >= VerbosityHost})
+}
+
+// RunScanWithOptions is RunScan with extra options
+func RunScanWithOptions(command string, target string, opts ScanOptions) (ScanResult, error) {
 	// Validate input
-	command = strings.TrimSpace(command)
-	target = strings.TrimSpace(target)
+	command, err := SanitiseScanCommand(command)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	target = FormatTarget(strings.TrimSpace(target))
 	if command == "" {
 		return ScanResult{}, errors.New("scan command cannot be empty")
 	}
-	if target == "" {
-		return ScanResult{}, errors.New("target cannot be empty")
+	// Several space-separated targets may be given, e.g. from -discover-first
+	fields := strings.Fields(target)
+	if len(fields) == 0 {
+		return ScanResult{}, validateTarget(target, opts.exclusions())
+	}
+	var targets, excluded []string
+	for _, t := range fields {
+		var skip *ExcludedTargetError
+		if err := validateTarget(t, opts.exclusions()); errors.As(err, &skip) {
+			if len(excluded)+1 == len(fields) {
+				return ScanResult{}, err
+			}
+			opts.logger().Warn("skipping %s: excluded by %q", t, skip.Rule)
+			excluded = append(excluded, t)
+		} else if err != nil {
+			return ScanResult{}, err
+		} else {
+			targets = append(targets, t)
+		}
 	}
 
 	// Parse command into executable and args
@@ -52,36 +128,119 @@ func RunScan(command string, target string) (ScanResult, error) {
 		executable = args[1] // Extract the real executable (Nmap)
 	}
 
-	args = append(args, target) // Append target at the end
+	if args, err = ipv6ScanArgs(args, targets); err != nil {
+		return ScanResult{}, err
+	}
+	args = append(args, nmapExcludeArgs(opts.exclusions())...)
+	args = append(args, targets...) // Append targets at the end
+
+	if _, err := lookupNmap(executable); err != nil {
+		return ScanResult{}, err
+	}
 
 	// Create command execution (keep original command structure)
 	cmd := exec.Command(executable, args[1:]...)
 
-	// Capture output
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
+	// Capture output. With "-oX -" stdout is streamed straight into the XML
+	// parser so large scans are never held in memory as a whole.
+	var out, errOut bytes.Buffer
+	xmlMode := wantsXMLOutput(args)
+	var copies []io.Writer // Also receive stdout as it arrives
+	if opts.RawOutput != nil {
+		copies = append(copies, opts.RawOutput)
+	}
+	if opts.Tail {
+		copies = append(copies, os.Stdout)
+	}
+	var stdout io.ReadCloser
+	if xmlMode {
+		pipe, err := cmd.StdoutPipe()
+		if err != nil {
+			return ScanResult{}, err
+		}
+		stdout = pipe
+	} else {
+		cmd.Stdout = io.MultiWriter(append([]io.Writer{&out}, copies...)...)
+	}
+	cmd.Stderr = &errOut // Separate buffer: stdout and stderr are copied concurrently
+	if opts.Tail {
+		cmd.Stderr = io.MultiWriter(&errOut, os.Stderr)
+	}
 
-	// Spinner for activity indication
+	// Spinner for activity indication (skipped when output is piped or tailed)
+	spinning := !opts.Quiet && !opts.Tail && IsTerminal(os.Stdout)
 	done := make(chan bool)
-	go Spinner(done)
+	if spinning {
+		go Spinner(done)
+	}
 
 	// Run command
-	err := cmd.Run()
-	done <- true // Stop the spinner
+	var results map[string][]string
+	var hosts map[string]HostResult
+	var parseErr error
+	err = cmd.Start()
+	if err == nil {
+		if xmlMode {
+			var xmlIn io.Reader = stdout
+			if len(copies) > 0 {
+				xmlIn = io.TeeReader(stdout, io.MultiWriter(copies...))
+			}
+			results, hosts, parseErr = collectXMLHosts(ParseNmapXMLStream(xmlIn))
+			io.Copy(io.Discard, stdout) // Drain anything left if parsing stopped early
+		}
+		err = cmd.Wait()
+	}
+	if spinning {
+		done <- true // Stop the spinner
+	}
 
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		opts.logger().Debug("nmap output:\n%s%s", out.String(), errOut.String())
+		nmapErr := ClassifyNmapError(exitErr.ExitCode(), errOut.String()+out.String())
+		return ScanResult{}, &nmapErr
+	}
 	if err != nil {
-		return ScanResult{}, fmt.Errorf("scan failed: %v\nOutput: %s", err, out.String())
+		return ScanResult{}, fmt.Errorf("scan failed: %v\nOutput: %s%s", err, out.String(), errOut.String())
+	}
+	if parseErr != nil {
+		return ScanResult{}, parseErr
 	}
 
-	// Parse Nmap output
-	results := ParseNmapOutput(out.String())
+	// Parse Nmap output, in whichever format the command asked for and the
+	// layout of the nmap version that ran it
+	scannerVersion := cmp.Or(nmapVersionAt(cmd.Path), nmapOutputVersion(out.String()))
+	if !xmlMode {
+		results, hosts, err = ParseAnyNmapOutput(out.String(), scannerVersion)
+		if errors.Is(err, ErrUnknownNmapFormat) {
+			results, err = ParseNmapOutputVersion(out.String(), scannerVersion), nil // Nothing recognisable, so no hosts up
+		}
+		if err != nil {
+			return ScanResult{}, err
+		}
+	}
 
 	// Return scan results with full timestamp
-	return ScanResult{
-		DateTime: time.Now().Format(time.RFC3339),
-		Ports:    results,
-	}, nil
+	scan := ScanResult{
+		Version:         1,
+		DateTime:        time.Now().Format(time.RFC3339),
+		Ports:           results,
+		Hosts:           hosts,
+		ExcludeRules:    opts.exclusions(),
+		ExcludedTargets: excluded,
+		Command:         strings.Join(cmd.Args, " "),
+		ScannerVersion:  scannerVersion,
+	}
+	if err := upgradeScan(&scan); err != nil {
+		return ScanResult{}, err
+	}
+	if !xmlMode {
+		applyMACAddresses(&scan, out.String())
+		applyTraceroutes(&scan, out.String())
+		applyOSDetails(&scan, out.String())
+		applyHostMetrics(&scan, out.String())
+	}
+	return scan, nil
 }
 
 // Spinner function to show activity while scan is running
@@ -102,8 +261,25 @@ func Spinner(done chan bool) {
 	}
 }
 
-// ParseNmapOutput extracts all port states (open, closed, filtered) from Nmap output
+// Nmap output patterns, compiled once at start-up
+var (
+	// "Nmap scan report for scanme.nmap.org (45.33.32.156)" or "Nmap scan report for 10.0.0.1"
+	scanReportRe = regexp.MustCompile(`^Nmap scan report for (?:\S+ \()?([^\s()]+)\)?$`)
+
+	// "80/tcp  open     http" or "53/udp  open|filtered  domain"
+	portLineRe = regexp.MustCompile(`^(\d+/(?:tcp|udp|sctp))\s+(\S+)\s+(\S+)`)
+)
+
+// ParseNmapOutput extracts all port states (open, closed, filtered) from Nmap
+// output, in the layout of the nmap version named in its "Starting Nmap" line
 func ParseNmapOutput(output string) map[string][]string {
+	return ParseNmapOutputVersion(output, nmapOutputVersion(output))
+}
+
+// ParseNmapOutputVersion is ParseNmapOutput for output from the given nmap
+// version, parsed with the layout nmapCompatibility has for it
+func ParseNmapOutputVersion(output, version string) map[string][]string {
+	hostRe := nmapParserFor(version).hostRe
 	results := make(map[string][]string)
 
 	lines := strings.Split(output, "\n")
@@ -113,187 +289,344 @@ func ParseNmapOutput(output string) map[string][]string {
 		line = strings.TrimSpace(line)
 
 		// Detect the scanned IP from "Nmap scan report for <IP>"
-		if strings.HasPrefix(line, "Nmap scan report for ") {
-			parts := strings.Fields(line)
-			currentIP = parts[len(parts)-1]
-			currentIP = strings.Trim(currentIP, "()") // Remove brackets if present
-		} else if (strings.Contains(line, "/tcp") || strings.Contains(line, "/udp")) && currentIP != "" {
-			// Example Nmap port output:
-			// 80/tcp  open     http
-			// 443/tcp closed   https
-			// 53/udp  open|filtered  domain
-
-			cols := strings.Fields(line)
-			if len(cols) >= 3 {
-				port := cols[0]    // Extract "80/tcp" or "53/udp"
-				state := cols[1]   // Extract "open", "closed", "filtered" or "open|filtered"
-				service := cols[2] // Extract "http", "https", "domain", etc.
-
-				// Save all states for proper tracking
-				results[currentIP] = append(results[currentIP], fmt.Sprintf("%s [%s] (%s)", port, state, service))
+		if m := hostRe.FindStringSubmatch(line); m != nil {
+			currentIP = m[1]
+
+			// Record the host even if no ports are listed so it counts as up
+			if _, ok := results[currentIP]; !ok {
+				results[currentIP] = []string{}
 			}
+		} else if m := portLineRe.FindStringSubmatch(line); m != nil && currentIP != "" {
+			port := m[1]    // "80/tcp" or "53/udp"
+			state := m[2]   // "open", "closed", "filtered" or "open|filtered"
+			service := m[3] // "http", "https", "domain", etc.
+			if number, proto, ok := strings.Cut(port, "/"); ok {
+				n, _ := strconv.Atoi(number)
+				service = nameUnknownService(n, proto, service)
+			}
+
+			// Save all states for proper tracking
+			results[currentIP] = append(results[currentIP], fmt.Sprintf("%s [%s] (%s)", port, state, service))
 		}
 	}
+	for _, entries := range results {
+		sortPortEntries(entries)
+	}
 	return results
 }
 
-// LoadPreviousScan loads previous scan results from a JSON file
-func LoadPreviousScan() (ScanResult, error) {
-	data, err := os.ReadFile(scanFile)
-	if err != nil {
-		return ScanResult{}, err
+// splitPortEntry breaks a stored port entry such as "80/tcp [open] (http)"
+// into its port number, protocol, state and service
+func splitPortEntry(entry string) (port, proto, state, service string) {
+	fields := strings.Fields(entry)
+	if len(fields) > 0 {
+		port, proto, _ = strings.Cut(fields[0], "/")
 	}
-
-	var scan ScanResult
-	err = json.Unmarshal(data, &scan)
-	if err != nil {
-		return ScanResult{}, err
+	if len(fields) > 1 {
+		state = strings.Trim(fields[1], "[]")
 	}
+	if len(fields) > 2 {
+		service = strings.Trim(fields[2], "()")
+	}
+	return port, proto, state, service
+}
 
-	return scan, nil
+// sortPortEntries orders port entries by port number, then protocol, then
+// state. nmap's own ordering can vary between runs, and a fixed order keeps
+// the stored scans stable under version control.
+func sortPortEntries(entries []string) {
+	slices.SortStableFunc(entries, func(a, b string) int {
+		portA, protoA, stateA, _ := splitPortEntry(a)
+		portB, protoB, stateB, _ := splitPortEntry(b)
+		numA, _ := strconv.Atoi(portA)
+		numB, _ := strconv.Atoi(portB)
+		return cmp.Or(cmp.Compare(numA, numB), cmp.Compare(protoA, protoB), cmp.Compare(stateA, stateB))
+	})
 }
 
-// SaveScan saves scan results to a JSON file, preserving the old scan before overwriting
-func SaveScan(scan ScanResult) error {
-	// Ensure the scan_data folder exists
-	err := EnsureScanFolderExists()
-	if err != nil {
-		return err
-	}
+// loadRetries is how many times LoadPreviousScan retries a transient read
+// failure; set from -load-retries
+var loadRetries = 3
+
+// loadRetryBackoff is the wait before LoadPreviousScan's first retry, doubled for each retry after it
+const loadRetryBackoff = 100 * time.Millisecond
+
+// LoadPreviousScan loads the most recently saved scan from store, retrying
+// up to loadRetries times if it is being written at the same moment
+func LoadPreviousScan(store Store) (ScanResult, error) {
+	return loadLatestWithRetry(store, loadRetries, loadRetryBackoff)
+}
+
+// LoadPreviousScanWithRetry loads the most recently saved scan from the
+// configured store, retrying up to maxRetries times on transient I/O errors
+// (EAGAIN, EBUSY), such as a save from the previous watch-mode scan still
+// holding the scan file's lock. The wait starts at backoff and doubles after
+// each retry.
+func LoadPreviousScanWithRetry(maxRetries int, backoff time.Duration) (ScanResult, error) {
+	return loadLatestWithRetry(scanStore, maxRetries, backoff)
+}
 
-	// If a previous scan exists, move it before overwriting
-	if _, err := os.Stat(scanFile); err == nil {
-		os.Rename(scanFile, backupScanFile) // Move previous scan to backup before overwriting
+// loadLatestWithRetry is LoadPreviousScanWithRetry for any store
+func loadLatestWithRetry(store Store, maxRetries int, backoff time.Duration) (ScanResult, error) {
+	for attempt := 0; ; attempt++ {
+		scan, err := store.Latest()
+		if err == nil || attempt >= maxRetries || !isTransientIOError(err) {
+			return scan, err
+		}
+		logger.Debug("loading the previous scan: %v, retrying in %s", err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
 	}
+}
 
-	data, err := json.MarshalIndent(scan, "", "  ")
-	if err != nil {
+// isTransientIOError reports whether err is worth retrying
+func isTransientIOError(err error) bool {
+	return errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EBUSY)
+}
+
+// SaveScan saves scan results to store and updates the host index in the data folder dir
+func SaveScan(store Store, dir string, scan ScanResult) error {
+	if err := store.Save(scan); err != nil {
 		return err
 	}
-	return os.WriteFile(scanFile, data, 0644)
+	return UpdateHostIndex(dir, scan)
 }
 
-// CompareScans finds differences between scans, updates stored scan if changes are detected
-func CompareScans(old, new ScanResult) {
-	// ANSI colour codes
-	green := "\033[32m" // Green for added ports
-	red := "\033[31m"   // Red for removed ports
-	reset := "\033[0m"  // Reset to default colour
+// CompareScansDiff finds differences between scans, prints them and returns
+// them as a DiffReport. Saving the new scan is left to the caller. If ctx is
+// cancelled, e.g. by a shutdown in watch mode, it stops before the next host
+// and returns ctx's error.
+func CompareScansDiff(ctx context.Context, old, new ScanResult) (DiffReport, error) {
+	c := Colours()
 
 	// Parse timestamps
 	oldTime, err := time.Parse(time.RFC3339, old.DateTime)
 	if err != nil {
-		fmt.Println("Error parsing old scan time:", err)
-		return
+		return DiffReport{}, fmt.Errorf("parsing old scan time: %v", err)
 	}
 
 	newTime, err := time.Parse(time.RFC3339, new.DateTime)
 	if err != nil {
-		fmt.Println("Error parsing new scan time:", err)
-		return
+		return DiffReport{}, fmt.Errorf("parsing new scan time: %v", err)
 	}
 
 	// Calculate elapsed time
 	elapsed := newTime.Sub(oldTime)
-	fmt.Printf("\n--- Checking Previous Scan Data (Last scan was %s ago) ---\n\n", formatElapsedTime(elapsed))
-
-	noChanges := true
-	totalAdded, totalRemoved := 0, 0
+	fmt.Printf("\n--- Checking Previous Scan Data (Last scan was %s ago) ---\n\n", formatElapsedTime(elapsed, verbosity >= VerbositySummary))
 
-	// Track changes for new scan results
-	for ip, newPorts := range new.Ports {
-		oldPorts := old.Ports[ip]
-		added, removed := DiffPorts(oldPorts, newPorts)
+	report, err := BuildDiffReportContext(ctx, old, new)
+	if err != nil {
+		return DiffReport{}, err
+	}
+	notes := loadAnnotationsForDisplay()
+	diffPager.Reset()
 
-		if len(added) > 0 || len(removed) > 0 {
-			noChanges = false
-			fmt.Printf("Changes for %s:\n", ip)
+	for _, a := range report.Alerts {
+		diffPager.Change("%s[!] %s: %s%s\n", c.Warning, strings.ToUpper(a.Severity), a, c.Reset)
+	}
+	if len(report.Alerts) > 0 {
+		diffPager.Printf("\n")
+	}
 
-			if len(added) > 0 {
-				totalAdded += len(added)
-				fmt.Println("  [+] Added Ports:")
-				for _, port := range added {
-					fmt.Printf("    - %s%s%s\n", green, port, reset) // Green for added
+	for _, host := range report.Hosts {
+		if err := ctx.Err(); err != nil {
+			return DiffReport{}, err
+		}
+		diffPager.Printf("Changes for %s%s:\n", host.IP, annotationSuffix(notes, host.IP))
+
+		if len(host.Added) > 0 {
+			diffPager.Printf("  [+] Added Ports:\n")
+			for _, port := range host.Added {
+				note := sensitiveNote(port)
+				if unexpected := unexpectedServiceNote(port); unexpected != "" {
+					note += c.Warning + unexpected + c.Reset
+				}
+				if unauthorised := unauthorisedPortNote(new, host.IP, port); unauthorised != "" {
+					note += c.Warning + unauthorised + c.Reset
 				}
+				diffPager.Change("    - %s%s%s%s\n", c.Added, port, c.Reset, note)
 			}
+		}
 
-			if len(removed) > 0 {
-				totalRemoved += len(removed)
-				fmt.Println("  [-] Removed Ports:")
-				for _, port := range removed {
-					fmt.Printf("    - %s%s%s\n", red, port, reset) // Red for removed
-				}
+		if len(host.Removed) > 0 {
+			diffPager.Printf("  [-] Removed Ports:\n")
+			for _, port := range host.Removed {
+				diffPager.Change("    - %s%s%s%s\n", c.Removed, port, c.Reset, sensitiveNote(port))
 			}
-			fmt.Println()
 		}
+		diffPager.Printf("\n")
 	}
 
-	// Detect IPs and ports that were present in old scan but missing in the new scan
-	for ip, oldPorts := range old.Ports {
-		if _, exists := new.Ports[ip]; !exists {
-			noChanges = false
-			fmt.Printf("All ports for %s removed:\n", ip)
-			for _, port := range oldPorts {
-				totalRemoved++
-				fmt.Printf("  [-] %s%s%s\n", red, port, reset) // Red for removed
-			}
-			fmt.Println()
+	// Hosts that disappeared are reported once rather than port by port
+	for _, change := range report.HostStateChanges {
+		if change.NewState == HostDown {
+			diffPager.Change("Host %s%s went %sDOWN%s (%d ports no longer visible)\n", change.IP, annotationSuffix(notes, change.IP), c.Removed, c.Reset, len(old.Ports[change.IP]))
+		} else {
+			diffPager.Change("Host %s%s came %sUP%s\n", change.IP, annotationSuffix(notes, change.IP), c.Added, c.Reset)
 		}
 	}
+	if len(report.HostStateChanges) > 0 {
+		diffPager.Printf("\n")
+	}
 
-	if noChanges {
+	for _, change := range report.MACChanges {
+		diffPager.Change("%s%s%s\n", c.Changed, change, c.Reset)
+	}
+	if len(report.MACChanges) > 0 {
+		diffPager.Printf("\n")
+	}
+
+	for _, change := range report.RouteChanges {
+		diffPager.Change("%s%s%s\n", c.Changed, change, c.Reset)
+	}
+	if len(report.RouteChanges) > 0 {
+		diffPager.Printf("\n")
+	}
+
+	for _, change := range report.OSChanges {
+		diffPager.Change("%s%s%s\n", c.Changed, change, c.Reset)
+	}
+	if len(report.OSChanges) > 0 {
+		diffPager.Printf("\n")
+	}
+
+	for _, change := range report.DNSChanges {
+		diffPager.Change("%s%s%s\n", c.Changed, change, c.Reset)
+	}
+	if len(report.DNSChanges) > 0 {
+		diffPager.Printf("\n")
+	}
+
+	if len(report.ScriptChanges) > 0 {
+		diffPager.Printf("Script Changes:\n")
+		for _, sc := range report.ScriptChanges {
+			diffPager.Change("  %s%s %s %s%s:\n%s%s%s%s%s%s", c.Changed, sc.Host, sc.Port, sc.ScriptName, c.Reset,
+				c.Removed, indentScriptOutput("    - ", sc.OldOutput), c.Reset,
+				c.Added, indentScriptOutput("    + ", sc.NewOutput), c.Reset)
+		}
+		diffPager.Printf("\n")
+	}
+
+	writeUnauthorisedPorts(os.Stdout, report.Unauthorised)
+	if !report.HasChanges() {
 		fmt.Println("No changes detected.")
-	} else {
-		fmt.Printf("Summary: %d new ports added, %d removed.\n", totalAdded, totalRemoved)
-		SaveScan(new) // Save updated scan data
+		return report, nil
+	}
+
+	totalAdded, totalRemoved := report.Totals()
+	fmt.Printf("Summary: %d new ports added, %d removed.\n", totalAdded, totalRemoved)
+	if up, down := report.HostCounts(); up > 0 || down > 0 {
+		fmt.Printf("Hosts: %d came up, %d went down.\n", up, down)
+	}
+	if len(report.ScriptChanges) > 0 {
+		fmt.Printf("Script output changed: %d\n", len(report.ScriptChanges))
+	}
+	if len(report.MACChanges) > 0 {
+		fmt.Printf("MAC addresses changed: %d\n", len(report.MACChanges))
+	}
+	if len(report.RouteChanges) > 0 {
+		fmt.Printf("Routes changed: %d\n", len(report.RouteChanges))
+	}
+	if len(report.OSChanges) > 0 {
+		fmt.Printf("OS fingerprints changed: %d\n", len(report.OSChanges))
+	}
+	if len(report.DNSChanges) > 0 {
+		fmt.Printf("DNS records changed: %d\n", len(report.DNSChanges))
 	}
+	fmt.Printf("Risk score: %d\n", report.Score)
+	return report, nil
 }
 
-// formatElapsedTime converts duration to human-readable format
-func formatElapsedTime(d time.Duration) string {
-	hours := int(d.Hours())
+// sensitiveNote returns a short annotation for high-sensitivity ports
+func sensitiveNote(entry string) string {
+	if !portDB.IsSensitive(entry) {
+		return ""
+	}
+	sig, _ := portDB.Lookup(entry)
+	c := Colours()
+	return fmt.Sprintf("  %s[sensitive: %s]%s", c.Warning, sig.Description, c.Reset)
+}
+
+// formatElapsedTime converts duration to human-readable format.
+// When full is true every unit down to seconds is included (for verbose output).
+func formatElapsedTime(d time.Duration, full bool) string {
+	if d < 0 {
+		return "(clock skew detected)"
+	}
+
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
 	minutes := int(d.Minutes()) % 60
-	days := hours / 24
+	seconds := int(d.Seconds()) % 60
+
+	if full {
+		parts := []string{}
+		if days > 0 {
+			parts = append(parts, fmt.Sprintf("%d days", days))
+		}
+		if days > 0 || hours > 0 {
+			parts = append(parts, fmt.Sprintf("%d hours", hours))
+		}
+		if days > 0 || hours > 0 || minutes > 0 {
+			parts = append(parts, fmt.Sprintf("%d minutes", minutes))
+		}
+		parts = append(parts, fmt.Sprintf("%d seconds", seconds))
+		return strings.Join(parts, ", ")
+	}
 
 	if days > 0 {
-		return fmt.Sprintf("%d days, %d hours", days, hours%24)
+		return fmt.Sprintf("%d days, %d hours", days, hours)
 	} else if hours > 0 {
 		return fmt.Sprintf("%d hours, %d minutes", hours, minutes)
-	} else {
+	} else if minutes > 0 {
 		return fmt.Sprintf("%d minutes", minutes)
+	} else {
+		return fmt.Sprintf("%d seconds", seconds)
 	}
 }
 
-// DiffPorts finds added and removed ports
+// DiffPorts finds added and removed ports using exact string equality
 func DiffPorts(old, new []string) (added, removed []string) {
+	return DiffPortsWithNormaliser(old, new, nil)
+}
+
+// DiffPortsWithNormaliser finds added and removed ports, treating two entries
+// as the same port if normalise maps them to the same string. The original,
+// un-normalised entries are returned. A nil normalise compares exactly.
+func DiffPortsWithNormaliser(old, new []string, normalise func(string) string) (added, removed []string) {
+	if normalise == nil {
+		normalise = func(s string) string { return s }
+	}
+
 	oldSet := make(map[string]bool)
 	for _, p := range old {
-		oldSet[p] = true
+		oldSet[normalise(p)] = true
 	}
 
 	newSet := make(map[string]bool)
 	for _, p := range new {
-		newSet[p] = true
+		newSet[normalise(p)] = true
 	}
 
-	for p := range newSet {
-		if !oldSet[p] {
+	for _, p := range new {
+		if key := normalise(p); !oldSet[key] {
 			added = append(added, p)
+			oldSet[key] = true // Report duplicates once
 		}
 	}
 
-	for p := range oldSet {
-		if !newSet[p] {
+	for _, p := range old {
+		if key := normalise(p); !newSet[key] {
 			removed = append(removed, p)
+			newSet[key] = true
 		}
 	}
 
 	return added, removed
 }
 
-// Main Execution
-func main() {
-	banner := `                                                            
+// banner is the ASCII art header printed at startup
+var banner = `                                                            
  ____   ___  ____ _____ _   _ _   _ _   _ _____ _____ ____  
 |  _ \ / _ \|  _ |_   _| | | | | | | \ | |_   _| ____|  _ \ 
 | |_) | | | | |_) || | | |_| | | | |  \| | | | |  _| | |_) |
@@ -304,25 +637,129 @@ func main() {
                     ⚡ Created by Richard Jones ⚡
 `
 
+// PrintBanner prints the ASCII art header unless suppressed by config or
+// stdout is not a terminal (e.g. piped into another tool)
+func PrintBanner(cfg Config) {
+	if cfg.NoBanner || !IsTerminal(os.Stdout) {
+		return
+	}
 	fmt.Println(banner)
+}
 
-	scanCmd := flag.String("c", "", "Full scan command (e.g., 'nmap -p- -T4' or 'nmap -sU -p- -T4' for UDP)")
-	target := flag.String("t", "", "Target IP/hostname")
-	flag.Parse()
+// IsTerminal reports whether the given file is attached to a terminal
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
 
-	scan, err := RunScan(*scanCmd, *target)
+// Main Execution
+func main() {
+	args := os.Args[1:]
+
+	// Subcommands
+	presetName, watchdogMode := "", false
+	if len(args) > 0 {
+		switch args[0] {
+		case "completion":
+			if len(args) < 2 {
+				fmt.Println("Usage: porthunter completion bash|zsh|fish")
+				return
+			}
+			if err := WriteCompletionScript(args[1], os.Stdout); err != nil {
+				logger.Error("%v", err)
+			}
+			return
+		case "__complete":
+			runCompleteValues(args[1:], os.Stdout)
+			return
+		case "replay":
+			if err := runReplay(args[1:]); err != nil {
+				logger.Error("%v", err)
+			}
+			return
+		case "save-preset":
+			if err := runSavePreset(args[1:]); err != nil {
+				logger.Error("%v", err)
+			}
+			return
+		case "campaign":
+			if err := runCampaign(args[1:]); err != nil {
+				logger.Error("%v", err)
+			}
+			return
+		case "diff":
+			if err := runDiff(args[1:]); err != nil {
+				logger.Error("%v", err)
+			}
+			return
+		case "annotate":
+			if err := runAnnotate(args[1:]); err != nil {
+				logger.Error("%v", err)
+			}
+			return
+		case "ctl":
+			if err := runCtl(args[1:]); err != nil {
+				logger.Error("%v", err)
+			}
+			return
+		case "encrypt-config":
+			if err := runEncryptConfig(args[1:]); err != nil {
+				logger.Error("%v", err)
+			}
+			return
+		case "list-presets":
+			if err := runListPresets(); err != nil {
+				logger.Error("%v", err)
+			}
+			return
+		case "run-preset":
+			if len(args) < 2 {
+				fmt.Println("Usage: porthunter run-preset <name> [flags]")
+				return
+			}
+			presetName, args = args[1], args[2:]
+		case "watchdog":
+			watchdogMode, args = true, args[1:]
+		case "update":
+			if err := runUpdate(); err != nil {
+				logger.Error("%v", err)
+			}
+			return
+		}
+	}
+
+	cfg, err := loadRuntimeConfig(args, presetName, flag.CommandLine)
 	if err != nil {
-		fmt.Println("Error:", err)
+		logger.Error("%v", err)
 		return
 	}
 
-	prevScan, err := LoadPreviousScan()
-	if err == nil {
-		CompareScans(prevScan, scan)
-	} else {
-		fmt.Println("No previous scan data found.")
+	if cfg.ShowVersion {
+		PrintVersion()
+		return
 	}
 
-	SaveScan(scan)
-	fmt.Println("Scan completed and saved.")
+	if err := applyConfig(cfg); err != nil {
+		logger.Error("%v", err)
+		return
+	}
+
+	app, err := NewApp(cfg)
+	if err != nil {
+		logger.Error("%v", err)
+		return
+	}
+	app.Args = flag.Args()
+	app.watchdog = watchdogMode
+	app.reloader = &configReloader{args: args, presetName: presetName}
+	if err := app.Run(context.Background()); err != nil {
+		var exit *ExitError
+		if !errors.As(err, &exit) {
+			logScanError(err)
+			return
+		}
+		if exit.Err != nil {
+			logger.Error("%v", exit.Err)
+		}
+		os.Exit(exit.Code)
+	}
 }
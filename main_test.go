@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunScanTail(t *testing.T) {
+	release := filepath.Join(t.TempDir(), "release")
+	installFakeNmap(t, fakeNmapRun{fixture: "multiple_hosts.txt", release: release})
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = saved }()
+
+	type result struct {
+		scan ScanResult
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		scan, err := RunScanWithOptions("nmap -sT", "10.0.0.0/24", ScanOptions{Tail: true})
+		done <- result{scan, err}
+	}()
+
+	// The first line is printed while nmap is still running
+	lines := bufio.NewReader(r)
+	first, err := lines.ReadString('\n')
+	if err != nil || !strings.HasPrefix(first, "Starting Nmap") {
+		t.Fatalf("first tailed line %q, %v", first, err)
+	}
+	select {
+	case <-done:
+		t.Fatal("scan finished before nmap did")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(release, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	res := <-done
+	w.Close()
+	os.Stdout = saved
+	rest, _ := lines.ReadString(0)
+
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if len(res.scan.Ports) != 3 {
+		t.Errorf("tailed scan parsed %d hosts, want 3 as without tailing", len(res.scan.Ports))
+	}
+	if !strings.Contains(rest, "Nmap done:") {
+		t.Errorf("rest of the output was not tailed:\n%s", rest)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MergeScans combines results from several scanners into one ScanResult.
+// Scans are applied oldest first, so when two scanners disagree about a port
+// the most recent observation wins, and the merged DateTime is the latest one.
+func MergeScans(scans ...ScanResult) ScanResult {
+	ordered := append([]ScanResult(nil), scans...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return scanTime(ordered[i].DateTime).Before(scanTime(ordered[j].DateTime))
+	})
+
+	merged := ScanResult{Ports: make(map[string][]string)}
+	for _, scan := range ordered {
+		if scan.DateTime != "" {
+			merged.DateTime = scan.DateTime
+		}
+		merged.Version = max(merged.Version, scan.Version)
+		merged.Command = cmp.Or(scan.Command, merged.Command)
+		merged.ScannerVersion = cmp.Or(scan.ScannerVersion, merged.ScannerVersion)
+		merged.ExcludeRules = unionStrings(merged.ExcludeRules, scan.ExcludeRules)
+		merged.ExcludedTargets = unionStrings(merged.ExcludedTargets, scan.ExcludedTargets)
+		for ip, ports := range scan.Ports {
+			merged.Ports[ip] = append(merged.Ports[ip], ports...)
+		}
+		for ip, elapsed := range scan.PerHostTiming {
+			if merged.PerHostTiming == nil {
+				merged.PerHostTiming = make(map[string]time.Duration)
+			}
+			merged.PerHostTiming[ip] = max(merged.PerHostTiming[ip], elapsed)
+		}
+		for ip, host := range scan.Hosts {
+			if merged.Hosts == nil {
+				merged.Hosts = make(map[string]HostResult)
+			}
+			merged.Hosts[ip] = mergeHostResult(merged.Hosts[ip], host)
+		}
+	}
+	return DeduplicateHosts(merged)
+}
+
+// DeduplicateHosts collapses entries that refer to the same host but were
+// recorded under differently formatted addresses (e.g. "::ffff:10.0.0.1" and
+// "10.0.0.1", or upper and lower case IPv6). Port lists for the same IP are
+// merged with DeduplicatePorts, later entries taking precedence.
+func DeduplicateHosts(result ScanResult) ScanResult {
+	deduped := ScanResult{Version: result.Version, DateTime: result.DateTime, Target: result.Target, Ports: make(map[string][]string, len(result.Ports))}
+	deduped.ExcludeRules, deduped.ExcludedTargets = result.ExcludeRules, result.ExcludedTargets
+	deduped.Command, deduped.ScannerVersion = result.Command, result.ScannerVersion
+
+	// Visit keys in a fixed order so the result doesn't depend on map iteration order
+	for _, ip := range sortedHostKeys(result.Ports) {
+		key := canonicalIP(ip)
+		deduped.Ports[key] = DeduplicatePorts(append(deduped.Ports[key], result.Ports[ip]...))
+	}
+
+	if len(result.Hosts) > 0 {
+		deduped.Hosts = make(map[string]HostResult, len(result.Hosts))
+		for _, ip := range sortedHostKeys(result.Hosts) {
+			key := canonicalIP(ip)
+			host := result.Hosts[ip]
+			host.IP = key
+			deduped.Hosts[key] = mergeHostResult(deduped.Hosts[key], host)
+		}
+	}
+
+	if len(result.PerHostTiming) > 0 {
+		deduped.PerHostTiming = make(map[string]time.Duration, len(result.PerHostTiming))
+		for ip, elapsed := range result.PerHostTiming {
+			key := canonicalIP(ip)
+			deduped.PerHostTiming[key] = max(deduped.PerHostTiming[key], elapsed)
+		}
+	}
+	return deduped
+}
+
+// FingerprintHost returns a stable hash of a host's open ports, ignoring its
+// address, so the same logical host behind several IPs (e.g. the VIPs of a
+// load balancer) can be recognised. Hosts without open ports have no
+// fingerprint and get "".
+func FingerprintHost(host HostResult) string {
+	return fingerprintPorts(host.PortStrings())
+}
+
+// fingerprintPorts hashes the sorted port/protocol pairs of the open entries in ports
+func fingerprintPorts(ports []string) string {
+	var open []string
+	for _, entry := range ports {
+		if port, proto, state, _ := splitPortEntry(entry); state == "open" {
+			open = append(open, port+"/"+proto)
+		}
+	}
+	if len(open) == 0 {
+		return ""
+	}
+	slices.Sort(open)
+	sum := sha256.Sum256([]byte(strings.Join(slices.Compact(open), ",")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// DeduplicateByFingerprint drops hosts whose FingerprintHost matches one
+// already kept, visiting hosts in numeric address order so the lowest address
+// is kept. Hosts only known by their port list (text output) are
+// fingerprinted from it. The dropped addresses are returned.
+func DeduplicateByFingerprint(result ScanResult) (ScanResult, []string) {
+	seen := make(map[string]bool)
+	var dropped []string
+	hosts := sortedHostKeys(result.Ports)
+	slices.SortFunc(hosts, compareIPs)
+	for _, ip := range hosts {
+		fingerprint := fingerprintPorts(result.Ports[ip])
+		if host, ok := result.Hosts[ip]; ok {
+			fingerprint = FingerprintHost(host)
+		}
+		if fingerprint == "" {
+			continue
+		}
+		if !seen[fingerprint] {
+			seen[fingerprint] = true
+			continue
+		}
+		dropped = append(dropped, ip)
+	}
+	if len(dropped) == 0 {
+		return result, nil
+	}
+
+	deduped := result
+	deduped.Ports = maps.Clone(result.Ports)
+	deduped.Hosts = maps.Clone(result.Hosts)
+	deduped.PerHostTiming = maps.Clone(result.PerHostTiming)
+	for _, ip := range dropped {
+		delete(deduped.Ports, ip)
+		delete(deduped.Hosts, ip)
+		delete(deduped.PerHostTiming, ip)
+	}
+	return deduped, dropped
+}
+
+// DeduplicatePorts keeps a single entry per port/protocol pair. Where the
+// same port appears more than once with a different state or service, the
+// last entry wins but keeps the position of the first.
+func DeduplicatePorts(ports []string) []string {
+	index := make(map[string]int, len(ports))
+	deduped := make([]string, 0, len(ports))
+	for _, entry := range ports {
+		port, proto, _, _ := splitPortEntry(entry)
+		key := port + "/" + proto
+		if i, ok := index[key]; ok {
+			deduped[i] = entry
+			continue
+		}
+		index[key] = len(deduped)
+		deduped = append(deduped, entry)
+	}
+	return deduped
+}
+
+// mergeHostResult folds next into prev, with next's details taking precedence
+func mergeHostResult(prev, next HostResult) HostResult {
+	if prev.IP == "" {
+		prev.IP = next.IP
+	}
+	if next.Hostname != "" {
+		prev.Hostname = next.Hostname
+	}
+	if next.State != "" {
+		prev.State = next.State
+	}
+	if next.MAC != "" {
+		prev.MAC, prev.Vendor = next.MAC, next.Vendor
+	}
+	if next.OS != "" {
+		prev.OS = next.OS
+	}
+	if next.OSFingerprint != "" {
+		prev.OSFingerprint = next.OSFingerprint
+	}
+	if len(next.Traceroute) > 0 {
+		prev.Traceroute = next.Traceroute
+	}
+	if next.Latency != 0 {
+		prev.Latency = next.Latency
+	}
+	if next.Hops != 0 {
+		prev.Hops = next.Hops
+	}
+	if next.DNSTTL != 0 {
+		prev.DNSTTL = next.DNSTTL
+	}
+
+	index := make(map[string]int, len(prev.Ports))
+	ports := make([]PortEntry, 0, len(prev.Ports)+len(next.Ports))
+	for _, p := range append(prev.Ports, next.Ports...) {
+		key := fmt.Sprintf("%d/%s", p.Port, p.Protocol)
+		if i, ok := index[key]; ok {
+			ports[i] = p
+			continue
+		}
+		index[key] = len(ports)
+		ports = append(ports, p)
+	}
+	prev.Ports = ports
+	return prev
+}
+
+// canonicalIP returns the normalised form of an address, or the input
+// unchanged if it is not an IP (e.g. a hostname)
+func canonicalIP(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ip
+}
+
+// scanTime parses a ScanResult.DateTime, treating unparseable values as the zero time
+func scanTime(datetime string) time.Time {
+	t, _ := time.Parse(time.RFC3339, datetime)
+	return t
+}
+
+// sortedHostKeys returns the keys of a host map in sorted order
+func sortedHostKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// unionStrings appends the values of b missing from a, keeping a's order
+func unionStrings(a, b []string) []string {
+	for _, v := range b {
+		if !slices.Contains(a, v) {
+			a = append(a, v)
+		}
+	}
+	return a
+}
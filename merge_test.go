@@ -0,0 +1,120 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeduplicateHosts(t *testing.T) {
+	tests := []struct {
+		name  string
+		ports map[string][]string
+		want  map[string][]string
+	}{
+		{
+			name: "shared and differing ports",
+			ports: map[string][]string{
+				"10.0.0.1":        {"22/tcp [open] (ssh)", "80/tcp [open] (http)"},
+				"::ffff:10.0.0.1": {"22/tcp [open] (ssh)", "443/tcp [open] (https)"},
+			},
+			want: map[string][]string{
+				"10.0.0.1": {"22/tcp [open] (ssh)", "80/tcp [open] (http)", "443/tcp [open] (https)"},
+			},
+		},
+		{
+			name: "mixed-case IPv6",
+			ports: map[string][]string{
+				"2001:DB8::1":  {"22/tcp [open] (ssh)"},
+				"2001:db8::1":  {"80/tcp [open] (http)"},
+				"2001:0db8::1": {"443/tcp [open] (https)"},
+			},
+			want: map[string][]string{
+				"2001:db8::1": {"443/tcp [open] (https)", "22/tcp [open] (ssh)", "80/tcp [open] (http)"},
+			},
+		},
+		{
+			name: "later entry wins",
+			ports: map[string][]string{
+				"10.0.0.1":        {"22/tcp [open] (ssh)"},
+				"::ffff:10.0.0.1": {"22/tcp [filtered] (ssh)"}, // Sorts after "10.0.0.1"
+			},
+			want: map[string][]string{
+				"10.0.0.1": {"22/tcp [filtered] (ssh)"},
+			},
+		},
+		{
+			name:  "hostnames are left alone",
+			ports: map[string][]string{"Router.local": {"22/tcp [open] (ssh)"}},
+			want:  map[string][]string{"Router.local": {"22/tcp [open] (ssh)"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DeduplicateHosts(ScanResult{Ports: tt.ports})
+			if !reflect.DeepEqual(got.Ports, tt.want) {
+				t.Errorf("got %v\nwant %v", got.Ports, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeduplicateHostsMergesHostResults(t *testing.T) {
+	result := ScanResult{
+		Ports: map[string][]string{"::ffff:10.0.0.1": {"22/tcp [open] (ssh)"}, "10.0.0.1": {"80/tcp [open] (http)"}},
+		Hosts: map[string]HostResult{
+			"10.0.0.1":        {IP: "10.0.0.1", OS: "Linux"},
+			"::ffff:10.0.0.1": {IP: "::ffff:10.0.0.1", MAC: "00:1A:2B:3C:4D:5E"},
+		},
+	}
+	got := DeduplicateHosts(result)
+	if len(got.Hosts) != 1 {
+		t.Fatalf("got %d hosts, want 1: %+v", len(got.Hosts), got.Hosts)
+	}
+	host := got.Hosts["10.0.0.1"]
+	if host.IP != "10.0.0.1" || host.OS != "Linux" || host.MAC != "00:1A:2B:3C:4D:5E" {
+		t.Errorf("merged host %+v", host)
+	}
+}
+
+func TestMergeScansLaterScanWins(t *testing.T) {
+	older := ScanResult{DateTime: "2024-05-01T10:00:00Z", Ports: map[string][]string{
+		"10.0.0.1": {"22/tcp [open] (ssh)", "80/tcp [open] (http)"},
+	}}
+	newer := ScanResult{DateTime: "2024-05-02T10:00:00Z", Ports: map[string][]string{
+		"::ffff:10.0.0.1": {"22/tcp [filtered] (ssh)"},
+	}}
+
+	// Passed newest first: MergeScans orders by DateTime, not argument order
+	got := MergeScans(newer, older)
+	want := map[string][]string{"10.0.0.1": {"22/tcp [filtered] (ssh)", "80/tcp [open] (http)"}}
+	if !reflect.DeepEqual(got.Ports, want) {
+		t.Errorf("got %v\nwant %v", got.Ports, want)
+	}
+	if got.DateTime != newer.DateTime {
+		t.Errorf("DateTime %q, want the latest scan's %q", got.DateTime, newer.DateTime)
+	}
+}
+
+func TestDeduplicatePorts(t *testing.T) {
+	got := DeduplicatePorts([]string{"22/tcp [open] (ssh)", "53/udp [open] (domain)", "22/tcp [closed] (ssh)", "53/tcp [open] (domain)"})
+	want := []string{"22/tcp [closed] (ssh)", "53/udp [open] (domain)", "53/tcp [open] (domain)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
+
+func TestDeduplicateByFingerprintKeepsLowestAddress(t *testing.T) {
+	web := []string{"80/tcp [open] (http)", "443/tcp [open] (https)"}
+	scan := ScanResult{Ports: map[string][]string{
+		"10.0.0.10": web,
+		"10.0.0.2":  web,
+		"10.0.0.3":  {"22/tcp [open] (ssh)"},
+	}}
+	deduped, dropped := DeduplicateByFingerprint(scan)
+	if len(dropped) != 1 || dropped[0] != "10.0.0.10" {
+		t.Errorf("dropped %v, want 10.0.0.10, the higher address", dropped)
+	}
+	if _, ok := deduped.Ports["10.0.0.2"]; !ok || len(deduped.Ports) != 2 {
+		t.Errorf("kept %v, want 10.0.0.2 and 10.0.0.3", deduped.Ports)
+	}
+}
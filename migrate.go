@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// currentScanVersion is the ScanResult schema written by this build:
+//
+//	1: port strings in Ports, Hosts only for XML scans (files without a version)
+//	2: Hosts holds a structured PortEntry list for every host
+const currentScanVersion = 2
+
+// scanMigrations[i] upgrades a scan from version i+1 to i+2
+var scanMigrations = []func(*ScanResult){
+	migrateScanV1ToV2,
+}
+
+// MigrateScanResult decodes a stored scan of any known version and upgrades
+// it to currentScanVersion. Scans written by a newer PortHunter are rejected
+// rather than being silently truncated.
+func MigrateScanResult(raw json.RawMessage) (ScanResult, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return ScanResult{}, err
+	}
+	if header.Version > currentScanVersion {
+		return ScanResult{}, newerScanVersionError(header.Version)
+	}
+
+	var scan ScanResult
+	if err := json.Unmarshal(raw, &scan); err != nil {
+		return ScanResult{}, err
+	}
+	return scan, upgradeScan(&scan)
+}
+
+// upgradeScan applies the migrations needed to bring scan to currentScanVersion
+func upgradeScan(scan *ScanResult) error {
+	if scan.Version == 0 {
+		scan.Version = 1 // Written before versioning was added
+	}
+	if scan.Version > currentScanVersion {
+		return newerScanVersionError(scan.Version)
+	}
+	for scan.Version < currentScanVersion {
+		scanMigrations[scan.Version-1](scan)
+		scan.Version++
+	}
+	return nil
+}
+
+func newerScanVersionError(version int) error {
+	return fmt.Errorf("scan data is version %d but this PortHunter only understands up to %d; please upgrade", version, currentScanVersion)
+}
+
+// migrateScanV1ToV2 builds structured host detail for hosts only stored as port strings
+func migrateScanV1ToV2(scan *ScanResult) {
+	for ip, ports := range scan.Ports {
+		if _, ok := scan.Hosts[ip]; ok {
+			continue
+		}
+		if scan.Hosts == nil {
+			scan.Hosts = make(map[string]HostResult, len(scan.Ports))
+		}
+		scan.Hosts[ip] = HostFromPortStrings(ip, ports)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrateScanResultV1(t *testing.T) {
+	// A scan saved before versioning: no "version", port strings only, plus
+	// one host with XML detail that must be kept as it is
+	v1 := `{
+  "datetime": "2024-05-01T10:00:00Z",
+  "ports": {
+    "10.0.0.1": ["22/tcp [open] (ssh)", "161/udp [open|filtered] (snmp)"],
+    "10.0.0.2": ["80/tcp [open] (http)"]
+  },
+  "hosts": {
+    "10.0.0.2": {"ip": "10.0.0.2", "os": "Linux", "ports": [{"port": 80, "protocol": "tcp", "state": "open", "service": "http"}]}
+  }
+}`
+	scan, err := MigrateScanResult([]byte(v1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.Version != currentScanVersion {
+		t.Errorf("version %d, want %d", scan.Version, currentScanVersion)
+	}
+
+	want := []PortEntry{
+		{Port: 22, Protocol: "tcp", State: "open", Service: "ssh"},
+		{Port: 161, Protocol: "udp", State: "open|filtered", Service: "snmp"},
+	}
+	if got := scan.Hosts["10.0.0.1"]; got.IP != "10.0.0.1" || !reflect.DeepEqual(got.Ports, want) {
+		t.Errorf("migrated host %+v, want ports %+v", got, want)
+	}
+	if got := scan.Hosts["10.0.0.2"]; got.OS != "Linux" {
+		t.Errorf("existing host detail was replaced: %+v", got)
+	}
+	if got := scan.Ports["10.0.0.1"]; len(got) != 2 {
+		t.Errorf("port strings changed: %v", got)
+	}
+}
+
+func TestMigrateScanResultCurrent(t *testing.T) {
+	raw := `{"version": 2, "datetime": "2024-05-01T10:00:00Z", "ports": {"10.0.0.1": ["22/tcp [open] (ssh)"]},
+"hosts": {"10.0.0.1": {"ip": "10.0.0.1", "ports": [{"port": 22, "protocol": "tcp", "state": "open", "service": "ssh"}]}}}`
+	scan, err := MigrateScanResult([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.Version != 2 || len(scan.Hosts["10.0.0.1"].Ports) != 1 {
+		t.Errorf("current scan changed on load: %+v", scan)
+	}
+}
+
+func TestMigrateScanResultRejectsNewer(t *testing.T) {
+	raw := `{"version": 99, "datetime": "2024-05-01T10:00:00Z", "ports": {}, "field_from_the_future": true}`
+	_, err := MigrateScanResult([]byte(raw))
+	if err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Errorf("got %v, want a newer-version error", err)
+	}
+
+	scan := ScanResult{Version: currentScanVersion + 1}
+	if err := upgradeScan(&scan); err == nil {
+		t.Error("upgradeScan accepted a newer version")
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nessusHostStartLayout is the format of a Nessus HOST_START tag, e.g.
+// "Tue Mar  4 10:00:00 2025"
+const nessusHostStartLayout = "Mon Jan _2 15:04:05 2006"
+
+// nessusHost mirrors the parts of a Nessus (.nessus, NessusClientData_v2)
+// <ReportHost> element that PortHunter uses
+type nessusHost struct {
+	Name       string `xml:"name,attr"`
+	Properties []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"HostProperties>tag"`
+	Items []struct {
+		Port       int    `xml:"port,attr"`
+		Protocol   string `xml:"protocol,attr"`
+		Service    string `xml:"svc_name,attr"`
+		Severity   int    `xml:"severity,attr"`
+		PluginID   string `xml:"pluginID,attr"`
+		PluginName string `xml:"pluginName,attr"`
+		Output     string `xml:"plugin_output"`
+	} `xml:"ReportItem"`
+}
+
+// ParseNessusXML reads a Nessus XML export so its results can be diffed like
+// PortHunter's own scans. Every port Nessus reported findings on becomes an
+// open PortEntry, with each plugin's finding stored as a script result keyed
+// "nessus-<plugin ID>", so changed findings show up as changed scripts.
+// Host-level findings (port 0) are left out. Hosts are read one at a time,
+// so large exports are never held in memory as a whole.
+func ParseNessusXML(path string) (ScanResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer f.Close()
+
+	scan := ScanResult{
+		Version: currentScanVersion,
+		Ports:   make(map[string][]string),
+		Hosts:   make(map[string]HostResult),
+	}
+	var started time.Time
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ScanResult{}, fmt.Errorf("parsing Nessus XML: %v", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "Report":
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "name" {
+					scan.Target = attr.Value
+				}
+			}
+		case "ReportHost":
+			var x nessusHost
+			if err := dec.DecodeElement(&x, &start); err != nil {
+				return ScanResult{}, fmt.Errorf("parsing Nessus XML: %v", err)
+			}
+			host, hostStart := nessusHostResult(x)
+			if started.IsZero() || (!hostStart.IsZero() && hostStart.Before(started)) {
+				started = hostStart
+			}
+			scan.Hosts[host.IP] = host
+			scan.Ports[host.IP] = host.PortStrings()
+		}
+	}
+
+	// The scan time is when Nessus started on the first host
+	if started.IsZero() {
+		info, err := f.Stat()
+		if err != nil {
+			return ScanResult{}, err
+		}
+		started = info.ModTime()
+	}
+	scan.DateTime = started.UTC().Format(time.RFC3339)
+	scan.ScannerVersion = "Nessus"
+	return scan, nil
+}
+
+// nessusHostResult converts a <ReportHost>, returning when Nessus started
+// scanning it (zero if the export doesn't say)
+func nessusHostResult(x nessusHost) (HostResult, time.Time) {
+	host := HostResult{IP: x.Name, State: HostUp}
+	var started time.Time
+	for _, p := range x.Properties {
+		value := strings.TrimSpace(p.Value)
+		switch p.Name {
+		case "host-ip":
+			host.IP = value
+		case "host-fqdn", "hostname":
+			if host.Hostname == "" {
+				host.Hostname = value
+			}
+		case "operating-system":
+			host.OS, _, _ = strings.Cut(value, "\n") // One candidate per line; the first is the best match
+		case "mac-address":
+			host.MAC, _, _ = strings.Cut(value, "\n")
+		case "HOST_START_TIMESTAMP":
+			if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+				started = time.Unix(secs, 0)
+			}
+		case "HOST_START":
+			if t, err := time.ParseInLocation(nessusHostStartLayout, value, time.Local); err == nil && started.IsZero() {
+				started = t
+			}
+		}
+	}
+
+	ports := make(map[string]*PortEntry)
+	for _, item := range x.Items {
+		if item.Port == 0 {
+			continue
+		}
+		key := strconv.Itoa(item.Port) + "/" + item.Protocol
+		entry, ok := ports[key]
+		if !ok {
+			entry = &PortEntry{
+				Port:     item.Port,
+				Protocol: item.Protocol,
+				State:    "open",
+				Service:  strings.TrimSuffix(item.Service, "?"),
+				Scripts:  make(map[string]string),
+			}
+			ports[key] = entry
+		}
+		finding := fmt.Sprintf("[severity %d] %s", item.Severity, item.PluginName)
+		if output := strings.TrimSpace(item.Output); output != "" {
+			finding += "\n" + output
+		}
+		entry.Scripts["nessus-"+item.PluginID] = finding
+	}
+	for _, entry := range ports {
+		host.Ports = append(host.Ports, *entry)
+	}
+	sort.Slice(host.Ports, func(i, j int) bool {
+		a, b := host.Ports[i], host.Ports[j]
+		return a.Port < b.Port || (a.Port == b.Port && a.Protocol < b.Protocol)
+	})
+	// Not checked against IANA: Nessus names services its own way, e.g. "www" for HTTPS
+	host.setExposureFlags()
+	return host, started
+}
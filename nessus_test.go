@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseNessusXMLFixture(t *testing.T) {
+	scan, err := ParseNessusXML(filepath.Join("testdata", "nessus_report.nessus"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Host-level findings (port 0) are left out, and services keep Nessus's names
+	want := map[string][]string{
+		"192.168.1.10": {"22/tcp [open] (ssh)", "80/tcp [open] (www)", "443/tcp [open] (www)"},
+		"192.168.1.20": {"137/udp [open] (netbios-ns)", "445/tcp [open] (cifs)", "3389/tcp [open] (msrdp)"},
+	}
+	if !reflect.DeepEqual(scan.Ports, want) {
+		t.Errorf("got %v\nwant %v", scan.Ports, want)
+	}
+	if scan.Target != "Office LAN" || scan.DateTime != "2025-03-04T10:00:00Z" || scan.ScannerVersion != "Nessus" {
+		t.Errorf("target %q, time %q, scanner %q", scan.Target, scan.DateTime, scan.ScannerVersion)
+	}
+
+	web := scan.Hosts["192.168.1.10"]
+	if web.Hostname != "web01.example.com" || web.OS != "Linux Kernel 5.15 on Ubuntu 22.04" || web.MAC != "52:54:00:12:34:56" {
+		t.Errorf("192.168.1.10: hostname %q, OS %q, MAC %q", web.Hostname, web.OS, web.MAC)
+	}
+	if ssh := web.Ports[0]; len(ssh.Scripts) != 2 || ssh.Scripts["nessus-153953"] != "[severity 2] SSH Weak Key Exchange Algorithms Enabled\nThe following weak key exchange algorithms are enabled :\n\n  diffie-hellman-group-exchange-sha1" {
+		t.Errorf("22/tcp findings %q", ssh.Scripts)
+	}
+
+	// Hosts named other than by address are keyed by their host-ip
+	files := scan.Hosts["192.168.1.20"]
+	if files.Hostname != "fileserver" || !files.SMBExposed || !files.RDPExposed {
+		t.Errorf("192.168.1.20: %+v", files)
+	}
+	if got := files.Ports[1].Scripts["nessus-57608"]; got != "[severity 3] SMB Signing not required" {
+		t.Errorf("445/tcp finding %q", got)
+	}
+}
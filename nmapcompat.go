@@ -0,0 +1,70 @@
+package main
+
+import (
+	"regexp"
+	"sync"
+)
+
+// nmapOutputVariant is how a range of nmap versions lays out its normal (-oN)
+// output, as far as ParseNmapOutput is concerned
+type nmapOutputVariant struct {
+	Since  string         // First nmap version with this layout
+	hostRe *regexp.Regexp // Starts a host's section; the address is the last group
+}
+
+// legacyHostRe matches the host headers nmap used before 5.10: "Interesting
+// ports on scanme.nmap.org (64.13.134.52):", or "All 1697 scanned ports on
+// 10.0.0.1 are: filtered" when there was nothing to list
+var legacyHostRe = regexp.MustCompile(`^(?:Interesting ports on|All \d+ scanned ports on) (?:\S+ \()?([^\s()]+?)\)?(?::| are:? .*)$`)
+
+// nmapCompatibility is the table of nmap output layouts, oldest first. Versions
+// from the first entry's Since up to newestTestedNmap are known; XML and
+// grepable output have not changed in ways that matter across them. NSE
+// output is only read from XML, whose <script output=""> attribute is the
+// same in every version.
+var nmapCompatibility = []nmapOutputVariant{
+	{Since: minNmapVersion, hostRe: legacyHostRe},
+	{Since: "5.10", hostRe: scanReportRe}, // "Nmap scan report for", and the PORT table as it is today
+}
+
+// newestTestedNmap is the newest nmap release whose output PortHunter is known to parse
+const newestTestedNmap = "7.95"
+
+// warnedNmapVersions records the unknown versions already warned about, so
+// watch mode warns once rather than every scan
+var warnedNmapVersions sync.Map
+
+// nmapVariantFor returns the output layout of an nmap version, and whether
+// the version is in the compatibility table. Versions outside it get the
+// closest known layout; an unknown version ("") gets the current one.
+func nmapVariantFor(version string) (nmapOutputVariant, bool) {
+	newest := nmapCompatibility[len(nmapCompatibility)-1]
+	if version == "" {
+		return newest, true
+	}
+	if compareVersions(version, nmapCompatibility[0].Since) < 0 {
+		return nmapCompatibility[0], false
+	}
+	for i := len(nmapCompatibility) - 1; i >= 0; i-- {
+		if compareVersions(version, nmapCompatibility[i].Since) >= 0 {
+			return nmapCompatibility[i], compareVersions(version, newestTestedNmap) <= 0
+		}
+	}
+	return newest, false
+}
+
+// nmapParserFor chooses the output layout to parse an nmap version's output
+// with, warning (once per version) if the version isn't in the table
+func nmapParserFor(version string) nmapOutputVariant {
+	variant, known := nmapVariantFor(version)
+	if !known {
+		if _, warned := warnedNmapVersions.LoadOrStore(version, true); !warned {
+			closest := variant.Since
+			if compareVersions(version, newestTestedNmap) > 0 {
+				closest = newestTestedNmap
+			}
+			logger.Warn("nmap %s is not in PortHunter's compatibility table, parsing its output as nmap %s's", version, closest)
+		}
+	}
+	return variant
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// NmapErrorCode is the broad cause of a failed nmap run
+type NmapErrorCode int
+
+const (
+	UnknownError NmapErrorCode = iota
+	PermissionDenied
+	HostNotFound
+	NetworkUnreachable
+	InvalidArgument
+)
+
+func (c NmapErrorCode) String() string {
+	return [...]string{"unknown error", "permission denied", "host not found", "network unreachable", "invalid argument"}[c]
+}
+
+// NmapError is a classified nmap failure with a hint on how to fix it
+type NmapError struct {
+	Code     NmapErrorCode
+	ExitCode int
+	Message  string // The line of nmap's output that explains the failure
+	Hint     string
+}
+
+func (e *NmapError) Error() string {
+	return fmt.Sprintf("nmap failed (%s, exit code %d): %s", e.Code, e.ExitCode, e.Message)
+}
+
+// nmapErrorPatterns maps phrases in nmap's output to the failure they indicate.
+// They are matched in order, case-insensitively.
+var nmapErrorPatterns = []struct {
+	code    NmapErrorCode
+	phrases []string
+	hint    string
+}{
+	{PermissionDenied, []string{"requires root privileges", "operation not permitted", "permission denied", "failed to open device"},
+		"Try running as root, e.g. prefix the command with sudo"},
+	{InvalidArgument, []string{"unrecognized option", "invalid argument", "illegal", "found no matches for the service mask", "quitting!"},
+		"Check the nmap options in the scan command (see nmap --help)"},
+	{HostNotFound, []string{"failed to resolve", "no targets were specified", "unable to split netmask"},
+		"Check the target's spelling and that it resolves in DNS"},
+	{NetworkUnreachable, []string{"network is unreachable", "no route to host"},
+		"Check the network connection and routing to the target"},
+}
+
+// ClassifyNmapError works out why nmap exited with exitCode from its output
+func ClassifyNmapError(exitCode int, output string) NmapError {
+	var lastLine string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		lastLine = line
+		lower := strings.ToLower(line)
+		for _, p := range nmapErrorPatterns {
+			for _, phrase := range p.phrases {
+				if strings.Contains(lower, phrase) {
+					return NmapError{Code: p.code, ExitCode: exitCode, Message: line, Hint: p.hint}
+				}
+			}
+		}
+	}
+	if lastLine == "" {
+		lastLine = "no output"
+	}
+	return NmapError{Code: UnknownError, ExitCode: exitCode, Message: lastLine, Hint: "Run the scan command by hand to see nmap's full output"}
+}
+
+// logScanError logs err, followed by the hint if nmap's failure was classified
+func logScanError(err error) {
+	logger.Error("%v", err)
+	var nmapErr *NmapError
+	if errors.As(err, &nmapErr) && nmapErr.Hint != "" {
+		fmt.Println("Hint:", nmapErr.Hint)
+	}
+}
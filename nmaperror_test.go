@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyNmapError(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   NmapErrorCode
+	}{
+		{"raw socket", "Starting Nmap 7.94 ( https://nmap.org )\nYou requested a scan type which requires root privileges.\nQUITTING!\n", PermissionDenied},
+		{"socket error", "dnet: Failed to open device eth0\nQUITTING!\n", PermissionDenied},
+		{"bad option", "nmap: unrecognized option '--fast'\nSee the output of nmap -h for a summary of options.\n", InvalidArgument},
+		{"unresolvable", "Failed to resolve \"nosuchhost.invalid\".\nWARNING: No targets were specified, so 0 hosts scanned.\n", HostNotFound},
+		{"no route", "sendto in send_ip_packet_sd: sendto(5, packet, 44, 0, 10.9.9.9, 16) => Network is unreachable\n", NetworkUnreachable},
+		{"unknown", "Segmentation fault\n", UnknownError},
+		{"no output", "", UnknownError},
+	}
+	for _, tt := range tests {
+		got := ClassifyNmapError(1, tt.output)
+		if got.Code != tt.want || got.Hint == "" || got.ExitCode != 1 {
+			t.Errorf("%s: got %+v, want code %s with a hint", tt.name, got, tt.want)
+		}
+	}
+
+	got := ClassifyNmapError(1, "Starting Nmap 7.94\nYou requested a scan type which requires root privileges.\n")
+	if got.Message != "You requested a scan type which requires root privileges." {
+		t.Errorf("message %q, want the line explaining the failure", got.Message)
+	}
+}
+
+func TestRunScanClassifiesFailure(t *testing.T) {
+	installFakeNmap(t, fakeNmapRun{
+		stderr:   "You requested a scan type which requires root privileges.\nQUITTING!\n",
+		exitCode: 1,
+	})
+
+	_, err := RunScan("nmap -sS", "10.0.0.1")
+	var nmapErr *NmapError
+	if !errors.As(err, &nmapErr) || nmapErr.Code != PermissionDenied || nmapErr.ExitCode != 1 {
+		t.Errorf("RunScan error %v, want a permission denied *NmapError", err)
+	}
+}
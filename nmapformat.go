@@ -0,0 +1,119 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"strings"
+)
+
+// NmapFormat is one of nmap's output formats
+type NmapFormat int
+
+const (
+	FormatNormal   NmapFormat = iota // -oN, the default terminal output
+	FormatXML                        // -oX
+	FormatGrepable                   // -oG
+	FormatScript                     // -oS, "script kiddie" output
+)
+
+func (f NmapFormat) String() string {
+	return [...]string{"normal", "XML", "grepable", "script kiddie"}[f]
+}
+
+// ErrUnknownNmapFormat is returned for output that is not from nmap
+var ErrUnknownNmapFormat = errors.New("unrecognised nmap output (expected normal, XML, grepable or script kiddie format)")
+
+// normalMarkers are lines that only appear in nmap's normal output
+var normalMarkers = []string{"Nmap scan report for", "Starting Nmap", "Nmap done:"}
+
+// DetectOutputFormat works out which nmap format output is in
+func DetectOutputFormat(output string) (NmapFormat, error) {
+	if isXMLOutput(output) {
+		return FormatXML, nil
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "Host: ") && (strings.Contains(line, "\tPorts: ") || strings.Contains(line, "\tStatus: ")) {
+			return FormatGrepable, nil
+		}
+	}
+	for _, marker := range normalMarkers {
+		if strings.Contains(output, marker) {
+			return FormatNormal, nil
+		}
+	}
+	unleet := strings.ToLower(unleetLine(output))
+	for _, marker := range normalMarkers {
+		if strings.Contains(unleet, strings.ToLower(marker)) {
+			return FormatScript, nil
+		}
+	}
+	return 0, ErrUnknownNmapFormat
+}
+
+// ParseAnyNmapOutput detects the format of output and parses it, returning
+// the port strings per host and, for XML, the full host detail. version is
+// the nmap version that wrote it, or "" to read it from the output.
+func ParseAnyNmapOutput(output, version string) (map[string][]string, map[string]HostResult, error) {
+	format, err := DetectOutputFormat(output)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch format {
+	case FormatXML:
+		return ParseNmapXMLOutput(output)
+	case FormatScript:
+		output = unleetOutput(output)
+		return ParseNmapOutputVersion(output, cmp.Or(version, nmapOutputVersion(output))), nil, nil
+	case FormatGrepable:
+		ports, err := ParseNmapGrepable(output)
+		return ports, nil, err
+	default:
+		return ParseNmapOutputVersion(output, cmp.Or(version, nmapOutputVersion(output))), nil, nil
+	}
+}
+
+// unleetOutput undoes the character swaps of nmap's script kiddie output
+// ("0p3n", "$$h") line by line, so it can be read by ParseNmapOutput
+func unleetOutput(output string) string {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		lines[i] = unleetLine(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unleetLine lowercases every word and un-swaps those without numbers that
+// must be kept as they are: addresses such as "10.0.0.1" and port specifiers
+// such as "80/tcp". The phrases ParseNmapOutput looks for are restored.
+func unleetLine(line string) string {
+	words := strings.Fields(strings.ToLower(line))
+	for i, word := range words {
+		if !isNumericToken(word) {
+			words[i] = leetReplacer.Replace(word)
+		}
+	}
+	return unleetPhrases.Replace(strings.Join(words, " "))
+}
+
+// unleetPhrases restores capitalisation and the "|" that leetReplacer cannot tell from an "i"
+var unleetPhrases = strings.NewReplacer(
+	"nmap scan report for", "Nmap scan report for",
+	"starting nmap", "Starting Nmap",
+	"nmap done:", "Nmap done:",
+	"openifiltered", "open|filtered",
+	"closedifiltered", "closed|filtered",
+)
+
+// leetReplacer reverses nmap's script kiddie substitutions. "z" is ambiguous
+// (nmap also swaps z and s), but s is far more common in nmap's vocabulary.
+var leetReplacer = strings.NewReplacer("4", "a", "3", "e", "0", "o", "!", "i", "|", "i", "1", "i", "$", "s", "z", "s")
+
+// isNumericToken reports whether a word is an address or port specifier
+// whose digits must not be un-swapped, e.g. "10.0.0.1", "(10.0.0.1)" or "80/tcp"
+func isNumericToken(word string) bool {
+	word = strings.Trim(word, "()")
+	if port, _, ok := strings.Cut(word, "/"); ok && port != "" && strings.Trim(port, "0123456789") == "" {
+		return true
+	}
+	return strings.Trim(word, "0123456789.:abcdef") == "" && strings.ContainsAny(word, ".:")
+}
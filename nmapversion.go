@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// version is set at build time with -ldflags "-X main.version=1.0.0"
+var version = "dev"
+
+// "Nmap version 7.94 ( https://nmap.org )" or "Nmap version 7.94SVN ..."
+var nmapVersionRe = regexp.MustCompile(`Nmap version (\d+(?:\.\d+)*)`)
+
+// "Starting Nmap 7.94 ( https://nmap.org )" or <nmaprun ... version="7.94"> at the top of a scan's output
+var nmapOutputVersionRe = regexp.MustCompile(`Starting Nmap (\d+(?:\.\d+)*)|<nmaprun\b[^>]*\sversion="(\d+(?:\.\d+)*)`)
+
+// Minimum nmap versions for features PortHunter relies on
+const (
+	minNmapVersion       = "3.0" // -oX XML output
+	minNmapScriptVersion = "5.0" // NSE scripting
+)
+
+// DetectNmapVersion runs "nmap --version" and returns the version number
+func DetectNmapVersion() (string, error) {
+	path, err := lookupNmap("nmap")
+	if err != nil {
+		return "", err
+	}
+	return runNmapVersion(path)
+}
+
+// nmapVersions caches nmapVersionAt by executable path
+var nmapVersions sync.Map
+
+// nmapVersionAt returns the version of the nmap executable at path, or "" if
+// it can't be found. It is only run once per executable.
+func nmapVersionAt(path string) string {
+	if v, ok := nmapVersions.Load(path); ok {
+		return v.(string)
+	}
+	v, err := runNmapVersion(path)
+	if err != nil {
+		logger.Debug("detecting the version of %s: %v", path, err)
+	}
+	nmapVersions.Store(path, v)
+	return v
+}
+
+// runNmapVersion runs the nmap executable at path with --version
+func runNmapVersion(path string) (string, error) {
+	out, err := exec.Command(path, "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("running nmap --version: %v", err)
+	}
+	m := nmapVersionRe.FindStringSubmatch(string(out))
+	if m == nil {
+		return "", fmt.Errorf("could not find a version in nmap --version output")
+	}
+	return m[1], nil
+}
+
+// nmapOutputVersion returns the nmap version that produced output, if it says
+func nmapOutputVersion(output string) string {
+	m := nmapOutputVersionRe.FindStringSubmatch(output)
+	if m == nil {
+		return ""
+	}
+	return m[1] + m[2]
+}
+
+// CheckNmapVersion returns the installed nmap version, with an error if it is
+// older than minVersion
+func CheckNmapVersion(minVersion string) (string, error) {
+	detected, err := DetectNmapVersion()
+	if err != nil {
+		return "", err
+	}
+	if compareVersions(detected, minVersion) < 0 {
+		return detected, fmt.Errorf("nmap %s is older than the required %s; output may not parse correctly", detected, minVersion)
+	}
+	return detected, nil
+}
+
+// RequiredNmapVersion returns the minimum nmap version needed for the
+// features used by a scan command
+func RequiredNmapVersion(command string) string {
+	for _, arg := range strings.Fields(command) {
+		if arg == "-sC" || arg == "-A" || strings.HasPrefix(arg, "--script") {
+			return minNmapScriptVersion
+		}
+	}
+	return minNmapVersion
+}
+
+// compareVersions compares dotted numeric versions, returning -1, 0 or 1
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// PrintVersion prints the PortHunter version and the detected nmap version
+func PrintVersion() {
+	fmt.Println("PortHunter", version)
+	if v, err := DetectNmapVersion(); err == nil {
+		fmt.Println("nmap", v)
+	} else {
+		fmt.Println("nmap not found:", err)
+	}
+}
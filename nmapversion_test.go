@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNmapOutputVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"Starting Nmap 7.94 ( https://nmap.org ) at 2024-05-01 10:00 UTC\n", "7.94"},
+		{"Starting Nmap 7.94SVN ( https://nmap.org ) at 2024-05-01 10:00 UTC\n", "7.94"},
+		{`<?xml version="1.0"?>` + "\n" + `<nmaprun scanner="nmap" args="nmap -oX - 10.0.0.1" start="1714557600" version="7.93" xmloutputversion="1.05">`, "7.93"},
+		{"Nmap scan report for 10.0.0.1\n", ""},
+	}
+	for _, tt := range tests {
+		if got := nmapOutputVersion(tt.output); got != tt.want {
+			t.Errorf("nmapOutputVersion(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestRunScanRecordsCommand(t *testing.T) {
+	fakeNmap(t, "tcp_default.txt")
+
+	scan, err := RunScanWithOptions("nmap -sT -p 1-1000", "192.168.1.0/24", ScanOptions{Exclusions: []string{"192.168.1.254"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.ScannerVersion != "7.94" {
+		t.Errorf("ScannerVersion %q, want the fake nmap's 7.94", scan.ScannerVersion)
+	}
+	if !strings.HasPrefix(scan.Command, "nmap -sT -p 1-1000") || !strings.Contains(scan.Command, "192.168.1.254") || !strings.HasSuffix(scan.Command, "192.168.1.0/24") {
+		t.Errorf("Command %q, want the command line with the exclusion and target appended", scan.Command)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultServiceAliases maps names different nmap versions and scripts use
+// for the same service onto one canonical name
+var defaultServiceAliases = map[string]string{
+	"www":           "http",
+	"www-http":      "http",
+	"http-alt":      "http",
+	"ssl/http":      "https",
+	"ssl/https":     "https",
+	"https-alt":     "https",
+	"ssl/ssh":       "ssh",
+	"microsoft-ds":  "smb",
+	"netbios-ssn":   "smb",
+	"ms-wbt-server": "rdp",
+	"postgres":      "postgresql",
+	"ms-sql-s":      "mssql",
+	"domain":        "dns",
+}
+
+// serviceAliases is defaultServiceAliases plus service_aliases from the
+// config; rebuilt by applyConfig
+var serviceAliases = defaultServiceAliases
+
+// diffNormaliser is applied to port entries before they are compared. Replace
+// it to change what counts as the same port, or set it to nil for exact matching.
+var diffNormaliser = NormalisePortEntry
+
+// NormaliseServiceName lowercases a service name, drops nmap's "?" marker for
+// guessed services and maps known aliases to a canonical name
+func NormaliseServiceName(service string) string {
+	service = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(service)), "?")
+	if canonical, ok := serviceAliases[service]; ok {
+		return canonical
+	}
+	return service
+}
+
+// NormalisePortEntry rewrites a port entry such as "80/tcp [open] (www)" with
+// its service name normalised, e.g. "80/tcp [open] (http)"
+func NormalisePortEntry(entry string) string {
+	port, proto, state, service := splitPortEntry(entry)
+	return fmt.Sprintf("%s/%s [%s] (%s)", port, proto, state, NormaliseServiceName(service))
+}
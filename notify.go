@@ -0,0 +1,102 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Notifier delivers a message to one notification channel
+type Notifier interface {
+	Send(subject, body string) error
+}
+
+// configuredNotifiers returns every notification channel set up in cfg
+func configuredNotifiers(cfg Config) []Notifier {
+	var notifiers []Notifier
+	if cfg.Email.Enabled() {
+		notifiers = append(notifiers, cfg.Email)
+	}
+	if cfg.Jira.Enabled() {
+		notifiers = append(notifiers, cfg.Jira)
+	}
+	return notifiers
+}
+
+// notify sends the diff report to each of the App's notifiers, unless a
+// change window covers it. Email and JIRA get the full diff email and a ticket
+// for high-risk changes; any other Notifier gets the diff as text. With a
+// digest configured, changes are queued for it instead of emailed. Failures
+// are reported but never abort the scan.
+func (a *App) notify(report DiffReport, scan ScanResult) {
+	cfg := a.Config
+	if w, ok := suppressingWindow(cfg.ChangeWindows, report, time.Now()); ok {
+		a.Logger.Info("Notifications suppressed during maintenance window %s (until %s)", w.Reason, w.End.Format(time.RFC3339))
+		return
+	}
+
+	digest, digesting := NewDigestNotifier(cfg)
+	if digesting {
+		if err := digest.Add(report); err != nil {
+			a.Logger.Error("queueing changes for the digest: %v", err)
+		}
+	}
+
+	for _, n := range a.Notifiers {
+		switch n := n.(type) {
+		case EmailConfig:
+			if digesting {
+				continue
+			}
+			if err := SendDiffEmail(n, report, scan); err != nil {
+				a.Logger.Error("sending email notification: %v", err)
+			}
+		case JIRAClient:
+			if !report.HighRisk() {
+				continue
+			}
+			key, err := n.CreateTicketForDiff(report)
+			switch {
+			case errors.Is(err, ErrTicketRateLimited):
+				a.Logger.Info("JIRA ticket %s already open for %s, not creating another", key, report.Target)
+			case err != nil:
+				a.Logger.Error("creating JIRA ticket: %v", err)
+			default:
+				fmt.Printf("Created JIRA ticket %s\n", key)
+			}
+		default:
+			added, removed := report.Totals()
+			if err := n.Send(fmt.Sprintf("PortHunter: %d ports added, %d removed", added, removed), report.Text()); err != nil {
+				a.Logger.Error("sending notification: %v", err)
+			}
+		}
+	}
+}
+
+// notifyScanFailures tells every notifier that scans keep failing, which
+// points to a problem with the scanner rather than a one-off network error,
+// unless a change window covers the target
+func notifyScanFailures(log Logger, notifiers []Notifier, windows []ChangeWindow, target string, failures int, err error) {
+	if w, ok := suppressingAlertWindow(windows, target, time.Now()); ok {
+		log.Info("Scan failure alert suppressed during maintenance window %s (until %s)", w.Reason, w.End.Format(time.RFC3339))
+		return
+	}
+	target = cmp.Or(target, "the configured targets")
+	subject := fmt.Sprintf("PortHunter: %d scans of %s failed in a row", failures, target)
+	body := fmt.Sprintf("The last %d scans of %s failed, so watch mode is backing off.\n\nLast error: %v\n", failures, target, err)
+	for _, n := range notifiers {
+		var err error
+		if jira, ok := n.(JIRAClient); ok {
+			err = jira.SendForTarget(target, subject, body)
+		} else {
+			err = n.Send(subject, body)
+		}
+		switch {
+		case errors.Is(err, ErrTicketRateLimited):
+			log.Info("JIRA ticket already open for failing scans of %s, not creating another", target)
+		case err != nil:
+			log.Error("sending scan failure notification: %v", err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// "OS details: Linux 5.0 - 5.4", printed by nmap -O when it has a confident match
+	osDetailsRe = regexp.MustCompile(`^OS details: (.+)$`)
+	// The SCAN test records when and how the fingerprint was taken, not the host
+	fingerprintScanRe = regexp.MustCompile(`SCAN\([^)]*\)`)
+)
+
+// applyOSDetails copies the OS matches and TCP/IP fingerprints in nmap's text
+// output onto the scan's hosts
+func applyOSDetails(scan *ScanResult, output string) {
+	var currentIP string
+	var fingerprint []string
+	inFingerprint := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if inFingerprint && strings.HasPrefix(line, "OS:") {
+			fingerprint = append(fingerprint, line)
+			continue
+		}
+		if inFingerprint {
+			inFingerprint = false
+			setHostOS(scan, currentIP, func(h *HostResult) { h.OSFingerprint = strings.Join(fingerprint, "\n") })
+		}
+
+		if m := scanReportRe.FindStringSubmatch(line); m != nil {
+			currentIP = m[1]
+		} else if m := osDetailsRe.FindStringSubmatch(line); m != nil {
+			setHostOS(scan, currentIP, func(h *HostResult) { h.OS = m[1] })
+		} else if strings.HasPrefix(line, "TCP/IP fingerprint:") {
+			inFingerprint, fingerprint = true, nil
+		}
+	}
+	if inFingerprint {
+		setHostOS(scan, currentIP, func(h *HostResult) { h.OSFingerprint = strings.Join(fingerprint, "\n") })
+	}
+}
+
+func setHostOS(scan *ScanResult, ip string, set func(*HostResult)) {
+	if host, ok := scan.Hosts[ip]; ok {
+		set(&host)
+		scan.Hosts[ip] = host
+	}
+}
+
+// fingerprintTests reduces a raw nmap fingerprint to the results of its
+// probes, dropping line wrapping and the SCAN test, which differs every run
+func fingerprintTests(fingerprint string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(fingerprint, "\n") {
+		sb.WriteString(strings.TrimPrefix(strings.TrimSpace(line), "OS:"))
+	}
+	return fingerprintScanRe.ReplaceAllString(sb.String(), "")
+}
+
+// OSChange is a host whose TCP/IP stack fingerprint changed between scans
+type OSChange struct {
+	IP             string `json:"ip"`
+	OldOS          string `json:"old_os,omitempty"`
+	NewOS          string `json:"new_os,omitempty"`
+	OldFingerprint string `json:"old_fingerprint"`
+	NewFingerprint string `json:"new_fingerprint"`
+}
+
+// SystemUpdated reports whether the host still runs the same family of OS (as
+// far as nmap could tell), so the new fingerprint most likely comes from a
+// patch or upgrade rather than a different machine
+func (c OSChange) SystemUpdated() bool {
+	return c.OldOS == "" || c.NewOS == "" || osFamily(c.OldOS) == osFamily(c.NewOS)
+}
+
+func (c OSChange) String() string {
+	if !c.SystemUpdated() {
+		return fmt.Sprintf("OS of %s changed from %s to %s", c.IP, c.OldOS, c.NewOS)
+	}
+	if c.OldOS != "" && c.NewOS != "" && c.OldOS != c.NewOS {
+		return fmt.Sprintf("System updated on %s: TCP/IP fingerprint changed, %s -> %s", c.IP, c.OldOS, c.NewOS)
+	}
+	if c.OldOS == c.NewOS && c.NewOS != "" {
+		return fmt.Sprintf("System updated on %s: TCP/IP fingerprint changed, still %s", c.IP, c.NewOS)
+	}
+	return fmt.Sprintf("System updated on %s: TCP/IP fingerprint changed", c.IP)
+}
+
+// osFamily is the first word of an OS match, e.g. "Linux" for "Linux 5.0 - 5.4"
+func osFamily(name string) string {
+	family, _, _ := strings.Cut(name, " ")
+	return family
+}
+
+// DiffOSFingerprints lists hosts whose TCP/IP fingerprint changed. Hosts
+// fingerprinted in only one scan are skipped.
+func DiffOSFingerprints(old, new ScanResult) []OSChange {
+	var changes []OSChange
+	for _, ip := range sortedHostKeys(new.Hosts) {
+		n, o := new.Hosts[ip], old.Hosts[ip]
+		if n.OSFingerprint == "" || o.OSFingerprint == "" || fingerprintTests(n.OSFingerprint) == fingerprintTests(o.OSFingerprint) {
+			continue
+		}
+		changes = append(changes, OSChange{IP: ip, OldOS: o.OS, NewOS: n.OS, OldFingerprint: o.OSFingerprint, NewFingerprint: n.OSFingerprint})
+	}
+	return changes
+}
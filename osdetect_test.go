@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// osFingerprintOutput is the end of an nmap -O run that found no confident match
+const osFingerprintOutput = `Nmap scan report for 10.0.0.7
+Host is up (0.00040s latency).
+PORT   STATE SERVICE
+22/tcp open  ssh
+No exact OS matches for host (If you know what OS is running on it, see https://nmap.org/submit/ ).
+TCP/IP fingerprint:
+OS:SCAN(V=7.94%E=4%D=5/1%OT=22%CT=1%CU=33521%PV=Y%DS=1%DC=D%G=Y%TM=66321A2B
+OS:%P=x86_64-pc-linux-gnu)SEQ(SP=105%GCD=1%ISR=10A%TI=Z%CI=Z%II=I%TS=A)
+OS:WIN(W1=FE88%W2=FE88%W3=FE88%W4=FE88%W5=FE88%W6=FE88)
+
+Network Distance: 1 hop
+`
+
+func TestApplyOSDetailsFingerprint(t *testing.T) {
+	scan := ScanResult{Hosts: map[string]HostResult{"10.0.0.7": {IP: "10.0.0.7"}}}
+	applyOSDetails(&scan, osFingerprintOutput)
+	fingerprint := scan.Hosts["10.0.0.7"].OSFingerprint
+	if !strings.HasPrefix(fingerprint, "OS:SCAN(") || !strings.HasSuffix(fingerprint, "W6=FE88)") || strings.Count(fingerprint, "\n") != 2 {
+		t.Errorf("fingerprint %q, want the three OS: lines", fingerprint)
+	}
+}
+
+func TestDiffOSFingerprints(t *testing.T) {
+	const (
+		base    = "OS:SCAN(V=7.94%D=5/1%TM=66321A2B)SEQ(SP=105%GCD=1%ISR=10A)\nOS:WIN(W1=FE88)"
+		rescan  = "OS:SCAN(V=7.94%D=5/8%TM=663B54AB)SEQ(SP=105%GCD=1%ISR=10A)\nOS:WIN(W1=FE88)"
+		patched = "OS:SCAN(V=7.94%D=5/8%TM=663B54AB)SEQ(SP=102%GCD=1%ISR=10C)\nOS:WIN(W1=FAF0)"
+	)
+	scan := func(os, fingerprint string) ScanResult {
+		return ScanResult{Hosts: map[string]HostResult{"10.0.0.7": {IP: "10.0.0.7", OS: os, OSFingerprint: fingerprint}}}
+	}
+
+	tests := []struct {
+		name     string
+		old, new ScanResult
+		want     string
+	}{
+		{"only the scan time differs", scan("Linux 5.4", base), scan("Linux 5.4", rescan), ""},
+		{"not fingerprinted before", scan("", ""), scan("Linux 5.4", patched), ""},
+		{"same OS name", scan("Linux 5.4", base), scan("Linux 5.4", patched), "System updated on 10.0.0.7: TCP/IP fingerprint changed, still Linux 5.4"},
+		{"newer kernel", scan("Linux 5.4", base), scan("Linux 5.15", patched), "System updated on 10.0.0.7: TCP/IP fingerprint changed, Linux 5.4 -> Linux 5.15"},
+		{"different OS", scan("Linux 5.4", base), scan("Microsoft Windows 10", patched), "OS of 10.0.0.7 changed from Linux 5.4 to Microsoft Windows 10"},
+	}
+	for _, tt := range tests {
+		changes := DiffOSFingerprints(tt.old, tt.new)
+		if tt.want == "" {
+			if len(changes) != 0 {
+				t.Errorf("%s: got %v, want no change", tt.name, changes)
+			}
+			continue
+		}
+		if len(changes) != 1 || changes[0].String() != tt.want {
+			t.Errorf("%s: got %v, want %q", tt.name, changes, tt.want)
+			continue
+		}
+		if report := BuildDiffReport(tt.old, tt.new); !report.HasChanges() || !strings.Contains(report.Text(), tt.want) {
+			t.Errorf("%s: diff report does not include the change:\n%s", tt.name, report.Text())
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// outputFormat is one of the files written after each scan
+type outputFormat struct {
+	name  string
+	write func() error
+}
+
+// WriteOutputs writes every output file cfg asks for (-output-json,
+// -output-csv, -output-markdown, -output-nmap-xml, -output-template and the -html report), one
+// goroutine per format. A format that fails does not stop the others; all
+// their errors are returned together.
+func WriteOutputs(cfg Config, scan ScanResult, report DiffReport) error {
+	var formats []outputFormat
+	if cfg.OutputJSON != "" {
+		formats = append(formats, outputFormat{"JSON", func() error { return writeScanJSON(cfg.OutputJSON, scan) }})
+	}
+	if cfg.OutputCSV != "" {
+		formats = append(formats, outputFormat{"CSV", func() error { return writeScanCSV(cfg.OutputCSV, scan) }})
+	}
+	if cfg.OutputMarkdown != "" {
+		formats = append(formats, outputFormat{"Markdown", func() error {
+			return os.WriteFile(cfg.OutputMarkdown, []byte(report.Markdown()), 0644)
+		}})
+	}
+	if cfg.OutputNmapXML != "" {
+		formats = append(formats, outputFormat{"nmap XML", func() error { return writeScanNmapXML(cfg.OutputNmapXML, scan) }})
+	}
+	if cfg.OutputTemplate != "" {
+		formats = append(formats, outputFormat{"template", func() error {
+			return writeTemplateReport(cfg.OutputTemplate, cfg.OutputText, scan, report)
+		}})
+	}
+	if cfg.HTMLReport != "" || cfg.OpenReport {
+		formats = append(formats, outputFormat{"HTML", func() error { return writeHTMLReport(cfg, scan, report) }})
+	}
+
+	errs := make([]error, len(formats))
+	var wg sync.WaitGroup
+	for i, f := range formats {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f.write(); err != nil {
+				errs[i] = fmt.Errorf("writing %s output: %v", f.name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// writeScanJSON writes scan as indented JSON, the same as previous_scan.json
+func writeScanJSON(path string, scan ScanResult) error {
+	data, err := json.MarshalIndent(scan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// writeScanNmapXML writes scan as nmap XML, see ExportNmapXML
+func writeScanNmapXML(path string, scan ScanResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := ExportNmapXML(scan, f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeScanCSV writes one row per host and port, with the same fields as
+// the OpenSearch documents
+func writeScanCSV(path string, scan ScanResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"datetime", "target", "ip", "hostname", "os", "port", "protocol", "state", "service"})
+	for _, doc := range opensearchDocs(scan) {
+		w.Write([]string{doc.DateTime, doc.Target, doc.IP, doc.Hostname, doc.OS, strconv.Itoa(doc.Port), doc.Protocol, doc.State, doc.Service})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
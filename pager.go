@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Pager writes diff output, pausing after every PageSize changes until the
+// user presses Enter. A PageSize of 0 disables paging.
+type Pager struct {
+	PageSize int
+	In       io.Reader
+	Out      io.Writer
+
+	reader  *bufio.Reader
+	changes int
+	quit    bool
+}
+
+// diffPager is used by CompareScansDiff; its PageSize is set from -page-size
+var diffPager = &Pager{In: os.Stdin, Out: os.Stdout}
+
+// Printf writes a non-change line such as a host heading
+func (p *Pager) Printf(format string, args ...any) {
+	if p.quit {
+		return
+	}
+	fmt.Fprintf(p.Out, format, args...)
+}
+
+// Change writes a single changed port line, prompting first if a page is full
+func (p *Pager) Change(format string, args ...any) {
+	if p.quit {
+		return
+	}
+
+	if p.PageSize > 0 && p.changes > 0 && p.changes%p.PageSize == 0 {
+		if !p.prompt() {
+			p.quit = true
+			return
+		}
+	}
+
+	p.changes++
+	fmt.Fprintf(p.Out, format, args...)
+}
+
+// Reset clears the change count so the pager can be reused for the next diff
+func (p *Pager) Reset() {
+	p.changes = 0
+	p.quit = false
+}
+
+// prompt asks the user whether to continue; it returns false on "q" or EOF
+func (p *Pager) prompt() bool {
+	if p.reader == nil {
+		p.reader = bufio.NewReader(p.In)
+	}
+
+	fmt.Fprint(p.Out, "-- Press Enter for more, q to quit --")
+	line, err := p.reader.ReadString('\n')
+	fmt.Fprint(p.Out, "\033[1A\r\033[K") // Erase the prompt line
+	if err != nil {
+		return false
+	}
+	return !strings.EqualFold(strings.TrimSpace(line), "q")
+}
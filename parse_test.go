@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseFixtures lists each testdata fixture with what ParseNmapOutput must
+// find in it; see testdata/README.md
+var parseFixtures = []struct {
+	name  string
+	ports map[string][]string
+}{
+	{"tcp_default", map[string][]string{
+		"192.168.1.10": {"22/tcp [open] (ssh)", "80/tcp [open] (http)", "443/tcp [open] (https)", "8080/tcp [open] (http-proxy)"},
+	}},
+	{"syn_scan", map[string][]string{
+		"192.168.1.20": {"22/tcp [open] (ssh)", "25/tcp [filtered] (smtp)", "139/tcp [open] (netbios-ssn)", "445/tcp [open] (microsoft-ds)", "3389/tcp [open] (ms-wbt-server)"},
+	}},
+	{"udp_scan", map[string][]string{
+		"192.168.1.1": {"53/udp [open] (domain)", "67/udp [open|filtered] (dhcps)", "123/udp [open] (ntp)", "161/udp [open|filtered] (snmp)"},
+	}},
+	{"version_detection", map[string][]string{
+		"192.168.1.30": {"22/tcp [open] (ssh)", "443/tcp [open] (ssl/http)", "5432/tcp [open] (postgresql)"},
+	}},
+	{"os_detection", map[string][]string{
+		"192.168.1.40": {"22/tcp [open] (ssh)", "80/tcp [open] (http)"},
+	}},
+	{"nse_scripts", map[string][]string{
+		"192.168.1.50": {"80/tcp [open] (http)", "443/tcp [open] (https)"},
+	}},
+	{"ipv6", map[string][]string{
+		"2001:db8::10":      {"22/tcp [open] (ssh)", "443/tcp [open] (https)"},
+		"2001:db8:0:1::443": {"443/tcp [open] (https)"},
+	}},
+	{"hostnames", map[string][]string{
+		"45.33.32.156": {"22/tcp [open] (ssh)", "80/tcp [open] (http)", "9929/tcp [open] (nping-echo)", "31337/tcp [open] (Elite)"},
+	}},
+	{"multiple_hosts", map[string][]string{
+		"10.0.0.1":  {"53/tcp [open] (domain)", "80/tcp [open] (http)", "443/tcp [open] (https)"},
+		"10.0.0.12": {"3306/tcp [open] (mysql)"},
+		"10.0.0.40": {"80/tcp [open] (http)", "515/tcp [open] (printer)", "9100/tcp [open] (jetdirect)"},
+	}},
+	{"no_open_ports", map[string][]string{
+		"192.168.1.99": {},
+	}},
+}
+
+func TestParseNmapOutputFixtures(t *testing.T) {
+	for _, tt := range parseFixtures {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.name+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ParseNmapOutput(string(data))
+			for ip, ports := range got {
+				if ports == nil {
+					got[ip] = []string{}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.ports) {
+				t.Errorf("got %v\nwant %v", got, tt.ports)
+			}
+		})
+	}
+}
+
+// TestReplayFixtures checks the full parse, extras such as MAC addresses and
+// OS matches included, against each fixture's expected JSON
+func TestReplayFixtures(t *testing.T) {
+	for _, tt := range parseFixtures {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReplayRawOutput(filepath.Join("testdata", tt.name+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("testdata", tt.name+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotJSON, err := json.MarshalIndent(got, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(gotJSON)+"\n" != string(want) {
+				t.Errorf("parse differs from %s.json; if intended, regenerate it as testdata/README.md describes\ngot:\n%s", tt.name, gotJSON)
+			}
+		})
+	}
+}
+
+func TestParseFixtureDetails(t *testing.T) {
+	load := func(name string) ScanResult {
+		t.Helper()
+		scan, err := ReplayRawOutput(filepath.Join("testdata", name+".txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return scan
+	}
+
+	if host := load("os_detection").Hosts["192.168.1.40"]; host.OS != "Linux 5.0 - 5.14" || host.MAC != "52:54:00:AB:CD:EF" {
+		t.Errorf("os_detection: OS %q, MAC %q", host.OS, host.MAC)
+	}
+	if host := load("syn_scan").Hosts["192.168.1.20"]; !host.SMBExposed || !host.RDPExposed || host.Vendor != "Dell" {
+		t.Errorf("syn_scan: %+v", host)
+	}
+	if scan := load("tcp_default"); scan.ScannerVersion != "7.94" || scan.Version != currentScanVersion {
+		t.Errorf("tcp_default: scanner %q, schema version %d", scan.ScannerVersion, scan.Version)
+	}
+}
+
+func TestParseNmapOutputSortsPorts(t *testing.T) {
+	output := `Nmap scan report for 10.0.0.1
+Host is up (0.00050s latency).
+PORT     STATE    SERVICE
+443/tcp  open     https
+53/udp   open     domain
+53/tcp   open     domain
+22/tcp   filtered ssh
+`
+	want := []string{"22/tcp [filtered] (ssh)", "53/tcp [open] (domain)", "53/udp [open] (domain)", "443/tcp [open] (https)"}
+	if got := ParseNmapOutput(output)["10.0.0.1"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("text output: got %v, want %v", got, want)
+	}
+
+	grepable := "Host: 10.0.0.1 ()\tPorts: 443/open/tcp//https///, 53/open/udp//domain///, 53/open/tcp//domain///, 22/filtered/tcp//ssh///\n"
+	got, err := ParseNmapGrepable(grepable)
+	if err != nil || !reflect.DeepEqual(got["10.0.0.1"], want) {
+		t.Errorf("grepable output: got %v, %v; want %v", got["10.0.0.1"], err, want)
+	}
+}
+
+// benchmarkNmapOutput builds normal nmap output of at least n lines: hosts
+// with open ports and NSE script output between them
+func benchmarkNmapOutput(n int) string {
+	var b strings.Builder
+	b.WriteString("Starting Nmap 7.94 ( https://nmap.org ) at 2025-02-03 10:40 GMT\n")
+	lines := 1
+	for host := 0; lines < n; host++ {
+		fmt.Fprintf(&b, "Nmap scan report for 10.%d.%d.%d\n", host>>16&255, host>>8&255, host&255)
+		b.WriteString("Host is up (0.00058s latency).\nNot shown: 990 closed tcp ports (conn-refused)\nPORT     STATE SERVICE\n")
+		for _, port := range []string{"22/tcp   open  ssh", "80/tcp   open  http", "443/tcp  open  https", "8080/tcp open  http-proxy"} {
+			b.WriteString(port + "\n")
+			b.WriteString("| http-methods: \n|_  Supported Methods: GET HEAD POST OPTIONS\n")
+		}
+		b.WriteString("\n")
+		lines += 17
+	}
+	b.WriteString("Nmap done: 1 IP address (1 host up) scanned in 4.06 seconds\n")
+	return b.String()
+}
+
+func BenchmarkParseNmapOutput(b *testing.B) {
+	b.Run("10000lines", func(b *testing.B) {
+		output := benchmarkNmapOutput(10000)
+		b.SetBytes(int64(len(output)))
+		b.ResetTimer()
+		for range b.N {
+			ParseNmapOutput(output)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ScanPolicy describes a scan in terms of an organisational standard rather
+// than nmap flags. Fields left empty take the defaults of Level.
+type ScanPolicy struct {
+	Level   string   `yaml:"level"`   // quick, standard or comprehensive
+	Ports   string   `yaml:"ports"`   // nmap -p value, "-" for all ports
+	OS      bool     `yaml:"os"`      // Enable OS detection
+	Scripts []string `yaml:"scripts"` // NSE scripts or categories
+	Timing  int      `yaml:"timing"`  // nmap -T template (0 = level default)
+}
+
+// policyLevels are the defaults for each policy level
+var policyLevels = map[string]ScanPolicy{
+	"quick":         {Level: "quick", Timing: 4},
+	"standard":      {Level: "standard", Timing: 3},
+	"comprehensive": {Level: "comprehensive", Ports: "-", Scripts: []string{"default", "vuln"}, Timing: 4},
+}
+
+// GenerateNmapCommand builds the nmap command for a policy, e.g. a
+// comprehensive policy gives "nmap -A -p- -T4 --script=default,vuln".
+// An unknown level is treated as standard.
+func GenerateNmapCommand(policy ScanPolicy) string {
+	defaults, ok := policyLevels[policy.Level]
+	if !ok {
+		defaults = policyLevels["standard"]
+	}
+	if policy.Ports == "" {
+		policy.Ports = defaults.Ports
+	}
+	if policy.Timing == 0 {
+		policy.Timing = defaults.Timing
+	}
+	if len(policy.Scripts) == 0 {
+		policy.Scripts = defaults.Scripts
+	}
+
+	args := []string{"nmap"}
+	switch defaults.Level {
+	case "comprehensive":
+		args = append(args, "-A") // Includes OS detection
+	case "standard":
+		args = append(args, "-sV")
+	}
+	if policy.OS && defaults.Level != "comprehensive" {
+		args = append(args, "-O")
+	}
+
+	switch {
+	case policy.Ports == "-":
+		args = append(args, "-p-")
+	case policy.Ports != "":
+		args = append(args, "-p", policy.Ports)
+	case defaults.Level == "quick":
+		args = append(args, "-F") // Top 100 ports
+	}
+
+	args = append(args, fmt.Sprintf("-T%d", policy.Timing))
+	if len(policy.Scripts) > 0 {
+		args = append(args, "--script="+strings.Join(policy.Scripts, ","))
+	}
+	return strings.Join(args, " ")
+}
+
+// LookupPolicy finds a policy by name: one defined under policies: in the
+// config file, or one of the built-in levels
+func LookupPolicy(cfg Config, name string) (ScanPolicy, error) {
+	if policy, ok := cfg.Policies[name]; ok {
+		if _, known := policyLevels[policy.Level]; policy.Level != "" && !known {
+			return ScanPolicy{}, fmt.Errorf("policy %q: unknown level %q (choose quick, standard or comprehensive)", name, policy.Level)
+		}
+		return policy, nil
+	}
+	if policy, ok := policyLevels[name]; ok {
+		return policy, nil
+	}
+	return ScanPolicy{}, fmt.Errorf("unknown policy %q (choose quick, standard, comprehensive or one from the config file)", name)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultPortDBData is the curated part of the built-in port significance
+// database: about 75 ports that matter for exposure, well-known ones such as
+// 22 and 445 plus commonly abused registered ones such as 3389 and 6379. It
+// is laid over entries generated for every well-known port in the embedded
+// IANA registry (see wellKnownPortDB). Ports missing from both score
+// unknownPortWeight; -port-db adds or overrides entries.
+//
+//go:embed portdb.json
+var defaultPortDBData []byte
+
+// PortSignificance describes how security-relevant a port is
+type PortSignificance struct {
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	Sensitivity string `json:"sensitivity"` // "high", "medium" or "low"
+	Description string `json:"description"`
+}
+
+// PortDB maps port numbers (e.g. "22") to their significance
+type PortDB map[string]PortSignificance
+
+// portDB is the active database, replaced at startup when -port-db is given
+var portDB = mustLoadDefaultPortDB()
+
+// sensitivityWeights are the per-port scores used by ScoreDiff
+var sensitivityWeights = map[string]int{
+	"high":   10,
+	"medium": 5,
+	"low":    1,
+}
+
+// unknownPortWeight is used for ports missing from the database
+const unknownPortWeight = 2
+
+func mustLoadDefaultPortDB() PortDB {
+	var curated PortDB
+	if err := json.Unmarshal(defaultPortDBData, &curated); err != nil {
+		panic("invalid embedded port database: " + err.Error())
+	}
+	db := wellKnownPortDB()
+	for port, sig := range curated {
+		db[port] = sig
+	}
+	return db
+}
+
+// wellKnownPortDB returns a low sensitivity entry for each port from 0 to
+// wellKnownPortMax registered in the embedded IANA CSV, named after the first
+// service registered for it (TCP, then UDP, then SCTP: the protocols nmap scans)
+func wellKnownPortDB() PortDB {
+	db := make(PortDB)
+	for port := 0; port <= wellKnownPortMax; port++ {
+		for _, proto := range []string{"tcp", "udp", "sctp"} {
+			key := strconv.Itoa(port) + "/" + proto
+			names := ianaServices[key]
+			if len(names) == 0 {
+				continue
+			}
+			db[strconv.Itoa(port)] = PortSignificance{
+				Name:        names[0],
+				Category:    "well-known",
+				Sensitivity: "low",
+				Description: cmp.Or(ianaDescriptions[key], "IANA registered service "+names[0]),
+			}
+			break
+		}
+	}
+	return db
+}
+
+// LoadPortDB returns the default database extended (or overridden) by the
+// entries in the JSON file at path. An empty path returns the default.
+func LoadPortDB(path string) (PortDB, error) {
+	db := mustLoadDefaultPortDB()
+	if path == "" {
+		return db, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var custom PortDB
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, err
+	}
+
+	for port, sig := range custom {
+		db[port] = sig
+	}
+	return db, nil
+}
+
+// Lookup returns the significance of a port entry such as "22/tcp [open] (ssh)"
+func (db PortDB) Lookup(entry string) (PortSignificance, bool) {
+	port, _, _, _ := splitPortEntry(entry)
+	sig, ok := db[port]
+	return sig, ok
+}
+
+// IsSensitive reports whether a port entry is rated high sensitivity
+func (db PortDB) IsSensitive(entry string) bool {
+	sig, ok := db.Lookup(entry)
+	return ok && sig.Sensitivity == "high"
+}
+
+// ScoreDiff rates the significance of a set of port changes. Only ports in an
+// open state contribute; closed and filtered ports are not an exposure.
+func ScoreDiff(added, removed []string) int {
+	score := 0
+	for _, entry := range append(append([]string{}, added...), removed...) {
+		_, _, state, _ := splitPortEntry(entry)
+		if !strings.HasPrefix(state, "open") {
+			continue
+		}
+
+		sig, ok := portDB.Lookup(entry)
+		if !ok {
+			score += unknownPortWeight
+			continue
+		}
+		score += sensitivityWeights[sig.Sensitivity]
+	}
+	return score
+}
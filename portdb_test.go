@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDefaultPortDB(t *testing.T) {
+	db := mustLoadDefaultPortDB()
+	for port, sig := range db {
+		if _, ok := sensitivityWeights[sig.Sensitivity]; !ok || sig.Name == "" || sig.Category == "" {
+			t.Errorf("port %s: incomplete entry %+v", port, sig)
+		}
+	}
+	// The curated subset must keep the ports ScoreDiff is mostly about
+	for _, port := range []string{"22", "23", "445", "3389", "6379"} {
+		if db[port].Sensitivity != "high" && db[port].Sensitivity != "medium" {
+			t.Errorf("port %s has sensitivity %q", port, db[port].Sensitivity)
+		}
+	}
+	// Every well-known port in the IANA registry has an entry, curated or generated
+	for key, names := range ianaServices {
+		port, proto, _ := strings.Cut(key, "/")
+		if n, _ := strconv.Atoi(port); n <= wellKnownPortMax && proto != "ddp" {
+			if _, ok := db[port]; !ok {
+				t.Errorf("well-known port %s (%s) missing from the database", port, names[0])
+			}
+		}
+	}
+	if sig := db["104"]; sig.Name != "acr-nema" || sig.Sensitivity != "low" || sig.Description == "" {
+		t.Errorf("port 104 generated as %+v, want a low sensitivity acr-nema entry", sig)
+	}
+	if got := ScoreDiff([]string{"8081/tcp [open] (blackice-icecap)"}, nil); got != unknownPortWeight {
+		t.Errorf("unlisted port scored %d, want unknownPortWeight %d", got, unknownPortWeight)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Preset is a saved scan command and target, stored under presets: in the config file
+type Preset struct {
+	Name    string `yaml:"-"`
+	Command string `yaml:"command"`
+	Target  string `yaml:"target,omitempty"`
+}
+
+// presetConfigPath is the config file presets are read from and written to
+func presetConfigPath(args []string) string {
+	if path := configPathFromArgs(args); path != "" {
+		return path
+	}
+	return defaultConfigFile
+}
+
+// ListPresets returns all saved presets, sorted by name
+func ListPresets() ([]Preset, error) {
+	cfg, err := LoadConfig(configPathFromArgs(os.Args[1:]), configPassphraseFromArgs(os.Args[1:]))
+	if err != nil {
+		return nil, err
+	}
+	return sortedPresets(cfg.Presets), nil
+}
+
+func sortedPresets(presets map[string]Preset) []Preset {
+	list := make([]Preset, 0, len(presets))
+	for name, p := range presets {
+		p.Name = name
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// SavePreset adds or replaces a preset in the YAML config file at path. The
+// file is edited as a YAML node tree so comments and other settings survive.
+func SavePreset(path string, preset Preset) error {
+	if preset.Name == "" {
+		return errors.New("preset name cannot be empty")
+	}
+	if strings.HasSuffix(path, encryptedConfigSuffix) {
+		return fmt.Errorf("%s is encrypted: save the preset to the plain config and run encrypt-config again", path)
+	}
+	if err := ValidateNmapCommand(preset.Command); err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("parsing %s: %v", path, err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: top level is not a mapping", path)
+	}
+	presets := mappingValue(root, "presets")
+
+	var value yaml.Node
+	if err := value.Encode(preset); err != nil {
+		return err
+	}
+	setMappingValue(presets, preset.Name, &value)
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out.Bytes(), 0644)
+}
+
+// mappingValue returns the mapping stored under key, creating it if needed
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			if mapping.Content[i+1].Kind != yaml.MappingNode {
+				mapping.Content[i+1] = &yaml.Node{Kind: yaml.MappingNode}
+			}
+			return mapping.Content[i+1]
+		}
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	setMappingValue(mapping, key, value)
+	return value
+}
+
+// setMappingValue sets key to value in a YAML mapping node
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// runSavePreset implements "porthunter save-preset -name NAME -c CMD [-t TARGET]"
+func runSavePreset(args []string) error {
+	fs := flag.NewFlagSet("save-preset", flag.ContinueOnError)
+	var preset Preset
+	var configPath string
+	fs.StringVar(&preset.Name, "name", "", "Preset name")
+	fs.StringVar(&preset.Command, "c", "", "Full scan command")
+	fs.StringVar(&preset.Target, "t", "", "Default target (can be overridden with -t when running)")
+	fs.StringVar(&configPath, "config", presetConfigPath(args), "YAML config file to save the preset in")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := SavePreset(configPath, preset); err != nil {
+		return err
+	}
+	fmt.Printf("Preset %q saved to %s\n", preset.Name, configPath)
+	return nil
+}
+
+// runListPresets implements "porthunter list-presets"
+func runListPresets() error {
+	presets, err := ListPresets()
+	if err != nil {
+		return err
+	}
+	if len(presets) == 0 {
+		fmt.Println("No presets saved.")
+		return nil
+	}
+	for _, p := range presets {
+		fmt.Printf("%-20s %s", p.Name, p.Command)
+		if p.Target != "" {
+			fmt.Printf("  (target: %s)", p.Target)
+		}
+		fmt.Println()
+	}
+	return nil
+}
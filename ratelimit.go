@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Packet rates accepted by -rate, -min-rate, -max-rate and nmap's
+// --min-rate/--max-rate, in packets per second
+const (
+	minScanRate = 1
+	maxScanRate = 10000
+)
+
+// ValidateScanRates checks the -rate, -min-rate and -max-rate flags. 0 means
+// the flag was not given. -rate is another name for -max-rate, so both may
+// only be given with the same value.
+func ValidateScanRates(cfg Config) error {
+	for _, r := range []struct {
+		flag  string
+		value int
+	}{{"rate", cfg.Rate}, {"min-rate", cfg.MinRate}, {"max-rate", cfg.MaxRate}} {
+		if r.value != 0 && (r.value < minScanRate || r.value > maxScanRate) {
+			return fmt.Errorf("-%s %d is out of range (%d-%d packets per second)", r.flag, r.value, minScanRate, maxScanRate)
+		}
+	}
+	if cfg.Rate != 0 && cfg.MaxRate != 0 && cfg.Rate != cfg.MaxRate {
+		return fmt.Errorf("-rate %d and -max-rate %d disagree; -rate is the same as -max-rate", cfg.Rate, cfg.MaxRate)
+	}
+	return nil
+}
+
+// WithRateLimits appends nmap's --min-rate and --max-rate options for the
+// rate flags in cfg to command. nmap uses the last value given, so these
+// override any rates already in the command.
+func WithRateLimits(command string, cfg Config) string {
+	maxRate := cfg.MaxRate
+	if maxRate == 0 {
+		maxRate = cfg.Rate
+	}
+	if cfg.MinRate != 0 {
+		command += fmt.Sprintf(" --min-rate %d", cfg.MinRate)
+	}
+	if maxRate != 0 {
+		command += fmt.Sprintf(" --max-rate %d", maxRate)
+	}
+	return command
+}
+
+// validateRateArgs checks the --min-rate and --max-rate options among nmap's
+// args, in either the "--max-rate N" or "--max-rate=N" form. As -T5 sets its
+// own aggressive timing, combining it with --max-rate draws a warning.
+func validateRateArgs(args []string) error {
+	rates := map[string]int{}
+	timing5 := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-T5" || arg == "-Tinsane" {
+			timing5 = true
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		if name != "--min-rate" && name != "--max-rate" {
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return fmt.Errorf("%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < minScanRate || n > maxScanRate {
+			return fmt.Errorf("%s %q must be a whole number from %d to %d", name, value, minScanRate, maxScanRate)
+		}
+		rates[name] = n // nmap keeps the last value given
+	}
+
+	minRate, hasMin := rates["--min-rate"]
+	maxRate, hasMax := rates["--max-rate"]
+	if hasMin && hasMax && minRate > maxRate {
+		return fmt.Errorf("--min-rate %d is above --max-rate %d", minRate, maxRate)
+	}
+	if hasMax && timing5 {
+		logger.Warn("--max-rate %d conflicts with -T5, which sets its own timing limits; the rate limit may not behave as expected", maxRate)
+	}
+	return nil
+}
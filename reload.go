@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/robfig/cron/v3"
+)
+
+// loadRuntimeConfig builds the configuration from the config file, the
+// preset (if any) and the command line flags in args, which are parsed into fs
+func loadRuntimeConfig(args []string, presetName string, fs *flag.FlagSet) (Config, error) {
+	cfg, err := LoadConfig(configPathFromArgs(args), configPassphraseFromArgs(args))
+	if err != nil {
+		return cfg, fmt.Errorf("loading config: %v", err)
+	}
+
+	// A preset supplies the command and target; flags given at run time still win
+	if presetName != "" {
+		preset, ok := cfg.Presets[presetName]
+		if !ok {
+			return cfg, fmt.Errorf("unknown preset %q", presetName)
+		}
+		cfg.Command, cfg.Target = preset.Command, preset.Target
+	}
+
+	RegisterFlags(fs, &cfg)
+	return cfg, fs.Parse(args)
+}
+
+// applyConfig validates cfg and sets the package-level settings taken from
+// it. Nothing is changed if any of it is invalid.
+func applyConfig(cfg Config) error {
+	if cfg.Format != "text" && cfg.Format != "json-patch" {
+		return fmt.Errorf("unknown -format %q (choose text or json-patch)", cfg.Format)
+	}
+	if err := ValidateScanRates(cfg); err != nil {
+		return err
+	}
+	if err := ValidateStorageFormat(cfg.StorageFormat); err != nil {
+		return err
+	}
+	if err := ValidateFilenamePattern(cfg.FilenamePattern); err != nil {
+		return err
+	}
+	if err := ValidateAnsibleGroupBy(cfg.AnsibleGroupBy); err != nil {
+		return err
+	}
+	if err := ValidateChangeWindows(cfg.ChangeWindows); err != nil {
+		return err
+	}
+	if cfg.Digest != "" {
+		if _, err := cron.ParseStandard(cfg.Digest); err != nil {
+			return fmt.Errorf("invalid -digest schedule %q: %v", cfg.Digest, err)
+		}
+	}
+	p, err := LookupPalette(cfg.ColourPalette, cfg.Colours)
+	if err != nil {
+		return err
+	}
+	db, err := LoadPortDB(cfg.PortDBPath)
+	if err != nil {
+		return fmt.Errorf("loading port database: %v", err)
+	}
+	excludes, err := loadExclusions(cfg)
+	if err != nil {
+		return err
+	}
+
+	logger = NewLogger(os.Stdout, cfg.Verbosity)
+	verbosity = cfg.Verbosity
+	SetColourOverride(cfg.Colour, cfg.NoColour)
+	aliases := maps.Clone(defaultServiceAliases)
+	for alias, name := range cfg.ServiceAliases {
+		aliases[strings.ToLower(alias)] = strings.ToLower(name)
+	}
+	dnsServer = cfg.DNSServer
+	historyDepth = cfg.HistoryDepth
+	loadRetries = cfg.LoadRetries
+	filenamePattern = cfg.FilenamePattern
+	scanProfile = ""
+	if cfg.Command == "" && cfg.Policy == "" {
+		scanProfile = cfg.Profile
+	}
+	scanFolder = cfg.DataDir
+	palette = p
+	portDB = db
+	allowLoopback = cfg.AllowLoopback
+	excludeList = excludes
+	scanStore = storeFor(cfg)
+	serviceAliases = aliases
+	return nil
+}
+
+// configReloader re-reads the configuration when reloadSignal arrives. The new
+// configuration is handed over between scans, so a running scan is unaffected.
+type configReloader struct {
+	args       []string
+	presetName string
+
+	mu      sync.Mutex
+	pending *Config
+}
+
+// Watch handles reload signals until ctx is cancelled
+func (r *configReloader) Watch(ctx context.Context) {
+	if reloadSignal == nil {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, reloadSignal)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+		}
+
+		fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		cfg, err := loadRuntimeConfig(r.args, r.presetName, fs)
+		if err == nil {
+			_, err = ResolveCommand(cfg)
+		}
+		if err != nil {
+			logger.Error("reloading configuration, keeping the current one: %v", err)
+			continue
+		}
+		r.mu.Lock()
+		r.pending = &cfg
+		r.mu.Unlock()
+		logger.Info("Configuration reload requested, applying before the next scan")
+	}
+}
+
+// Next returns the configuration for the next scan: a newly reloaded one if
+// there is one, otherwise current. ok reports whether it was reloaded.
+func (r *configReloader) Next(current Config) (cfg Config, ok bool) {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+	if pending == nil {
+		return current, false
+	}
+
+	if err := applyConfig(*pending); err != nil {
+		logger.Error("reloading configuration, keeping the current one: %v", err)
+		return current, false
+	}
+	command, _ := ResolveCommand(*pending)
+	fmt.Printf("Configuration reloaded: command %q, target %q, interval %s-%s\n",
+		command, pending.Target, pending.Interval, pending.MaxInterval)
+	return *pending, true
+}
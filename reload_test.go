@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestApplyConfigServiceAliases(t *testing.T) {
+	savedAliases, savedFolder, savedStore, savedLogger := serviceAliases, scanFolder, scanStore, logger
+	t.Cleanup(func() {
+		serviceAliases, scanFolder, scanStore, logger = savedAliases, savedFolder, savedStore, savedLogger
+	})
+
+	apply := func(aliases map[string]string) {
+		t.Helper()
+		cfg := DefaultConfig()
+		cfg.DataDir = t.TempDir()
+		cfg.ServiceAliases = aliases
+		if err := applyConfig(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	apply(map[string]string{"HTTP-Mgmt": "HTTP", "www": "web"})
+	for service, want := range map[string]string{"http-mgmt": "http", "www": "web", "ssl/http": "https"} {
+		if got := NormaliseServiceName(service); got != want {
+			t.Errorf("with aliases: %s normalised to %q, want %q", service, got, want)
+		}
+	}
+
+	// A reload without the aliases goes back to the built-in ones
+	apply(nil)
+	for service, want := range map[string]string{"http-mgmt": "http-mgmt", "www": "http"} {
+		if got := NormaliseServiceName(service); got != want {
+			t.Errorf("after reload: %s normalised to %q, want %q", service, got, want)
+		}
+	}
+	if defaultServiceAliases["www"] != "http" || len(defaultServiceAliases) != len(savedAliases) {
+		t.Error("applyConfig modified the built-in aliases")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import "fmt"
+
+// Severities of an ExposureAlert
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+)
+
+// remoteService is a Windows remote-management service worth its own alert
+type remoteService struct {
+	Name     string
+	Severity string
+}
+
+// remoteServices maps TCP ports to the remote-management services flagged on
+// HostResult. WinRM gives an attacker with any valid credential a remote shell.
+var remoteServices = map[int]remoteService{
+	135:  {"MS-RPC", SeverityMedium},
+	445:  {"SMB", SeverityMedium},
+	3389: {"RDP", SeverityMedium},
+	5985: {"WinRM (PowerShell remoting, HTTP)", SeverityHigh},
+	5986: {"WinRM (PowerShell remoting, HTTPS)", SeverityHigh},
+}
+
+// ExposureAlert is a remote-management service that opened since the last scan
+type ExposureAlert struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"` // e.g. "5985/tcp"
+	Service  string `json:"service"`
+	Severity string `json:"severity"`
+}
+
+func (a ExposureAlert) String() string {
+	return fmt.Sprintf("%s exposed on %s (%s)", a.Service, a.Host, a.Port)
+}
+
+// setExposureFlags records which remote-management services are open on the host
+func (h *HostResult) setExposureFlags() {
+	for _, p := range h.Ports {
+		if p.Protocol != "tcp" || p.State != "open" {
+			continue
+		}
+		switch p.Port {
+		case 5985, 5986:
+			h.RemotingExposed = true
+		case 135:
+			h.RPCExposed = true
+		case 445:
+			h.SMBExposed = true
+		case 3389:
+			h.RDPExposed = true
+		}
+	}
+}
+
+// DiffExposures returns an alert for every newly-open remote-management port in the diff
+func DiffExposures(hosts []HostDiff) []ExposureAlert {
+	var alerts []ExposureAlert
+	for _, h := range hosts {
+		for _, entry := range h.Added {
+			p := ParsePortEntry(entry)
+			svc, ok := remoteServices[p.Port]
+			if !ok || p.Protocol != "tcp" || p.State != "open" {
+				continue
+			}
+			alerts = append(alerts, ExposureAlert{
+				Host:     h.IP,
+				Port:     fmt.Sprintf("%d/%s", p.Port, p.Protocol),
+				Service:  svc.Name,
+				Severity: svc.Severity,
+			})
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ReplayRawOutput re-parses archived raw nmap output with the current parsers.
+// The scan time is taken from the archive's <datetime>_raw.txt filename.
+func ReplayRawOutput(path string) (ScanResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ScanResult{}, err
+	}
+
+	stamp := strings.TrimSuffix(filepath.Base(path), rawArchiveSuffix)
+	datetime := ""
+	if t, err := time.ParseInLocation("20060102T150405", stamp, time.Local); err == nil {
+		datetime = t.Format(time.RFC3339)
+	}
+
+	output := string(data)
+	scan := ScanResult{Version: 1, DateTime: datetime, ScannerVersion: nmapOutputVersion(output)}
+	if scan.Ports, scan.Hosts, err = ParseAnyNmapOutput(output, scan.ScannerVersion); err != nil {
+		return ScanResult{}, err
+	}
+	if err := upgradeScan(&scan); err != nil {
+		return ScanResult{}, err
+	}
+	if !isXMLOutput(output) {
+		applyMACAddresses(&scan, output)
+		applyTraceroutes(&scan, output)
+		applyOSDetails(&scan, output)
+	}
+	return scan, nil
+}
+
+// isXMLOutput reports whether raw output looks like nmap XML rather than text
+func isXMLOutput(output string) bool {
+	head := strings.TrimSpace(output)
+	return strings.HasPrefix(head, "<?xml") || strings.HasPrefix(head, "<nmaprun")
+}
+
+// UpdateStoredScan overwrites whichever stored scan was taken at the same time
+// as the replayed result, returning the file that was updated
+func UpdateStoredScan(result ScanResult) (string, error) {
+	for _, path := range []string{scanFile(scanFolder), backupScanFile(scanFolder), scanFileProto(scanFolder), backupScanFileProto(scanFolder)} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		stored, err := decodeScan(data, path)
+		if err != nil {
+			continue
+		}
+		if fileTimestamp(stored.DateTime) != fileTimestamp(result.DateTime) {
+			continue
+		}
+
+		result.DateTime = stored.DateTime // Keep the original timestamp string exactly
+		out, err := encodeScan(result, path)
+		if err != nil {
+			return "", err
+		}
+		return path, os.WriteFile(path, out, 0644)
+	}
+	return "", errors.New("no stored scan matches the raw file's timestamp")
+}
+
+// runReplay implements "porthunter replay [-update-stored] <raw file>"
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	update := fs.Bool("update-stored", false, "Overwrite the matching stored JSON scan with the re-parsed result")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: porthunter replay [-update-stored] scan_data/<datetime>_raw.txt")
+	}
+
+	result, err := ReplayRawOutput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+
+	if *update {
+		path, err := UpdateStoredScan(result)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Updated", path)
+	}
+	return nil
+}
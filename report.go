@@ -0,0 +1,121 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+// reportTemplateData is the HTML report template
+//
+//go:embed report.html
+var reportTemplateData string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportTemplateData))
+
+// reportHost is a host row group in the HTML report
+type reportHost struct {
+	IP    string
+	Ports []PortEntry
+}
+
+// GenerateHTMLReport writes a self-contained HTML report of the scan and its
+// diff against the previous scan (an empty DiffReport if there was none).
+// trend, if given, is the open port count of recent scans, oldest first.
+func GenerateHTMLReport(scan ScanResult, diff DiffReport, trend []int, path string) error {
+	added, removed := diff.Totals()
+	data := struct {
+		Scan           ScanResult
+		Diff           DiffReport
+		Added, Removed int
+		Hosts          []reportHost
+		Trend          []int
+		Sparkline      string
+		Notes          map[string]string
+	}{scan, diff, added, removed, reportHosts(scan), trend, Sparkline(trend), loadAnnotationsForDisplay()}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := reportTemplate.Execute(f, data); err != nil {
+		return fmt.Errorf("rendering HTML report: %v", err)
+	}
+	return f.Close()
+}
+
+// reportHosts returns the scan's hosts sorted by IP with structured ports
+func reportHosts(scan ScanResult) []reportHost {
+	hosts := make([]reportHost, 0, len(scan.Ports))
+	for ip, ports := range scan.Ports {
+		hosts = append(hosts, reportHost{IP: ip, Ports: HostFromPortStrings(ip, ports).Ports})
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].IP < hosts[j].IP })
+	return hosts
+}
+
+// OpenBrowser opens a file in the default browser using the platform's opener
+func OpenBrowser(path string) error {
+	var name string
+	var args []string
+	switch runtime.GOOS {
+	case "darwin":
+		name, args = "open", []string{path}
+	case "windows":
+		name, args = "cmd", []string{"/c", "start", "", path} // start is a cmd.exe builtin
+	default:
+		name, args = "xdg-open", []string{path}
+	}
+
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("cannot open browser: %s not found", name)
+	}
+	return exec.Command(name, args...).Start()
+}
+
+// isHeadless reports whether there is no user session to open a browser in
+func isHeadless() bool {
+	if !IsTerminal(os.Stdout) {
+		return true
+	}
+	if runtime.GOOS == "linux" || runtime.GOOS == "freebsd" || runtime.GOOS == "openbsd" {
+		return os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == ""
+	}
+	return false
+}
+
+// writeHTMLReport generates the configured HTML report and opens it if requested
+func writeHTMLReport(cfg Config, scan ScanResult, diff DiffReport) error {
+	if cfg.HTMLReport == "" {
+		if cfg.OpenReport {
+			return errors.New("-open requires -html")
+		}
+		return nil
+	}
+
+	if err := GenerateHTMLReport(scan, diff, nil, cfg.HTMLReport); err != nil {
+		return err
+	}
+	fmt.Println("HTML report written to", cfg.HTMLReport)
+
+	if cfg.OpenReport && !isHeadless() {
+		if err := OpenBrowser(cfg.HTMLReport); err != nil {
+			logger.Warn("%v", err)
+		}
+	}
+	return nil
+}
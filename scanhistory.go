@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// historyFolder keeps a copy of every scan saved in the data folder dir, named by filenamePattern
+func historyFolder(dir string) string { return filepath.Join(dir, "history") }
+
+// historyDepth is how many scans are kept in historyFolder (0 = none); set from -history-depth
+var historyDepth = 100
+
+// filenamePattern names the scans in historyFolder; set from -filename-pattern.
+// The extension is replaced with the storage format's.
+var filenamePattern = "scan_{datetime}.json"
+
+// scanProfile is the profile the scan command came from, for {profile}; set from the config
+var scanProfile = ""
+
+var (
+	filenameTokenRe = regexp.MustCompile(`\{[^}]*\}`)
+	// A fileTimestamp, which orders history files whatever pattern named them
+	historyTimestampRe = regexp.MustCompile(`\d{8}T\d{6}`)
+	// Characters kept from a profile name in a filename
+	filenameUnsafeRe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+)
+
+// ValidateFilenamePattern checks a -filename-pattern value: it must name a
+// file in the history folder and include {datetime}
+func ValidateFilenamePattern(pattern string) error {
+	if !strings.Contains(pattern, "{datetime}") {
+		return fmt.Errorf("-filename-pattern %q must include {datetime}", pattern)
+	}
+	if strings.ContainsAny(pattern, `/\`) {
+		return fmt.Errorf("-filename-pattern %q must be a file name, not a path", pattern)
+	}
+	for _, token := range filenameTokenRe.FindAllString(pattern, -1) {
+		switch token {
+		case "{datetime}", "{target_hash}", "{profile}", "{host_count}":
+		default:
+			return fmt.Errorf("unknown -filename-pattern token %s (choose {datetime}, {target_hash}, {profile} or {host_count})", token)
+		}
+	}
+	return nil
+}
+
+// historyFileName expands filenamePattern for scan. Tokens in wildcards are
+// replaced with "*" instead, for matching other scans' files.
+func historyFileName(scan ScanResult, ext string, wildcards ...string) string {
+	profile := filenameUnsafeRe.ReplaceAllString(scanProfile, "_")
+	if profile == "" {
+		profile = "custom"
+	}
+	values := map[string]string{
+		"{datetime}":    fileTimestamp(scan.DateTime),
+		"{target_hash}": targetHash(scan.Target),
+		"{profile}":     profile,
+		"{host_count}":  strconv.Itoa(len(scan.Ports)),
+	}
+	for _, token := range wildcards {
+		values[token] = "*"
+	}
+	name := filenameTokenRe.ReplaceAllStringFunc(filenamePattern, func(token string) string { return values[token] })
+	if old := filepath.Ext(name); old == ".json" || old == ".pb" {
+		name = strings.TrimSuffix(name, old)
+	}
+	return name + ext
+}
+
+// archiveScan writes data, an encoded scan, into the history folder in dir
+// and drops the oldest scans beyond historyDepth. Only scans whose names
+// differ from it just by time and host count are dropped, so scans of other
+// targets or profiles sharing the folder are kept.
+func archiveScan(dir string, scan ScanResult, data []byte, ext string) error {
+	if historyDepth <= 0 {
+		return nil
+	}
+	if err := os.MkdirAll(historyFolder(dir), 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(historyFolder(dir), historyFileName(scan, ext))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+
+	files, err := filepath.Glob(filepath.Join(historyFolder(dir), historyFileName(scan, ".*", "{datetime}", "{host_count}")))
+	if err != nil {
+		return err
+	}
+	sortHistoryFiles(files)
+	for len(files) > historyDepth {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+	return nil
+}
+
+// historyFiles lists the saved scans in the history folder in dir, oldest first
+func historyFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(historyFolder(dir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if name := e.Name(); strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".pb") {
+			files = append(files, filepath.Join(historyFolder(dir), name))
+		}
+	}
+	sortHistoryFiles(files)
+	return files, nil
+}
+
+// sortHistoryFiles sorts history files chronologically by the scan time in
+// their names, then by name
+func sortHistoryFiles(files []string) {
+	sort.SliceStable(files, func(i, j int) bool {
+		a := historyTimestampRe.FindString(filepath.Base(files[i]))
+		b := historyTimestampRe.FindString(filepath.Base(files[j]))
+		if a != b {
+			return a < b
+		}
+		return files[i] < files[j]
+	})
+}
+
+// LoadScanHistory returns every stored scan, oldest first. Before the history
+// folder existed only the last two scans were kept, so those are used instead.
+func LoadScanHistory() ([]ScanResult, error) {
+	keys, err := scanStore.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		var files []string
+		for _, path := range []string{backupScanFile(scanFolder), backupScanFileProto(scanFolder), scanFile(scanFolder), scanFileProto(scanFolder)} {
+			if _, err := os.Stat(path); err == nil {
+				files = append(files, path)
+			}
+		}
+		return loadScans(LocalStore{}, files)
+	}
+	return loadScans(scanStore, keys)
+}
+
+// ScanHistoryPage returns page (counting from 1) of the stored scans, newest
+// first if desc, along with the total number of scans. Only the scans on the
+// page are read from the store.
+func ScanHistoryPage(page, perPage int, desc bool) ([]ScanResult, int, error) {
+	keys, err := scanStore.List()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(keys) == 0 {
+		scans, err := LoadScanHistory()
+		return pageOf(scans, page, perPage, desc), len(scans), err
+	}
+
+	scans, err := loadScans(scanStore, pageOf(keys, page, perPage, desc))
+	if desc {
+		slices.Reverse(scans)
+	}
+	return scans, len(keys), err
+}
+
+// ScanHistoryAfter returns up to limit stored scans taken after the scan
+// time after, oldest first, along with the total number of scans. It is for
+// consumers reading the history incrementally.
+func ScanHistoryAfter(after string, limit int) ([]ScanResult, int, error) {
+	keys, err := scanStore.List()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(keys) == 0 {
+		scans, err := LoadScanHistory()
+		i := sort.Search(len(scans), func(i int) bool { return scanTime(scans[i].DateTime).After(scanTime(after)) })
+		return scans[i:min(len(scans), i+limit)], len(scans), err
+	}
+
+	cursor := fileTimestamp(after)
+	i := sort.Search(len(keys), func(i int) bool {
+		return historyTimestampRe.FindString(filepath.Base(keys[i])) > cursor
+	})
+	scans, err := loadScans(scanStore, keys[i:min(len(keys), i+limit)])
+	return scans, len(keys), err
+}
+
+// pageOf returns page (counting from 1) of items, reversing them first if desc
+func pageOf[T any](items []T, page, perPage int, desc bool) []T {
+	if desc {
+		items = slices.Clone(items)
+		slices.Reverse(items)
+	}
+	start := (page - 1) * perPage
+	if start >= len(items) {
+		return nil
+	}
+	return items[start:min(len(items), start+perPage)]
+}
+
+// loadScans reads the scans stored under keys, returning them oldest first
+func loadScans(store Store, keys []string) ([]ScanResult, error) {
+	scans := make([]ScanResult, 0, len(keys))
+	for _, key := range keys {
+		scan, err := store.Load(key)
+		if err != nil {
+			return nil, err
+		}
+		scans = append(scans, scan)
+	}
+	sort.SliceStable(scans, func(i, j int) bool { return scanTime(scans[i].DateTime).Before(scanTime(scans[j].DateTime)) })
+
+	// SaveScan can store the same scan as both current and backup
+	unique := scans[:0]
+	for _, scan := range scans {
+		if len(unique) == 0 || unique[len(unique)-1].DateTime != scan.DateTime {
+			unique = append(unique, scan)
+		}
+	}
+	return unique, nil
+}
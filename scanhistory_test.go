@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useFilenamePattern sets filenamePattern and scanProfile for the test
+func useFilenamePattern(t *testing.T, pattern, profile string) {
+	savedPattern, savedProfile := filenamePattern, scanProfile
+	filenamePattern, scanProfile = pattern, profile
+	t.Cleanup(func() { filenamePattern, scanProfile = savedPattern, savedProfile })
+}
+
+func TestValidateFilenamePattern(t *testing.T) {
+	for pattern, ok := range map[string]bool{
+		"scan_{datetime}.json":                            true,
+		"{target_hash}_{profile}_{datetime}_{host_count}": true,
+		"scan.json":                false, // No {datetime}
+		"{datetime}_{target}.json": false,
+		"tenant/{datetime}.json":   false,
+		`..\{datetime}.json`:       false,
+	} {
+		if err := ValidateFilenamePattern(pattern); (err == nil) != ok {
+			t.Errorf("ValidateFilenamePattern(%q) = %v, want ok %v", pattern, err, ok)
+		}
+	}
+}
+
+func TestHistoryFileName(t *testing.T) {
+	scan := ScanResult{DateTime: "2024-05-01T10:00:00Z", Target: "10.0.0.0/24", Ports: map[string][]string{"10.0.0.1": nil, "10.0.0.2": nil}}
+	hash := targetHash("10.0.0.0/24")
+
+	tests := []struct {
+		pattern, profile, ext string
+		want                  string
+	}{
+		{"scan_{datetime}.json", "", ".json", "scan_20240501T100000.json"},
+		{"scan_{datetime}.json", "", ".pb", "scan_20240501T100000.pb"},
+		{"{target_hash}_{profile}_{datetime}_{host_count}", "web quick", ".json", hash + "_web_quick_20240501T100000_2.json"},
+		{"{profile}_{datetime}", "", ".json", "custom_20240501T100000.json"},
+	}
+	for _, tt := range tests {
+		useFilenamePattern(t, tt.pattern, tt.profile)
+		if got := historyFileName(scan, tt.ext); got != tt.want {
+			t.Errorf("%s with profile %q: got %q, want %q", tt.pattern, tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestArchiveScanPrunesPerTarget(t *testing.T) {
+	dir := t.TempDir()
+	useFilenamePattern(t, "{target_hash}_{datetime}_{host_count}.json", "")
+	saved := historyDepth
+	historyDepth = 2
+	defer func() { historyDepth = saved }()
+
+	archive := func(target, datetime string, hosts int) {
+		scan := ScanResult{DateTime: datetime, Target: target, Ports: make(map[string][]string)}
+		for h := range hosts {
+			scan.Ports[string(rune('a'+h))] = nil
+		}
+		if err := archiveScan(dir, scan, []byte("{}"), ".json"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archive("10.0.0.0/24", "2024-05-01T10:00:00Z", 1)
+	archive("10.1.0.0/24", "2024-05-01T11:00:00Z", 1)
+	archive("10.0.0.0/24", "2024-05-02T10:00:00Z", 3)
+	archive("10.0.0.0/24", "2024-05-03T10:00:00Z", 2)
+
+	files, err := historyFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := targetHash("10.0.0.0/24"), targetHash("10.1.0.0/24")
+	want := []string{b + "_20240501T110000_1.json", a + "_20240502T100000_3.json", a + "_20240503T100000_2.json"}
+	if len(files) != len(want) {
+		t.Fatalf("history has %v, want %v", files, want)
+	}
+	for i, path := range files {
+		if filepath.Base(path) != want[i] {
+			t.Errorf("file %d is %s, want %s", i, filepath.Base(path), want[i])
+		}
+	}
+}
+
+func TestSortHistoryFilesMixedPatterns(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(historyFolder(dir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Files named by timestamp alone, from before -filename-pattern, and
+	// by two different patterns since
+	names := []string{"zz_20240503T100000.json", "20240501T100000.json", "scan_20240502T100000.pb"}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(historyFolder(dir), name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := historyFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"20240501T100000.json", "scan_20240502T100000.pb", "zz_20240503T100000.json"}
+	for i, path := range files {
+		if filepath.Base(path) != want[i] {
+			t.Errorf("position %d: %s, want %s", i, filepath.Base(path), want[i])
+		}
+	}
+}
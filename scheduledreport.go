@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// reportFolder holds the reports written by -report-every
+func reportFolder() string { return filepath.Join(scanFolder, "reports") }
+
+// ScheduledReports writes an HTML report every Every watch cycles, keeping
+// the newest Keep reports
+type ScheduledReports struct {
+	Every int
+	Keep  int
+
+	cycles int
+}
+
+// AfterScan counts a watch cycle, writing a report when one is due
+func (r *ScheduledReports) AfterScan() {
+	if r == nil || r.Every <= 0 {
+		return
+	}
+	r.cycles++
+	if r.cycles%r.Every != 0 {
+		return
+	}
+	path, err := WriteTrendReport(r.Every)
+	if err != nil {
+		logger.Error("writing scheduled report: %v", err)
+		return
+	}
+	logger.Info("Report written to %s", path)
+	if err := pruneReports(r.Keep); err != nil {
+		logger.Warn("pruning old reports: %v", err)
+	}
+}
+
+// WriteTrendReport writes an HTML report of the latest scan, its diff against
+// the scan before it and a sparkline of open ports over the last cycles scans
+func WriteTrendReport(cycles int) (string, error) {
+	scans, err := LoadScanHistory()
+	if err != nil {
+		return "", err
+	}
+	if len(scans) == 0 {
+		return "", errors.New("no scans stored yet")
+	}
+	latest := scans[len(scans)-1]
+
+	var diff DiffReport
+	if len(scans) > 1 {
+		diff = BuildDiffReport(scans[len(scans)-2], latest)
+	}
+
+	var trend []int
+	for _, scan := range scans[max(0, len(scans)-cycles):] {
+		trend = append(trend, openPortCount(scan))
+	}
+
+	path := filepath.Join(reportFolder(), "report_"+fileTimestamp(latest.DateTime)+".html")
+	return path, GenerateHTMLReport(latest, diff, trend, path)
+}
+
+// openPortCount returns the number of open ports across every host in the scan
+func openPortCount(scan ScanResult) int {
+	n := 0
+	for _, ports := range scan.Ports {
+		for _, entry := range ports {
+			if _, _, state, _ := splitPortEntry(entry); state == "open" {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// pruneReports deletes all but the newest keep reports (0 = keep all)
+func pruneReports(keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(reportFolder())
+	if err != nil {
+		return err
+	}
+	var reports []string
+	for _, e := range entries {
+		if name := e.Name(); strings.HasPrefix(name, "report_") && strings.HasSuffix(name, ".html") {
+			reports = append(reports, name)
+		}
+	}
+	sort.Strings(reports) // Names are timestamps, so this is chronological
+	for len(reports) > keep {
+		if err := os.Remove(filepath.Join(reportFolder(), reports[0])); err != nil {
+			return err
+		}
+		reports = reports[1:]
+	}
+	return nil
+}
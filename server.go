@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+)
+
+// NewServer returns the read-only REST API:
+//
+//	GET /history  stored scans, a page at a time (see handleHistory)
+//	GET /stats    Statistics over the stored scans
+//
+// When ac has users, requests must authenticate with basic auth and each user
+// only sees the scans of targets they are authorised for.
+func NewServer(ac AccessControl) http.Handler {
+	s := apiServer{access: ac}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /history", s.handleHistory)
+	mux.HandleFunc("GET /stats", s.handleStats)
+	return ac.requireAuth(mux)
+}
+
+// apiServer holds the REST API's settings for its handlers
+type apiServer struct {
+	access AccessControl
+}
+
+// errUnauthorized is returned to requests without valid credentials
+var errUnauthorized = errors.New("authentication required")
+
+// RunServer serves the REST API on addr until interrupted
+func RunServer(addr string, ac AccessControl) error {
+	srv := &http.Server{Addr: addr, Handler: NewServer(ac), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	logger.Info("REST API listening on %s", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Page sizes for GET /history
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// HistoryPage is the response to GET /history
+type HistoryPage struct {
+	Total   int          `json:"total"`
+	Page    int          `json:"page,omitempty"`
+	PerPage int          `json:"per_page"`
+	Results []ScanResult `json:"results"`
+	Next    string       `json:"next,omitempty"` // Cursor for the following ?after= request
+}
+
+// handleHistory serves stored scans. ?page=1&per_page=20&sort=desc pages
+// through them (newest first by default); ?after=<datetime> returns the scans
+// taken after a scan's datetime, oldest first, for polling without missing
+// scans as new ones shift the pages.
+func (s apiServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	perPage, err := queryInt(query, "per_page", defaultPerPage)
+	if err == nil && (perPage < 1 || perPage > maxPerPage) {
+		err = fmt.Errorf("per_page must be between 1 and %d", maxPerPage)
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if after := query.Get("after"); after != "" {
+		if _, err := time.Parse(time.RFC3339, after); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("after must be an RFC 3339 datetime: %v", err))
+			return
+		}
+		scans, total, err := s.historyAfter(r, after, perPage)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		next := after
+		if scans == nil {
+			scans = []ScanResult{}
+		}
+		if len(scans) > 0 {
+			next = scans[len(scans)-1].DateTime
+		}
+		writeJSON(w, HistoryPage{Total: total, PerPage: perPage, Results: scans, Next: next})
+		return
+	}
+
+	page, err := queryInt(query, "page", 1)
+	if err == nil && page < 1 {
+		err = errors.New("page must be at least 1")
+	}
+	sortOrder := query.Get("sort")
+	if err == nil && sortOrder != "" && sortOrder != "asc" && sortOrder != "desc" {
+		err = errors.New(`sort must be "asc" or "desc"`)
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	scans, total, err := s.historyPage(r, page, perPage, sortOrder != "asc")
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if scans == nil {
+		scans = []ScanResult{}
+	}
+	writeJSON(w, HistoryPage{Total: total, Page: page, PerPage: perPage, Results: scans})
+}
+
+// queryInt returns the integer query parameter name, or def if it is absent
+func queryInt(query url.Values, name string, def int) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	return n, nil
+}
+
+// historyAfter is ScanHistoryAfter limited to the scans the user may see
+func (s apiServer) historyAfter(r *http.Request, after string, limit int) ([]ScanResult, int, error) {
+	if user, ok := r.Context().Value(apiUserKey{}).(string); ok {
+		return s.access.visibleScansAfter(user, after, limit)
+	}
+	return ScanHistoryAfter(after, limit)
+}
+
+// historyPage is ScanHistoryPage limited to the scans the user may see
+func (s apiServer) historyPage(r *http.Request, page, perPage int, desc bool) ([]ScanResult, int, error) {
+	if user, ok := r.Context().Value(apiUserKey{}).(string); ok {
+		scans, err := s.access.visibleScans(user)
+		if err != nil {
+			return nil, 0, err
+		}
+		return pageOf(scans, page, perPage, desc), len(scans), nil
+	}
+	return ScanHistoryPage(page, perPage, desc)
+}
+
+func (s apiServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	var scans []ScanResult
+	var err error
+	if user, ok := r.Context().Value(apiUserKey{}).(string); ok {
+		scans, err = s.access.visibleScans(user)
+	} else {
+		scans, err = LoadScanHistory()
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, Statistics(scans))
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
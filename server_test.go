@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// saveHistory stores n scans, an hour apart from 2024-05-01T00:00:00Z, in a
+// temporary data folder
+func saveHistory(t *testing.T, n int) {
+	t.Helper()
+	useTempScanFolder(t)
+	for i := range n {
+		scan := ScanResult{
+			Version:  currentScanVersion,
+			DateTime: fmt.Sprintf("2024-05-01T%02d:00:00Z", i),
+			Target:   "10.0.0.0/24",
+			Ports:    map[string][]string{"10.0.0.1": {"22/tcp [open] (ssh)"}},
+		}
+		if err := (LocalStore{}).Save(scan); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// getHistory requests url from the API and decodes the page it returns
+func getHistory(t *testing.T, url string) (HistoryPage, int) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	NewServer(AccessControl{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	var page HistoryPage
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("%s: %v", url, err)
+		}
+	}
+	return page, rec.Code
+}
+
+// scanHours returns the hour of each scan, to compare pages briefly
+func scanHours(scans []ScanResult) []int {
+	hours := make([]int, len(scans))
+	for i, scan := range scans {
+		hours[i] = scanTime(scan.DateTime).Hour()
+	}
+	return hours
+}
+
+func TestHandleHistoryPages(t *testing.T) {
+	saveHistory(t, 5)
+
+	tests := []struct {
+		url   string
+		hours []int
+	}{
+		{"/history", []int{4, 3, 2, 1, 0}},
+		{"/history?per_page=2", []int{4, 3}},
+		{"/history?page=3&per_page=2", []int{0}},
+		{"/history?page=4&per_page=2", []int{}},
+		{"/history?page=2&per_page=2&sort=asc", []int{2, 3}},
+	}
+	for _, tt := range tests {
+		page, code := getHistory(t, tt.url)
+		if code != http.StatusOK {
+			t.Errorf("%s: status %d", tt.url, code)
+			continue
+		}
+		if page.Total != 5 || fmt.Sprint(scanHours(page.Results)) != fmt.Sprint(tt.hours) {
+			t.Errorf("%s: total %d, scans %v; want 5, %v", tt.url, page.Total, scanHours(page.Results), tt.hours)
+		}
+	}
+}
+
+func TestHandleHistoryCursor(t *testing.T) {
+	saveHistory(t, 5)
+
+	// Follow the next cursor through the history, two scans at a time
+	var hours []int
+	url := "/history?after=2024-04-30T00:00:00Z&per_page=2"
+	for range 5 {
+		page, code := getHistory(t, url)
+		if code != http.StatusOK {
+			t.Fatalf("%s: status %d", url, code)
+		}
+		if len(page.Results) == 0 {
+			break
+		}
+		hours = append(hours, scanHours(page.Results)...)
+		url = "/history?per_page=2&after=" + page.Next
+	}
+	if fmt.Sprint(hours) != "[0 1 2 3 4]" {
+		t.Errorf("following the cursor returned scans %v, want each of 0-4 once", hours)
+	}
+}
+
+func TestHandleHistoryBadRequests(t *testing.T) {
+	for _, url := range []string{
+		"/history?per_page=0",
+		"/history?per_page=101",
+		"/history?page=0",
+		"/history?page=x",
+		"/history?sort=up",
+		"/history?after=yesterday",
+	} {
+		if _, code := getHistory(t, url); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", url, code, http.StatusBadRequest)
+		}
+	}
+}
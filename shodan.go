@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// shodanInternetDBURL is Shodan's free, keyless host lookup API
+var shodanInternetDBURL = "https://internetdb.shodan.io/"
+
+// shodanConcurrency and shodanInterval keep -import-shodan within
+// InternetDB's fair-use limits
+const (
+	shodanConcurrency = 4
+	shodanInterval    = 200 * time.Millisecond
+)
+
+// ShodanInfo is what Shodan's InternetDB knows about a host beyond its ports
+type ShodanInfo struct {
+	Hostnames []string `json:"hostnames,omitempty"`
+	CPEs      []string `json:"cpes,omitempty"`  // e.g. "cpe:/a:nginx:nginx"
+	Vulns     []string `json:"vulns,omitempty"` // CVE IDs
+	Tags      []string `json:"tags,omitempty"`  // e.g. "cloud", "self-signed"
+}
+
+// errShodanNoInfo is returned for hosts InternetDB has no record of
+var errShodanNoInfo = errors.New("no information available")
+
+// ImportShodanInternetDB fetches ip's record from Shodan's InternetDB. Its
+// ports are assumed to be open TCP ports, named from the IANA registry since
+// InternetDB doesn't say which service answered.
+func ImportShodanInternetDB(ip string) (HostResult, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(shodanInternetDBURL + ip)
+	if err != nil {
+		return HostResult{}, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return HostResult{}, errShodanNoInfo
+	case resp.StatusCode != http.StatusOK:
+		return HostResult{}, fmt.Errorf("InternetDB returned %s", resp.Status)
+	}
+
+	var record struct {
+		IP string `json:"ip"`
+		ShodanInfo
+		Ports []int `json:"ports"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		return HostResult{}, fmt.Errorf("reading InternetDB response: %v", err)
+	}
+
+	host := HostResult{IP: ip, State: HostUp, Ports: make([]PortEntry, 0, len(record.Ports))}
+	if len(record.Hostnames) > 0 {
+		host.Hostname = record.Hostnames[0]
+	}
+	sort.Ints(record.Ports)
+	for _, port := range record.Ports {
+		host.Ports = append(host.Ports, PortEntry{Port: port, Protocol: "tcp", State: "open", Service: nameUnknownService(port, "tcp", "unknown")})
+	}
+	host.Shodan = &record.ShodanInfo
+	host.setExposureFlags()
+	return host, nil
+}
+
+// shodanImporter fills in hosts from InternetDB, so -import-shodan gets
+// EnrichParallel's rate limiting and circuit breaker
+type shodanImporter struct{}
+
+func (shodanImporter) Name() string               { return "shodan" }
+func (shodanImporter) MinInterval() time.Duration { return shodanInterval }
+
+func (shodanImporter) Enrich(host *HostResult) error {
+	imported, err := ImportShodanInternetDB(host.IP)
+	if errors.Is(err, errShodanNoInfo) {
+		return nil // Left without Shodan info, and dropped by ImportShodanHosts
+	}
+	if err != nil {
+		return err
+	}
+	*host = imported
+	return nil
+}
+
+// ImportShodanHosts builds a scan of ips from InternetDB. Hosts InternetDB
+// has no record of are left out. Lookup errors are returned along with the
+// hosts that did import.
+func ImportShodanHosts(ips []string) (ScanResult, error) {
+	scan := ScanResult{
+		Version:        currentScanVersion,
+		DateTime:       time.Now().UTC().Format(time.RFC3339),
+		Target:         strings.Join(ips, " "),
+		ScannerVersion: "Shodan InternetDB",
+		Ports:          make(map[string][]string, len(ips)),
+		Hosts:          make(map[string]HostResult, len(ips)),
+	}
+	for _, ip := range ips {
+		scan.Ports[ip] = []string{}
+		scan.Hosts[ip] = HostResult{IP: ip}
+	}
+	err := EnrichParallel(&scan, []Enricher{shodanImporter{}}, shodanConcurrency)
+	for ip, host := range scan.Hosts {
+		if host.Shodan == nil {
+			delete(scan.Hosts, ip)
+			delete(scan.Ports, ip)
+			continue
+		}
+		scan.Ports[ip] = host.PortStrings()
+	}
+	return scan, err
+}
+
+// shodanTargets reads the -import-shodan argument: a file of IP addresses,
+// one per line with # comments, or a comma-separated list
+func shodanTargets(arg string) ([]string, error) {
+	var entries []string
+	if f, err := os.Open(arg); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line, _, _ := strings.Cut(scanner.Text(), "#")
+			entries = append(entries, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else {
+		entries = strings.Split(arg, ",")
+	}
+
+	var ips []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if net.ParseIP(entry) == nil {
+			return nil, fmt.Errorf("-import-shodan: %q is not an IP address (InternetDB only looks up addresses)", entry)
+		}
+		ips = append(ips, entry)
+	}
+	if len(ips) == 0 {
+		return nil, errors.New("-import-shodan: no IP addresses given")
+	}
+	return ips, nil
+}
+
+// RunShodanImport implements -import-shodan: it looks the addresses up, prints
+// what was found and writes any -output-* files, or the scan as JSON to
+// stdout if none are configured
+func RunShodanImport(cfg Config) error {
+	ips, err := shodanTargets(cfg.ImportShodan)
+	if err != nil {
+		return err
+	}
+	scan, err := ImportShodanHosts(ips)
+	if err != nil {
+		logger.Warn("%v", err)
+	}
+	fmt.Fprintf(os.Stderr, "InternetDB has %d of %d hosts\n", len(scan.Hosts), len(ips))
+
+	if cfg.OutputJSON == "" && cfg.OutputCSV == "" && cfg.OutputMarkdown == "" && cfg.OutputNmapXML == "" && cfg.OutputTemplate == "" && cfg.HTMLReport == "" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(scan)
+	}
+	return WriteOutputs(cfg, scan, DiffReport{})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fakeInternetDB answers lookups of 203.0.113.10 with the InternetDB fixture,
+// and has no record of any other address
+func fakeInternetDB(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/203.0.113.10" {
+			http.Error(w, `{"detail":"No information available"}`, http.StatusNotFound)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", "shodan_internetdb.json"))
+	}))
+	t.Cleanup(server.Close)
+
+	old := shodanInternetDBURL
+	shodanInternetDBURL = server.URL + "/"
+	t.Cleanup(func() { shodanInternetDBURL = old })
+}
+
+func TestImportShodanInternetDBFixture(t *testing.T) {
+	fakeInternetDB(t)
+
+	scan, err := ImportShodanHosts([]string{"203.0.113.10", "203.0.113.99"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ports are sorted open TCP ports named from the IANA registry where it
+	// has them, and hosts InternetDB doesn't know are left out
+	want := map[string][]string{
+		"203.0.113.10": {"22/tcp [open] (ssh)", "80/tcp [open] (http)", "443/tcp [open] (https)", "8443/tcp [open] (unknown)"},
+	}
+	if !reflect.DeepEqual(scan.Ports, want) {
+		t.Errorf("got %v\nwant %v", scan.Ports, want)
+	}
+	if scan.ScannerVersion != "Shodan InternetDB" || scan.Target != "203.0.113.10 203.0.113.99" {
+		t.Errorf("scanner %q, target %q", scan.ScannerVersion, scan.Target)
+	}
+
+	host := scan.Hosts["203.0.113.10"]
+	if host.Hostname != "www.example.com" || host.State != HostUp || host.Shodan == nil {
+		t.Fatalf("203.0.113.10: %+v", host)
+	}
+	if !reflect.DeepEqual(host.Shodan.Vulns, []string{"CVE-2021-23017", "CVE-2023-38408"}) || !reflect.DeepEqual(host.Shodan.Tags, []string{"cloud", "self-signed"}) || len(host.Shodan.CPEs) != 3 {
+		t.Errorf("Shodan info %+v", *host.Shodan)
+	}
+
+	if _, err := ImportShodanInternetDB("203.0.113.99"); !errors.Is(err, errShodanNoInfo) {
+		t.Errorf("lookup of an unknown address returned %v, want errShodanNoInfo", err)
+	}
+}
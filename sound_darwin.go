@@ -0,0 +1,14 @@
+//go:build darwin
+
+package main
+
+import "os/exec"
+
+// PlayCompletionSound plays the system "Glass" sound. It silently does nothing
+// if afplay is unavailable.
+func PlayCompletionSound() error {
+	if _, err := exec.LookPath("afplay"); err != nil {
+		return nil
+	}
+	return exec.Command("afplay", "/System/Library/Sounds/Glass.aiff").Run()
+}
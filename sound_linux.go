@@ -0,0 +1,26 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// completionSoundFile is the freedesktop sound theme's "complete" event
+const completionSoundFile = "/usr/share/sounds/freedesktop/stereo/complete.oga"
+
+// PlayCompletionSound plays a completion sound through PulseAudio/PipeWire
+// (paplay), falling back to the PC speaker (beep). It silently does nothing if
+// no audio subsystem is available.
+func PlayCompletionSound() error {
+	if _, err := exec.LookPath("paplay"); err == nil {
+		if _, err := os.Stat(completionSoundFile); err == nil {
+			return exec.Command("paplay", completionSoundFile).Run()
+		}
+	}
+	if _, err := exec.LookPath("beep"); err == nil {
+		return exec.Command("beep").Run()
+	}
+	return nil
+}
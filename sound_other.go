@@ -0,0 +1,8 @@
+//go:build !darwin && !linux
+
+package main
+
+// PlayCompletionSound is a no-op on platforms without a supported audio player
+func PlayCompletionSound() error {
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sparkBars are the block characters used by Sparkline, lowest first
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a one-line bar chart, e.g. "▁▃▅█▅"
+func Sparkline(values []int) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+
+	bars := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if hi > lo {
+			level = (v - lo) * (len(sparkBars) - 1) / (hi - lo)
+		}
+		bars[i] = sparkBars[level]
+	}
+	return string(bars)
+}
+
+// Size of a TrendChart plot, in characters. Each braille character holds a
+// 2x4 grid of dots, so the plot is twice as wide and four times as tall in dots.
+const (
+	trendWidth  = 60
+	trendHeight = 10
+)
+
+// trendMetrics are the values TrendChart can plot, by name
+var trendMetrics = map[string]func(ScanResult) int{
+	"open_ports":     func(s ScanResult) int { return countPortStates(s, "open") },
+	"filtered_ports": func(s ScanResult) int { return countPortStates(s, "filtered") },
+	"host_count":     func(s ScanResult) int { return len(s.Ports) },
+}
+
+// countPortStates counts the port entries in scan whose state is state or,
+// for "filtered", includes it (e.g. "open|filtered")
+func countPortStates(scan ScanResult, state string) int {
+	n := 0
+	for _, ports := range scan.Ports {
+		for _, entry := range ports {
+			_, _, s, _ := splitPortEntry(entry)
+			if s == state || state == "filtered" && strings.Contains(s, state) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// ValidateTrendMetric checks a -trend value
+func ValidateTrendMetric(metric string) error {
+	if _, ok := trendMetrics[metric]; !ok {
+		return fmt.Errorf("unknown -trend metric %q (choose open_ports, filtered_ports or host_count)", metric)
+	}
+	return nil
+}
+
+// brailleDots are the bits of a braille character's dots, by [row][column]
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// TrendChart plots metric over results (oldest first) as a line chart drawn
+// with braille characters, scan time across and the metric's value up. It
+// returns "" for an unknown metric or no results.
+func TrendChart(results []ScanResult, metric string) string {
+	count, ok := trendMetrics[metric]
+	if !ok || len(results) == 0 {
+		return ""
+	}
+
+	values := make([]int, len(results))
+	lo, hi := count(results[0]), count(results[0])
+	for i, scan := range results {
+		values[i] = count(scan)
+		lo, hi = min(lo, values[i]), max(hi, values[i])
+	}
+	first, last := scanTime(results[0].DateTime), scanTime(results[len(results)-1].DateTime)
+	byTime := !first.IsZero() && last.After(first)
+
+	// Work out each scan's dot, spacing them by time when the times are known
+	const dotsX, dotsY = trendWidth * 2, trendHeight * 4
+	xs, ys := make([]int, len(results)), make([]int, len(results))
+	for i, scan := range results {
+		switch {
+		case byTime:
+			xs[i] = int(float64(dotsX-1) * float64(scanTime(scan.DateTime).Sub(first)) / float64(last.Sub(first)))
+		case len(results) > 1:
+			xs[i] = i * (dotsX - 1) / (len(results) - 1)
+		}
+		if hi > lo {
+			ys[i] = (values[i] - lo) * (dotsY - 1) / (hi - lo)
+		}
+		xs[i] = min(max(xs[i], 0), dotsX-1)
+	}
+
+	var canvas [trendHeight][trendWidth]rune
+	plot := func(x, y int) {
+		row := dotsY - 1 - y
+		canvas[row/4][x/2] |= brailleDots[row%4][x%2]
+	}
+	plot(xs[0], ys[0])
+	for i := 1; i < len(results); i++ {
+		dx, dy := xs[i]-xs[i-1], ys[i]-ys[i-1]
+		steps := max(abs(dx), abs(dy), 1)
+		for s := 1; s <= steps; s++ {
+			plot(xs[i-1]+dx*s/steps, ys[i-1]+dy*s/steps)
+		}
+	}
+
+	var sb strings.Builder
+	labelWidth := max(len(strconv.Itoa(lo)), len(strconv.Itoa(hi)))
+	fmt.Fprintf(&sb, "%s over %d scans\n", metric, len(results))
+	for row := range canvas {
+		label := ""
+		switch row {
+		case 0:
+			label = strconv.Itoa(hi)
+		case trendHeight - 1:
+			label = strconv.Itoa(lo)
+		}
+		fmt.Fprintf(&sb, "%*s ┤", labelWidth, label)
+		for _, dots := range canvas[row] {
+			sb.WriteRune(0x2800 + dots)
+		}
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "%*s └%s\n", labelWidth, "", strings.Repeat("─", trendWidth))
+
+	from, to := "scan 1", fmt.Sprintf("scan %d", len(results))
+	if byTime {
+		from, to = first.Format("2006-01-02"), last.Format("2006-01-02")
+	} else if len(results) == 1 {
+		to = ""
+	}
+	fmt.Fprintf(&sb, "%*s  %s%*s\n", labelWidth, "", from, trendWidth-len(from), to)
+	return sb.String()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// trendScans returns one scan per datetime with the given number of open ports
+func trendScans(datetimes []string, open []int) []ScanResult {
+	scans := make([]ScanResult, len(datetimes))
+	for i, datetime := range datetimes {
+		ports := []string{"161/udp [open|filtered] (snmp)"}
+		for p := range open[i] {
+			ports = append(ports, PortEntry{Port: 1000 + p, Protocol: "tcp", State: "open", Service: "http"}.String())
+		}
+		scans[i] = ScanResult{DateTime: datetime, Ports: map[string][]string{"10.0.0.1": ports}}
+	}
+	return scans
+}
+
+func TestTrendChart(t *testing.T) {
+	scans := trendScans([]string{"2024-05-01T00:00:00Z", "2024-05-02T00:00:00Z", "2024-05-05T00:00:00Z"}, []int{3, 12, 40})
+	chart := TrendChart(scans, "open_ports")
+	lines := strings.Split(strings.TrimSuffix(chart, "\n"), "\n")
+	if len(lines) != 1+trendHeight+2 {
+		t.Fatalf("got %d lines, want a title, %d rows, an axis and dates:\n%s", len(lines), trendHeight, chart)
+	}
+	if lines[0] != "open_ports over 3 scans" {
+		t.Errorf("title %q", lines[0])
+	}
+
+	top, bottom := []rune(lines[1]), []rune(lines[trendHeight])
+	if !strings.HasPrefix(lines[1], "40 ┤") || !strings.HasPrefix(lines[trendHeight], " 3 ┤") {
+		t.Errorf("axis labels wrong:\n%s", chart)
+	}
+	// The line starts bottom left and ends top right
+	if bottom[4] == '⠀' || top[len(top)-1] == '⠀' || top[4] != '⠀' {
+		t.Errorf("line does not run from bottom left to top right:\n%s", chart)
+	}
+	// Scans are spaced by time, so moving the last one closer changes the line
+	even := trendScans([]string{"2024-05-01T00:00:00Z", "2024-05-02T00:00:00Z", "2024-05-03T00:00:00Z"}, []int{3, 12, 40})
+	if evenChart := TrendChart(even, "open_ports"); strings.Join(strings.Split(evenChart, "\n")[1:1+trendHeight], "\n") == strings.Join(lines[1:1+trendHeight], "\n") {
+		t.Errorf("scans a day and three days apart drawn the same:\n%s", chart)
+	}
+	if dates := lines[len(lines)-1]; !strings.HasPrefix(strings.TrimSpace(dates), "2024-05-01") || !strings.HasSuffix(dates, "2024-05-05") {
+		t.Errorf("date axis %q", dates)
+	}
+	for _, line := range lines[1 : 1+trendHeight] {
+		if n := len([]rune(line)); n != 4+trendWidth {
+			t.Errorf("row of %d characters, want %d: %q", n, 4+trendWidth, line)
+		}
+	}
+}
+
+func TestTrendChartMetrics(t *testing.T) {
+	scans := trendScans([]string{"", ""}, []int{1, 2})
+	tests := []struct {
+		metric string
+		want   string // Label of the top row
+	}{
+		{"open_ports", "2 ┤"},
+		{"filtered_ports", "1 ┤"}, // The open|filtered port
+		{"host_count", "1 ┤"},
+	}
+	for _, tt := range tests {
+		chart := TrendChart(scans, tt.metric)
+		if lines := strings.Split(chart, "\n"); len(lines) < 2 || !strings.HasPrefix(lines[1], tt.want) {
+			t.Errorf("%s: chart\n%s\nwant the top labelled %q", tt.metric, chart, tt.want)
+		}
+		if !strings.Contains(chart, "scan 1") || !strings.Contains(chart, "scan 2") {
+			t.Errorf("%s: scans without times not labelled by number:\n%s", tt.metric, chart)
+		}
+	}
+
+	if chart := TrendChart(scans, "closed_ports"); chart != "" {
+		t.Errorf("unknown metric drew\n%s", chart)
+	}
+	if chart := TrendChart(nil, "open_ports"); chart != "" {
+		t.Errorf("no scans drew\n%s", chart)
+	}
+	if chart := TrendChart(scans[:1], "open_ports"); !strings.Contains(chart, "over 1 scans") {
+		t.Errorf("single scan drew\n%s", chart)
+	}
+}
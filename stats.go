@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Stats summarises a set of scans, e.g. "we monitor X hosts across Y scans
+// with Z average open ports"
+type Stats struct {
+	Scans             int     `json:"scans"`
+	TotalHosts        int     `json:"total_hosts"` // Distinct hosts seen up in any scan
+	TotalPorts        int     `json:"total_ports"` // Port entries across all scans
+	OpenPorts         int     `json:"open_ports"`
+	ClosedPorts       int     `json:"closed_ports"`
+	FilteredPorts     int     `json:"filtered_ports"` // Includes open|filtered
+	MostCommonService string  `json:"most_common_service,omitempty"`
+	MostScannedHost   string  `json:"most_scanned_host,omitempty"` // Host up in the most scans
+	AveragePorts      float64 `json:"average_ports"`               // Open ports per host per scan
+}
+
+// Statistics computes Stats over results. Ties are broken alphabetically so
+// the output is stable.
+func Statistics(results []ScanResult) Stats {
+	stats := Stats{Scans: len(results)}
+	services := make(map[string]int)
+	hostScans := make(map[string]int)
+	observations := 0
+
+	for _, scan := range results {
+		for ip, ports := range scan.Ports {
+			hostScans[ip]++
+			observations++
+			for _, entry := range ports {
+				stats.TotalPorts++
+				_, _, state, service := splitPortEntry(entry)
+				switch {
+				case state == "open":
+					stats.OpenPorts++
+					services[service]++
+				case state == "closed":
+					stats.ClosedPorts++
+				case strings.Contains(state, "filtered"):
+					stats.FilteredPorts++
+				}
+			}
+		}
+	}
+
+	stats.TotalHosts = len(hostScans)
+	stats.MostCommonService = mostFrequent(services)
+	stats.MostScannedHost = mostFrequent(hostScans)
+	if observations > 0 {
+		stats.AveragePorts = float64(stats.OpenPorts) / float64(observations)
+	}
+	return stats
+}
+
+// mostFrequent returns the key with the highest count, or "" for an empty map
+func mostFrequent(counts map[string]int) string {
+	best := ""
+	for _, key := range sortedHostKeys(counts) {
+		if best == "" || counts[key] > counts[best] {
+			best = key
+		}
+	}
+	return best
+}
+
+// PrintStatistics writes stats in a human-readable form
+func PrintStatistics(stats Stats, w io.Writer) {
+	fmt.Fprintf(w, "Scans:               %d\n", stats.Scans)
+	fmt.Fprintf(w, "Hosts monitored:     %d\n", stats.TotalHosts)
+	fmt.Fprintf(w, "Ports recorded:      %d (%d open, %d closed, %d filtered)\n", stats.TotalPorts, stats.OpenPorts, stats.ClosedPorts, stats.FilteredPorts)
+	fmt.Fprintf(w, "Average open ports:  %.1f per host\n", stats.AveragePorts)
+	if stats.MostCommonService != "" {
+		fmt.Fprintf(w, "Most common service: %s\n", stats.MostCommonService)
+	}
+	if stats.MostScannedHost != "" {
+		fmt.Fprintf(w, "Most scanned host:   %s\n", stats.MostScannedHost)
+	}
+}
+
+// portFrequencyTop is how many ports PrintPortFrequency lists
+const portFrequencyTop = 20
+
+// PortFrequency is how many of the monitored hosts have a port open
+type PortFrequency struct {
+	Port       string  `json:"port"`    // e.g. "443/tcp"
+	Service    string  `json:"service"` // The service nmap most often reported on it
+	OpenCount  int     `json:"open_count"`
+	TotalHosts int     `json:"total_hosts"`
+	Percentage float64 `json:"percentage"` // OpenCount as a percentage of TotalHosts
+}
+
+// PortFrequencyAnalysis counts, for every open port, how many hosts have it
+// open, keyed by port. Each host counts once, using the latest of results
+// (which are oldest first) that includes it.
+func PortFrequencyAnalysis(results []ScanResult) map[string]PortFrequency {
+	latest := make(map[string][]string)
+	for _, scan := range results {
+		for ip, ports := range scan.Ports {
+			latest[ip] = ports
+		}
+	}
+
+	open := make(map[string]int)
+	services := make(map[string]map[string]int)
+	for _, ports := range latest {
+		seen := make(map[string]bool)
+		for _, entry := range ports {
+			port, proto, state, service := splitPortEntry(entry)
+			if key := port + "/" + proto; state == "open" && !seen[key] {
+				seen[key] = true
+				open[key]++
+				if services[key] == nil {
+					services[key] = make(map[string]int)
+				}
+				services[key][service]++
+			}
+		}
+	}
+
+	freq := make(map[string]PortFrequency, len(open))
+	for port, count := range open {
+		freq[port] = PortFrequency{
+			Port:       port,
+			Service:    mostFrequent(services[port]),
+			OpenCount:  count,
+			TotalHosts: len(latest),
+			Percentage: 100 * float64(count) / float64(len(latest)),
+		}
+	}
+	return freq
+}
+
+// PrintPortFrequency writes the most commonly open ports as a table, most
+// common first
+func PrintPortFrequency(freq map[string]PortFrequency, w io.Writer) {
+	ports := make([]PortFrequency, 0, len(freq))
+	for _, f := range freq {
+		ports = append(ports, f)
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].OpenCount != ports[j].OpenCount {
+			return ports[i].OpenCount > ports[j].OpenCount
+		}
+		numI, _ := strconv.Atoi(strings.Split(ports[i].Port, "/")[0])
+		numJ, _ := strconv.Atoi(strings.Split(ports[j].Port, "/")[0])
+		return numI < numJ || numI == numJ && ports[i].Port < ports[j].Port
+	})
+	if len(ports) == 0 {
+		fmt.Fprintln(w, "No open ports in the stored scans.")
+		return
+	}
+
+	fmt.Fprintf(w, "%-12s %-16s %6s %7s\n", "PORT", "SERVICE", "HOSTS", "SHARE")
+	for _, f := range ports[:min(len(ports), portFrequencyTop)] {
+		fmt.Fprintf(w, "%-12s %-16s %6d %6.1f%%\n", f.Port, f.Service, f.OpenCount, f.Percentage)
+	}
+	fmt.Fprintf(w, "\nHosts: %d, distinct open ports: %d\n", ports[0].TotalHosts, len(ports))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPortFrequencyAnalysis(t *testing.T) {
+	results := []ScanResult{
+		{Ports: map[string][]string{
+			"10.0.0.1": {"22/tcp [open] (ssh)", "3306/tcp [open] (mysql)"}, // Superseded by the later scan
+			"10.0.0.2": {"22/tcp [open] (ssh)", "80/tcp [open] (http)"},
+		}},
+		{Ports: map[string][]string{
+			"10.0.0.1": {"22/tcp [open] (ssh)", "80/tcp [filtered] (http)"},
+			"10.0.0.3": {"22/tcp [open] (ssh)", "80/tcp [open] (www)", "80/tcp [open] (http)"},
+			"10.0.0.4": {"53/udp [open] (domain)"},
+		}},
+	}
+	freq := PortFrequencyAnalysis(results)
+
+	want := map[string]PortFrequency{
+		"22/tcp": {Port: "22/tcp", Service: "ssh", OpenCount: 3, TotalHosts: 4, Percentage: 75},
+		"80/tcp": {Port: "80/tcp", Service: "http", OpenCount: 2, TotalHosts: 4, Percentage: 50},
+		"53/udp": {Port: "53/udp", Service: "domain", OpenCount: 1, TotalHosts: 4, Percentage: 25},
+	}
+	if len(freq) != len(want) {
+		t.Errorf("got ports %v, want %v", freq, want)
+	}
+	for port, w := range want {
+		if freq[port] != w {
+			t.Errorf("%s: got %+v, want %+v", port, freq[port], w)
+		}
+	}
+}
+
+func TestPrintPortFrequency(t *testing.T) {
+	// 25 ports open on a decreasing number of hosts, so the top 20 are known
+	scan := ScanResult{Ports: make(map[string][]string)}
+	for h := range 25 {
+		var ports []string
+		for p := range 25 - h {
+			ports = append(ports, fmt.Sprintf("%d/tcp [open] (svc%d)", 1000+p, p))
+		}
+		scan.Ports[fmt.Sprintf("10.0.0.%d", h+1)] = ports
+	}
+
+	var sb strings.Builder
+	PrintPortFrequency(PortFrequencyAnalysis([]ScanResult{scan}), &sb)
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+	if len(lines) != 1+portFrequencyTop+2 {
+		t.Fatalf("got %d lines, want a header, %d ports and a summary:\n%s", len(lines), portFrequencyTop, sb.String())
+	}
+	if !strings.HasPrefix(lines[1], "1000/tcp     svc0                 25  100.0%") || !strings.HasPrefix(lines[portFrequencyTop], "1019/tcp") {
+		t.Errorf("ports not listed most common first:\n%s", sb.String())
+	}
+	if lines[len(lines)-1] != "Hosts: 25, distinct open ports: 25" {
+		t.Errorf("summary %q", lines[len(lines)-1])
+	}
+
+	sb.Reset()
+	PrintPortFrequency(nil, &sb)
+	if sb.String() != "No open ports in the stored scans.\n" {
+		t.Errorf("empty analysis printed %q", sb.String())
+	}
+}
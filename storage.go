@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"scanchecker/pb"
+)
+
+// Binary (protobuf) counterparts of scanFile and backupScanFile
+func scanFileProto(dir string) string       { return filepath.Join(dir, "previous_scan.pb") }
+func backupScanFileProto(dir string) string { return filepath.Join(dir, "previous_previous_scan.pb") }
+
+// storedScanPaths returns the current and backup scan files in dir for a storage format
+func storedScanPaths(dir, format string) (current, backup string) {
+	if format == "proto" {
+		return scanFileProto(dir), backupScanFileProto(dir)
+	}
+	return scanFile(dir), backupScanFile(dir)
+}
+
+// ValidateStorageFormat checks a -storage-format value
+func ValidateStorageFormat(format string) error {
+	if format != "json" && format != "proto" {
+		return fmt.Errorf("unknown storage format %q (choose json or proto)", format)
+	}
+	return nil
+}
+
+// encodeScan serialises a scan for the file at path, choosing the format from
+// its extension. The scan is upgraded to the current schema version first.
+func encodeScan(scan ScanResult, path string) ([]byte, error) {
+	if err := upgradeScan(&scan); err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(path, ".pb") {
+		return proto.Marshal(ScanResultToProto(scan))
+	}
+	return json.MarshalIndent(scan, "", "  ")
+}
+
+// decodeScan parses a stored scan, choosing the format from the file's extension
+func decodeScan(data []byte, path string) (ScanResult, error) {
+	if strings.HasSuffix(path, ".pb") {
+		var msg pb.ScanResult
+		if err := proto.Unmarshal(data, &msg); err != nil {
+			return ScanResult{}, err
+		}
+		scan := ScanResultFromProto(&msg)
+		return scan, upgradeScan(&scan)
+	}
+	return MigrateScanResult(data)
+}
+
+// LoadScanFile reads a scan file given on the command line: one of
+// PortHunter's own JSON or .pb scans, or another scanner's export: Nessus
+// (.nessus) or an XML file recognised by its root element (Nessus or GVM)
+func LoadScanFile(path string) (ScanResult, error) {
+	if strings.HasSuffix(path, ".nessus") {
+		return ParseNessusXML(path)
+	}
+	if strings.HasSuffix(path, ".xml") {
+		root, err := xmlRootElement(path)
+		if err != nil {
+			return ScanResult{}, err
+		}
+		switch root {
+		case "NessusClientData_v2":
+			return ParseNessusXML(path)
+		case "report", "get_reports_response":
+			return ParseGVMXML(path)
+		}
+		return ScanResult{}, fmt.Errorf("unsupported XML report <%s> (Nessus and GVM reports are supported)", root)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	return decodeScan(data, path)
+}
+
+// xmlRootElement returns the name of the XML document's root element
+func xmlRootElement(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", fmt.Errorf("parsing %s: %v", path, err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// ScanResultToProto converts a scan to its protobuf message
+func ScanResultToProto(r ScanResult) *pb.ScanResult {
+	msg := &pb.ScanResult{
+		Version:  int32(r.Version),
+		Datetime: r.DateTime,
+		Target:   r.Target,
+		Command:  r.Command,
+		Ports:    make(map[string]*pb.PortList, len(r.Ports)),
+
+		ExcludeRules:    r.ExcludeRules,
+		ExcludedTargets: r.ExcludedTargets,
+		ScannerVersion:  r.ScannerVersion,
+	}
+	for ip, ports := range r.Ports {
+		msg.Ports[ip] = &pb.PortList{Entries: ports}
+	}
+	if len(r.Hosts) > 0 {
+		msg.Hosts = make(map[string]*pb.HostResult, len(r.Hosts))
+		for ip, host := range r.Hosts {
+			h := &pb.HostResult{Ip: host.IP, Hostname: host.Hostname, State: host.State, Mac: host.MAC, Vendor: host.Vendor, Os: host.OS, OsFingerprint: host.OSFingerprint, Latency: int64(host.Latency), Hops: int32(host.Hops), DnsTtl: int32(host.DNSTTL)}
+			for _, hop := range host.Traceroute {
+				h.Traceroute = append(h.Traceroute, &pb.TraceHop{HopNum: int32(hop.HopNum), Ip: hop.IP, Rtt: hop.RTT, Hostname: hop.Hostname})
+			}
+			for _, p := range host.Ports {
+				h.Ports = append(h.Ports, &pb.PortEntry{
+					Port:     int32(p.Port),
+					Protocol: p.Protocol,
+					State:    p.State,
+					Service:  p.Service,
+					Scripts:  p.Scripts,
+				})
+			}
+			msg.Hosts[ip] = h
+		}
+	}
+	if len(r.PerHostTiming) > 0 {
+		msg.PerHostTiming = make(map[string]int64, len(r.PerHostTiming))
+		for ip, d := range r.PerHostTiming {
+			msg.PerHostTiming[ip] = int64(d)
+		}
+	}
+	return msg
+}
+
+// ScanResultFromProto converts a protobuf message back to a scan
+func ScanResultFromProto(p *pb.ScanResult) ScanResult {
+	r := ScanResult{
+		Version:  int(p.GetVersion()),
+		DateTime: p.GetDatetime(),
+		Target:   p.GetTarget(),
+		Command:  p.GetCommand(),
+		Ports:    make(map[string][]string, len(p.GetPorts())),
+
+		ExcludeRules:    p.GetExcludeRules(),
+		ExcludedTargets: p.GetExcludedTargets(),
+		ScannerVersion:  p.GetScannerVersion(),
+	}
+	for ip, ports := range p.GetPorts() {
+		// A host with no ports is still up, so keep an empty (not nil) list
+		r.Ports[ip] = append([]string{}, ports.GetEntries()...)
+	}
+	if len(p.GetHosts()) > 0 {
+		r.Hosts = make(map[string]HostResult, len(p.GetHosts()))
+		for ip, h := range p.GetHosts() {
+			host := HostResult{IP: h.GetIp(), Hostname: h.GetHostname(), State: h.GetState(), MAC: h.GetMac(), Vendor: h.GetVendor(), OS: h.GetOs(), OSFingerprint: h.GetOsFingerprint(), DNSTTL: int(h.GetDnsTtl()), Ports: make([]PortEntry, 0, len(h.GetPorts()))}
+			for _, e := range h.GetPorts() {
+				host.Ports = append(host.Ports, PortEntry{
+					Port:     int(e.GetPort()),
+					Protocol: e.GetProtocol(),
+					State:    e.GetState(),
+					Service:  e.GetService(),
+					Scripts:  e.GetScripts(),
+				})
+			}
+			host.HostMetrics = HostMetrics{Latency: time.Duration(h.GetLatency()), Hops: int(h.GetHops())}
+			for _, hop := range h.GetTraceroute() {
+				host.Traceroute = append(host.Traceroute, TraceHop{HopNum: int(hop.GetHopNum()), IP: hop.GetIp(), RTT: hop.GetRtt(), Hostname: hop.GetHostname()})
+			}
+			host.setExposureFlags()
+			host.markUnexpectedServices()
+			r.Hosts[ip] = host
+		}
+	}
+	if len(p.GetPerHostTiming()) > 0 {
+		r.PerHostTiming = make(map[string]time.Duration, len(p.GetPerHostTiming()))
+		for ip, ns := range p.GetPerHostTiming() {
+			r.PerHostTiming[ip] = time.Duration(ns)
+		}
+	}
+	return r
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// benchmarkScan returns a scan of hosts×ports ports, with full host detail
+// as an XML scan has
+func benchmarkScan(hosts, ports int) ScanResult {
+	scan := ScanResult{
+		Version:  currentScanVersion,
+		DateTime: "2024-05-01T10:00:00Z",
+		Ports:    make(map[string][]string, hosts),
+		Hosts:    make(map[string]HostResult, hosts),
+	}
+	for h := range hosts {
+		ip := fmt.Sprintf("10.0.%d.%d", h/256, h%256)
+		host := HostResult{IP: ip, State: HostUp, OS: "Linux 5.0 - 5.4"}
+		for p := range ports {
+			host.Ports = append(host.Ports, PortEntry{Port: 1000 + p, Protocol: "tcp", State: "open", Service: "http"})
+		}
+		scan.Hosts[ip] = host
+		scan.Ports[ip] = host.PortStrings()
+	}
+	return scan
+}
+
+// BenchmarkScanStorage compares the two -storage-format encodings on a
+// 50,000-port scan (500 hosts × 100 ports)
+func BenchmarkScanStorage(b *testing.B) {
+	scan := benchmarkScan(500, 100)
+	for _, format := range []string{"json", "proto"} {
+		path := "previous_scan.json"
+		if format == "proto" {
+			path = "previous_scan.pb"
+		}
+		data, err := encodeScan(scan, path)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run("encode/"+format, func(b *testing.B) {
+			b.ReportMetric(float64(len(data)), "bytes")
+			for range b.N {
+				if _, err := encodeScan(scan, path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("decode/"+format, func(b *testing.B) {
+			for range b.N {
+				if _, err := decodeScan(data, path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestScanResultProtoRoundTrip(t *testing.T) {
+	scan := benchmarkScan(3, 4)
+	data, err := encodeScan(scan, "previous_scan.pb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeScan(data, "previous_scan.pb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, want := range scan.Ports {
+		if fmt.Sprint(got.Ports[ip]) != fmt.Sprint(want) || len(got.Hosts[ip].Ports) != 4 {
+			t.Errorf("%s: got %v, want %v", ip, got.Ports[ip], want)
+		}
+	}
+}
+
+// flakyStore fails Latest with err the first failures times
+type flakyStore struct {
+	memStore
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *flakyStore) Latest() (ScanResult, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return ScanResult{}, s.err
+	}
+	return s.memStore.Latest()
+}
+
+func TestLoadPreviousScanWithRetry(t *testing.T) {
+	saved := scanStore
+	defer func() { scanStore = saved }()
+	scan := ScanResult{Version: currentScanVersion, DateTime: "2024-05-01T10:00:00Z"}
+	busy := &os.PathError{Op: "flock", Path: "previous_scan.lock", Err: syscall.EAGAIN}
+
+	tests := []struct {
+		name      string
+		store     *flakyStore
+		retries   int
+		wantErr   bool
+		wantCalls int
+	}{
+		{"succeeds after retries", &flakyStore{failures: 2, err: busy}, 3, false, 3},
+		{"gives up", &flakyStore{failures: 5, err: busy}, 3, true, 4},
+		{"no retry for other errors", &flakyStore{failures: 1, err: os.ErrPermission}, 3, true, 1},
+	}
+	for _, tt := range tests {
+		tt.store.scans = []ScanResult{scan}
+		scanStore = tt.store
+		got, err := LoadPreviousScanWithRetry(tt.retries, time.Millisecond)
+		if (err != nil) != tt.wantErr || tt.store.calls != tt.wantCalls {
+			t.Errorf("%s: err %v after %d calls, want error %v after %d", tt.name, err, tt.store.calls, tt.wantErr, tt.wantCalls)
+		}
+		if !tt.wantErr && got.DateTime != scan.DateTime {
+			t.Errorf("%s: got %+v", tt.name, got)
+		}
+	}
+}
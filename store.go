@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Store is where scans are kept between runs
+type Store interface {
+	Save(scan ScanResult) error
+	Latest() (ScanResult, error) // The most recently saved scan
+	List() ([]string, error)     // Keys of the stored scans, oldest first
+	Load(key string) (ScanResult, error)
+}
+
+// scanStore is where the scan history is read from; set from the config
+var scanStore Store = LocalStore{}
+
+// storeFor returns the store configured by cfg: its S3 bucket, if set, otherwise the data folder
+func storeFor(cfg Config) Store {
+	if cfg.S3.Bucket != "" {
+		return cfg.S3
+	}
+	return LocalStore{Dir: cfg.DataDir, Format: cfg.StorageFormat, Path: cfg.OutputFile}
+}
+
+// LocalStore keeps scans in the data folder: the last two as previous_scan
+// and previous_previous_scan, and older ones in the history folder. Its keys
+// are file paths.
+type LocalStore struct {
+	// Dir is the data folder (default: the -data-dir folder)
+	Dir string
+	// Format is how Save writes scans, "json" (the default) or "proto"
+	Format string
+	// Path, if set, is the file the latest scan is written to and compared
+	// against instead of previous_scan in the data folder, e.g. on a Docker
+	// volume. Its extension picks the format, like -storage-format does.
+	Path string
+}
+
+// dir returns the data folder the store keeps its scans in
+func (s LocalStore) dir() string { return cmp.Or(s.Dir, scanFolder) }
+
+// Save writes scan in the store's format, preserving the old scan before overwriting
+func (s LocalStore) Save(scan ScanResult) error {
+	// Ensure the data folder exists; the history is kept there either way
+	if err := os.MkdirAll(s.dir(), 0755); err != nil {
+		return err
+	}
+
+	// If a previous scan exists, move it before overwriting
+	current, backup := storedScanPaths(s.dir(), s.Format)
+	if s.Path != "" {
+		if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+			return err
+		}
+		ext := filepath.Ext(s.Path)
+		current, backup = s.Path, strings.TrimSuffix(s.Path, ext)+".previous"+ext
+	}
+	unlock, err := lockScanFile(s.lockPath(), true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, err := os.Stat(current); err == nil {
+		os.Rename(current, backup) // Move previous scan to backup before overwriting
+	}
+
+	data, err := encodeScan(scan, current)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(current, data, 0644); err != nil {
+		return err
+	}
+	return archiveScan(s.dir(), scan, data, filepath.Ext(current))
+}
+
+// Latest reads Path, if set, or else the newest of the JSON and protobuf scan files
+func (s LocalStore) Latest() (ScanResult, error) {
+	unlock, err := lockScanFile(s.lockPath(), false)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer unlock()
+	if s.Path != "" {
+		return s.Load(s.Path)
+	}
+	path := scanFile(s.dir())
+	var newest time.Time
+	for _, candidate := range []string{path, scanFileProto(s.dir())} {
+		if info, err := os.Stat(candidate); err == nil && info.ModTime().After(newest) {
+			path, newest = candidate, info.ModTime()
+		}
+	}
+	return s.Load(path)
+}
+
+// lockPath is the lock file serialising Save and Latest across processes
+func (s LocalStore) lockPath() string {
+	if s.Path != "" {
+		return s.Path + ".lock"
+	}
+	return filepath.Join(s.dir(), "previous_scan.lock")
+}
+
+// List returns the scans in the history folder
+func (s LocalStore) List() ([]string, error) { return historyFiles(s.dir()) }
+
+// Load reads the scan file at key
+func (LocalStore) Load(key string) (ScanResult, error) {
+	data, err := os.ReadFile(key)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	return decodeScan(data, key)
+}
+
+// s3ScanPrefix is the key prefix S3Store stores scans under
+const s3ScanPrefix = "scans/"
+
+// S3Store keeps scans as JSON objects in an S3 bucket, or any S3-compatible
+// store such as MinIO or Ceph, for deployments without persistent local disk.
+// Keys are scans/<datetime>_<target hash>.json, so they sort chronologically.
+type S3Store struct {
+	Bucket    string `yaml:"bucket"`
+	Region    string `yaml:"region"`     // Default: us-east-1
+	Endpoint  string `yaml:"endpoint"`   // For S3-compatible stores, e.g. http://minio:9000
+	AccessKey string `yaml:"access_key"` // Default: $AWS_ACCESS_KEY_ID
+	SecretKey string `yaml:"secret_key"` // Default: $AWS_SECRET_ACCESS_KEY
+}
+
+func (s S3Store) client() *s3.Client {
+	opts := s3.Options{
+		Region: cmp.Or(s.Region, "us-east-1"),
+		Credentials: credentials.NewStaticCredentialsProvider(
+			cmp.Or(s.AccessKey, os.Getenv("AWS_ACCESS_KEY_ID")),
+			cmp.Or(s.SecretKey, os.Getenv("AWS_SECRET_ACCESS_KEY")),
+			os.Getenv("AWS_SESSION_TOKEN")),
+	}
+	if s.Endpoint != "" {
+		opts.BaseEndpoint = aws.String(s.Endpoint)
+		opts.UsePathStyle = true // MinIO and Ceph don't serve buckets as subdomains by default
+	}
+	return s3.New(opts)
+}
+
+// targetHash identifies a target in file and object names without revealing it
+func targetHash(target string) string {
+	sum := sha256.Sum256([]byte(target))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// scanKey is the object key scan is stored under
+func scanKey(scan ScanResult) string {
+	return s3ScanPrefix + fileTimestamp(scan.DateTime) + "_" + targetHash(scan.Target) + ".json"
+}
+
+// Save uploads scan as a new object
+func (s S3Store) Save(scan ScanResult) error {
+	key := scanKey(scan)
+	data, err := encodeScan(scan, key)
+	if err != nil {
+		return err
+	}
+	_, err = s.client().PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("uploading s3://%s/%s: %v", s.Bucket, key, err)
+	}
+	return nil
+}
+
+// Latest downloads the newest stored scan
+func (s S3Store) Latest() (ScanResult, error) {
+	keys, err := s.List()
+	if err != nil {
+		return ScanResult{}, err
+	}
+	if len(keys) == 0 {
+		return ScanResult{}, fmt.Errorf("no scans in s3://%s/%s: %w", s.Bucket, s3ScanPrefix, os.ErrNotExist)
+	}
+	return s.Load(keys[len(keys)-1])
+}
+
+// List returns the keys of every object under scans/, oldest first
+func (s S3Store) List() ([]string, error) {
+	var keys []string
+	pages := s3.NewListObjectsV2Paginator(s.client(), &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(s3ScanPrefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("listing s3://%s/%s: %v", s.Bucket, s3ScanPrefix, err)
+		}
+		for _, obj := range page.Contents {
+			if key := aws.ToString(obj.Key); strings.HasSuffix(key, ".json") {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys) // Keys start with the scan time, so this is chronological
+	return keys, nil
+}
+
+// Load downloads the scan stored under key
+func (s S3Store) Load(key string) (ScanResult, error) {
+	out, err := s.client().GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("downloading s3://%s/%s: %v", s.Bucket, key, err)
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	return decodeScan(data, key)
+}
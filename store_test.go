@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3Server serves PutObject, GetObject and ListObjectsV2 for one bucket
+// from memory, with path-style addressing as MinIO uses
+type fakeS3Server struct {
+	bucket  string
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+// listBucketResult is the part of a ListObjectsV2 response S3Store reads
+type listBucketResult struct {
+	XMLName     xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name        string
+	Prefix      string
+	KeyCount    int
+	IsTruncated bool
+	Contents    []struct{ Key string }
+}
+
+func (s *fakeS3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.bucket {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPut && key != "":
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = data
+	case r.Method == http.MethodGet && key != "":
+		data, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		result := listBucketResult{Name: s.bucket, Prefix: r.URL.Query().Get("prefix")}
+		// Listed in the reverse order, to check S3Store sorts them itself
+		keys := make([]string, 0, len(s.objects))
+		for k := range s.objects {
+			if strings.HasPrefix(k, result.Prefix) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+		for _, k := range keys {
+			result.Contents = append(result.Contents, struct{ Key string }{k})
+		}
+		result.KeyCount = len(keys)
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(result)
+	default:
+		http.Error(w, "NotImplemented", http.StatusNotImplemented)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3Server{bucket: "scans", objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	store := S3Store{Bucket: "scans", Endpoint: server.URL, AccessKey: "test", SecretKey: "test"}
+
+	if _, err := store.Latest(); err == nil {
+		t.Error("Latest succeeded on an empty bucket")
+	}
+
+	for _, datetime := range []string{"2024-05-02T10:00:00Z", "2024-05-01T10:00:00Z"} {
+		scan := ScanResult{Version: currentScanVersion, DateTime: datetime, Target: "10.0.0.0/24",
+			Ports: map[string][]string{"10.0.0.1": {"22/tcp [open] (ssh)"}}}
+		if err := store.Save(scan); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	fake.objects["scans/notes.txt"] = []byte("not a scan")
+
+	keys, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := targetHash("10.0.0.0/24")
+	want := []string{"scans/20240501T100000_" + hash + ".json", "scans/20240502T100000_" + hash + ".json"}
+	if strings.Join(keys, " ") != strings.Join(want, " ") {
+		t.Errorf("List() = %v, want %v", keys, want)
+	}
+
+	latest, err := store.Latest()
+	if err != nil || latest.DateTime != "2024-05-02T10:00:00Z" || len(latest.Ports["10.0.0.1"]) != 1 {
+		t.Errorf("Latest() = %+v, %v; want the 2024-05-02 scan", latest, err)
+	}
+	if _, err := store.Load("scans/missing.json"); err == nil {
+		t.Error("Load of a missing key succeeded")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Stress test scan settings
+const (
+	stressCommand = "nmap -p 1-1024"
+	stressTarget  = "127.0.0.1"
+)
+
+// StressReport summarises a stress test run
+type StressReport struct {
+	Scans      int
+	Failures   int
+	Wall       time.Duration
+	Mean       time.Duration
+	P95        time.Duration
+	P99        time.Duration
+	Throughput float64       // Successful scans per second
+	PeakHeap   uint64        // Peak heap in use, bytes
+	FileIO     time.Duration // Total time spent writing results to disk
+}
+
+// RunStressTest runs n concurrent scans of the loopback address and measures
+// scan durations, throughput, peak memory and result file write time. Results
+// are written to a temporary directory, never to scan_data.
+func RunStressTest(n int) (StressReport, error) {
+	report := StressReport{Scans: n}
+	if n < 1 {
+		return report, fmt.Errorf("stress test needs at least one scan")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "porthunter-stress-")
+	if err != nil {
+		return report, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Loopback is the whole point here
+	prevAllow := allowLoopback
+	allowLoopback = true
+	defer func() { allowLoopback = prevAllow }()
+
+	// Sample memory while the scans run
+	stopSampling := make(chan struct{})
+	samplerDone := make(chan struct{})
+	go func() {
+		defer close(samplerDone)
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		var m runtime.MemStats
+		for {
+			runtime.ReadMemStats(&m)
+			if m.HeapInuse > report.PeakHeap {
+				report.PeakHeap = m.HeapInuse
+			}
+			select {
+			case <-stopSampling:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var (
+		mu        sync.Mutex
+		durations []time.Duration
+		wg        sync.WaitGroup
+	)
+	started := time.Now()
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			scanStart := time.Now()
+			result, err := RunScanWithOptions(stressCommand, stressTarget, ScanOptions{Quiet: true})
+			elapsed := time.Since(scanStart)
+
+			var ioTime time.Duration
+			if err == nil {
+				ioStart := time.Now()
+				data, _ := json.MarshalIndent(result, "", "  ")
+				err = os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("scan_%d.json", i)), data, 0644)
+				ioTime = time.Since(ioStart)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.FileIO += ioTime
+			if err != nil {
+				report.Failures++
+				logger.Debug("stress scan %d failed: %v", i, err)
+				return
+			}
+			durations = append(durations, elapsed)
+		}(i)
+	}
+	wg.Wait()
+	report.Wall = time.Since(started)
+
+	close(stopSampling)
+	<-samplerDone
+
+	if len(durations) == 0 {
+		return report, fmt.Errorf("all %d scans failed", n)
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	report.Mean = total / time.Duration(len(durations))
+	report.P95 = percentile(durations, 0.95)
+	report.P99 = percentile(durations, 0.99)
+	report.Throughput = float64(len(durations)) / report.Wall.Seconds()
+	return report, nil
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+// PrintStressReport writes a human-readable stress test summary
+func PrintStressReport(r StressReport, w io.Writer) {
+	fmt.Fprintf(w, "\n--- Stress Test: %d concurrent scans (%s %s) ---\n\n", r.Scans, stressCommand, stressTarget)
+	fmt.Fprintf(w, "  Succeeded:   %d\n", r.Scans-r.Failures)
+	fmt.Fprintf(w, "  Failed:      %d\n", r.Failures)
+	fmt.Fprintf(w, "  Wall time:   %s\n", r.Wall.Round(time.Millisecond))
+	fmt.Fprintf(w, "  Throughput:  %.2f scans/s\n", r.Throughput)
+	fmt.Fprintf(w, "  Mean:        %s\n", r.Mean.Round(time.Millisecond))
+	fmt.Fprintf(w, "  p95:         %s\n", r.P95.Round(time.Millisecond))
+	fmt.Fprintf(w, "  p99:         %s\n", r.P99.Round(time.Millisecond))
+	fmt.Fprintf(w, "  Peak heap:   %.1f MiB\n", float64(r.PeakHeap)/(1<<20))
+	fmt.Fprintf(w, "  File I/O:    %s total\n", r.FileIO.Round(time.Microsecond))
+}
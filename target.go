@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxCIDRHostBits limits CIDR targets to at most a /16 (IPv4) or /112 (IPv6)
+// so a typo cannot scan a huge slice of the internet
+const maxCIDRHostBits = 16
+
+// allowLoopback permits scanning loopback targets; set from -allow-loopback
+var allowLoopback bool
+
+// nmapRangeRe matches nmap's IPv4 octet range syntax, e.g. "10.0.0.1-50" or "192.168.*.1"
+var nmapRangeRe = regexp.MustCompile(`^[0-9*,\-]+(\.[0-9*,\-]+){3}$`)
+
+// specialRanges are special-purpose networks that are almost never meant to be
+// scanned. Targets in them are allowed but produce a warning.
+var specialRanges = []struct {
+	network *net.IPNet
+	reason  string
+}{
+	{mustCIDR("169.254.169.254/32"), "the cloud instance metadata endpoint, which can disclose credentials"},
+	{mustCIDR("fd00:ec2::254/128"), "the cloud instance metadata endpoint, which can disclose credentials"},
+	{mustCIDR("169.254.0.0/16"), "the link-local range, which is not routable"},
+	{mustCIDR("fe80::/10"), "the link-local range, which is not routable"},
+	{mustCIDR("0.0.0.0/8"), "the \"this network\" range, which is not a valid destination"},
+	{mustCIDR("100.64.0.0/10"), "the carrier-grade NAT shared address space"},
+	{mustCIDR("192.0.0.0/24"), "the IETF protocol assignments range"},
+	{mustCIDR("192.0.2.0/24"), "the TEST-NET-1 documentation range"},
+	{mustCIDR("198.51.100.0/24"), "the TEST-NET-2 documentation range"},
+	{mustCIDR("203.0.113.0/24"), "the TEST-NET-3 documentation range"},
+	{mustCIDR("2001:db8::/32"), "the IPv6 documentation range"},
+	{mustCIDR("198.18.0.0/15"), "the network benchmarking range"},
+	{mustCIDR("224.0.0.0/4"), "a multicast range"},
+	{mustCIDR("ff00::/8"), "a multicast range"},
+	{mustCIDR("240.0.0.0/4"), "the reserved 240.0.0.0/4 range"},
+}
+
+func mustCIDR(s string) *net.IPNet {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// specialRangeReason returns why network falls in a special-purpose range, if
+// it does. The most specific match (the metadata endpoint) is listed first.
+func specialRangeReason(network *net.IPNet) (string, bool) {
+	for _, r := range specialRanges {
+		if r.network.Contains(network.IP) || network.Contains(r.network.IP) {
+			return r.reason, true
+		}
+	}
+	return "", false
+}
+
+// warnSpecialTarget logs a warning when network is in a special-purpose range
+func warnSpecialTarget(target string, network *net.IPNet) {
+	if reason, ok := specialRangeReason(network); ok {
+		logger.Warn("target %s overlaps %s", target, reason)
+	}
+}
+
+// TargetValidationError explains why a scan target was rejected
+type TargetValidationError struct {
+	Target string
+	Reason string
+}
+
+func (e *TargetValidationError) Error() string {
+	return fmt.Sprintf("invalid target %q: %s", e.Target, e.Reason)
+}
+
+// ValidateTarget checks a target before it is handed to nmap: it must be a
+// valid IP, CIDR, nmap range or resolvable hostname, must not be the broadcast
+// address, must not be loopback (unless allowed), must not be on the exclusion
+// list, and CIDRs and nmap octet ranges must cover a /16 (65,536 addresses) or less.
+// Targets in special-purpose ranges such as the cloud metadata endpoint,
+// link-local or TEST-NET are allowed with a warning.
+func ValidateTarget(target string) error {
+	return validateTarget(target, excludeList)
+}
+
+// validateTarget is ValidateTarget with the exclusion list given
+func validateTarget(target string, exclusions []string) error {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return &TargetValidationError{Target: target, Reason: "target cannot be empty"}
+	}
+	if rule, ok := matchExclusion(exclusions, target); ok {
+		return &ExcludedTargetError{Target: target, Rule: rule}
+	}
+
+	invalid := func(format string, args ...any) error {
+		return &TargetValidationError{Target: target, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	if strings.Contains(target, "/") {
+		ip, network, err := net.ParseCIDR(target)
+		if err != nil {
+			return invalid("not a valid CIDR: %v", err)
+		}
+		ones, bits := network.Mask.Size()
+		if bits-ones > maxCIDRHostBits {
+			return invalid("prefix /%d is too large, use /%d or smaller", ones, bits-maxCIDRHostBits)
+		}
+		if ip.IsLoopback() && !allowLoopback {
+			return invalid("loopback range (use -allow-loopback to permit)")
+		}
+		warnSpecialTarget(target, network)
+		return nil
+	}
+
+	if ip := net.ParseIP(target); ip != nil {
+		return checkTargetIP(target, ip, exclusions)
+	}
+
+	if nmapRangeRe.MatchString(target) {
+		size, err := nmapRangeSize(target)
+		if err != nil {
+			return invalid("%v", err)
+		}
+		if size > 1<<maxCIDRHostBits {
+			return invalid("range covers %d addresses, at most %d (a /16) are allowed", size, 1<<maxCIDRHostBits)
+		}
+		return nil
+	}
+
+	addrs, err := net.LookupHost(target)
+	if err != nil {
+		return invalid("not an IP address or resolvable hostname: %v", err)
+	}
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil {
+			if err := checkTargetIP(target, ip, exclusions); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// nmapRangeSize counts the addresses an nmap octet range such as
+// "10.0.1-5,9.*" covers: the product of the values each octet allows
+func nmapRangeSize(target string) (int, error) {
+	size := 1
+	for _, octet := range strings.Split(target, ".") {
+		values, err := nmapOctetValues(octet)
+		if err != nil {
+			return 0, err
+		}
+		count := 0
+		for _, set := range values {
+			if set {
+				count++
+			}
+		}
+		size *= count
+	}
+	return size, nil
+}
+
+// nmapRangeBounds returns the lowest and highest addresses of an nmap octet
+// range. Every address in the range lies between them.
+func nmapRangeBounds(target string) (lo, hi netip.Addr, err error) {
+	octets := strings.Split(target, ".")
+	if len(octets) != 4 {
+		return lo, hi, fmt.Errorf("%q is not an IPv4 octet range", target)
+	}
+	var low, high [4]byte
+	for i, octet := range octets {
+		values, err := nmapOctetValues(octet)
+		if err != nil {
+			return lo, hi, err
+		}
+		first, last := -1, -1
+		for v, set := range values {
+			if set {
+				if first < 0 {
+					first = v
+				}
+				last = v
+			}
+		}
+		low[i], high[i] = byte(first), byte(last)
+	}
+	return netip.AddrFrom4(low), netip.AddrFrom4(high), nil
+}
+
+// nmapOctetValues returns the values one octet of an nmap range allows: a
+// comma-separated list of numbers, "a-b" ranges (open-ended as "-b" or "a-")
+// and "*"
+func nmapOctetValues(octet string) ([256]bool, error) {
+	var values [256]bool
+	for _, part := range strings.Split(octet, ",") {
+		lo, hi := 0, 255
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			from, to, _ := strings.Cut(part, "-")
+			var err error
+			if from != "" {
+				if lo, err = strconv.Atoi(from); err != nil {
+					return values, fmt.Errorf("bad octet range %q", part)
+				}
+			}
+			if to != "" {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return values, fmt.Errorf("bad octet range %q", part)
+				}
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return values, fmt.Errorf("bad octet %q", part)
+			}
+			lo, hi = n, n
+		}
+		if lo < 0 || hi > 255 || lo > hi {
+			return values, fmt.Errorf("octet range %q is outside 0-255", part)
+		}
+		for v := lo; v <= hi; v++ {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// checkTargetIP applies the per-address rules to a single IP
+func checkTargetIP(target string, ip net.IP, exclusions []string) error {
+	if rule, ok := matchExclusion(exclusions, ip.String()); ok {
+		return &ExcludedTargetError{Target: target, Rule: rule}
+	}
+	if ip.Equal(net.IPv4bcast) {
+		return &TargetValidationError{Target: target, Reason: "broadcast address"}
+	}
+	if ip.IsLoopback() && !allowLoopback {
+		return &TargetValidationError{Target: target, Reason: "loopback address (use -allow-loopback to permit)"}
+	}
+	bits := 8 * len(ip)
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 32
+	}
+	warnSpecialTarget(target, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	return nil
+}
+
+// FormatTarget prepares space-separated targets for nmap's command line.
+// nmap is run without a shell, so IPv6 addresses need no quoting.
+//
+// This is the opposite of wrapping IPv6 addresses in brackets: nmap resolves
+// "[2001:db8::1]" as a hostname and fails, so brackets from URL-style
+// addresses are removed instead. The result doesn't depend on -6 being in the
+// command, so FormatTarget takes only the targets, not the command; adding -6
+// when it is missing is left to ipv6ScanArgs.
+func FormatTarget(target string) string {
+	fields := strings.Fields(target)
+	for i, t := range fields {
+		if inner, ok := strings.CutPrefix(t, "["); ok {
+			addr, suffix, found := strings.Cut(inner, "]")
+			if found && isIPv6Target(addr) {
+				fields[i] = addr + suffix // Keeps a "/64" prefix length after the bracket
+			}
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// isIPv6Target reports whether target is an IPv6 address or CIDR, including
+// link-local addresses with a zone such as "fe80::1%eth0"
+func isIPv6Target(target string) bool {
+	host, _, _ := strings.Cut(target, "/")
+	addr, err := netip.ParseAddr(host)
+	return err == nil && addr.Is6() && !addr.Is4In6()
+}
+
+// ipv6ScanArgs adds -6 to args when every target is IPv6 and it is missing,
+// since nmap only scans IPv6 with it. nmap can't mix IPv4 and IPv6 targets in
+// one run, so a mix is an error.
+func ipv6ScanArgs(args, targets []string) ([]string, error) {
+	if slices.Contains(args, "-6") {
+		return args, nil
+	}
+	v6 := 0
+	for _, t := range targets {
+		if isIPv6Target(t) {
+			v6++
+		}
+	}
+	switch {
+	case v6 == 0:
+		return args, nil
+	case v6 < len(targets):
+		return nil, errors.New("nmap can't scan IPv4 and IPv6 targets in one run, use -batch to scan them separately")
+	}
+	logger.Info("Adding -6 to the nmap command for IPv6 targets")
+	return append(args, "-6"), nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestValidateTargetRanges(t *testing.T) {
+	tests := []struct {
+		target string
+		ok     bool
+	}{
+		{"10.0.0.1-50", true},
+		{"192.168.*.1", true},
+		{"10.0.*.*", true},    // 65,536 addresses, the same as a /16
+		{"10.0-1.*.*", false}, // 131,072
+		{"*.*.*.*", false},    // All of IPv4
+		{"0-255.0-255.0-255.0-255", false},
+		{"1-255.*.*.*", false},
+		{"10.0.1,3,5.-10", true},
+		{"10.0.0.250-300", false}, // Not an octet
+		{"10.0.0.50-10", false},
+	}
+	for _, tt := range tests {
+		err := ValidateTarget(tt.target)
+		if tt.ok && err != nil {
+			t.Errorf("ValidateTarget(%q) = %v, want nil", tt.target, err)
+		}
+		var verr *TargetValidationError
+		if !tt.ok && !errors.As(err, &verr) {
+			t.Errorf("ValidateTarget(%q) = %v, want a TargetValidationError", tt.target, err)
+		}
+	}
+}
+
+func TestNmapRangeSize(t *testing.T) {
+	tests := map[string]int{
+		"10.0.0.1":       1,
+		"10.0.0.1-50":    50,
+		"10.0.0.*":       256,
+		"10.0.1,3,5.-10": 3 * 11,
+		"10.0.0.250-":    6,
+		"10.0.0.1,1,1-2": 2,
+		"*.*.*.*":        1 << 32,
+	}
+	for target, want := range tests {
+		if got, err := nmapRangeSize(target); err != nil || got != want {
+			t.Errorf("nmapRangeSize(%q) = %d, %v, want %d", target, got, err, want)
+		}
+	}
+}
+
+func TestFormatTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"::1", "::1"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"[::1]", "::1"},
+		{"[2001:db8::1]", "2001:db8::1"},
+		{"[2001:db8::]/64", "2001:db8::/64"},
+		{"fe80::1%eth0", "fe80::1%eth0"},
+		{"  [::1]   10.0.0.1 ", "::1 10.0.0.1"},
+		{"[example.com]", "[example.com]"}, // Not an address; ValidateTarget rejects it
+	}
+	for _, tt := range tests {
+		if got := FormatTarget(tt.target); got != tt.want {
+			t.Errorf("FormatTarget(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+// TestIPv6ScanArgs checks the nmap arguments IPv6 targets end up with
+func TestIPv6ScanArgs(t *testing.T) {
+	defer func(saved bool) { allowLoopback = saved }(allowLoopback)
+	allowLoopback = true // For ::1
+
+	for _, target := range []string{"::1", "2001:db8::1", "[2001:db8::1]"} {
+		targets := []string{FormatTarget(target)}
+		args, err := ipv6ScanArgs([]string{"-sT", "-p", "22"}, targets)
+		if err != nil {
+			t.Fatalf("%s: %v", target, err)
+		}
+		if !slices.Contains(args, "-6") {
+			t.Errorf("%s: args %v, want -6 added", target, args)
+		}
+		if err := ValidateTarget(targets[0]); err != nil {
+			t.Errorf("%s: formatted as %q, which ValidateTarget rejects: %v", target, targets[0], err)
+		}
+	}
+
+	if _, err := ipv6ScanArgs([]string{"-sT"}, []string{"::1", "10.0.0.1"}); err == nil {
+		t.Error("mixed IPv4 and IPv6 targets were accepted")
+	}
+}
+
+func TestMatchExclusion(t *testing.T) {
+	rules := []string{"10.0.0.0/16", "192.168.1.5", "*.prod.example.com", "2001:db8::/32"}
+	tests := []struct {
+		target string
+		rule   string // "" if not excluded
+	}{
+		{"10.0.3.7", "10.0.0.0/16"},
+		{"10.1.0.1", ""},
+		{"10.0.4.0/24", "10.0.0.0/16"}, // Inside the excluded range
+		{"10.0.0.0/8", ""},             // Only partly excluded
+		{"192.168.1.5", "192.168.1.5"},
+		{" 192.168.1.5 ", "192.168.1.5"},
+		{"192.168.1.50", ""},
+		{"db1.PROD.example.com", "*.prod.example.com"},
+		{"prod.example.com", ""},
+		{"2001:db8::10", "2001:db8::/32"},
+		{"2001:db9::10", ""},
+	}
+	for _, tt := range tests {
+		rule, ok := matchExclusion(rules, tt.target)
+		if rule != tt.rule || ok != (tt.rule != "") {
+			t.Errorf("matchExclusion(%q) = %q, %v; want %q", tt.target, rule, ok, tt.rule)
+		}
+	}
+
+	var skip *ExcludedTargetError
+	if err := validateTarget("10.0.3.7", rules); !errors.As(err, &skip) || skip.Rule != "10.0.0.0/16" {
+		t.Errorf("validateTarget returned %v, want the target excluded by 10.0.0.0/16", err)
+	}
+	if err := validateTarget("10.0.3.7", nil); err != nil {
+		t.Errorf("validateTarget with no exclusions returned %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// templateHelpers are the functions available to user-written templates
+// (command templates and -output-template reports): Sprig's text template
+// functions, e.g. {{ .DateTime | date "Jan 2, 2006" }}. Sprig's date
+// functions take a time.Time or Unix seconds and format any other value as
+// the current time, so date and ago are replaced with versions that also
+// take RFC 3339 strings such as ScanResult.DateTime.
+var templateHelpers = func() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	funcs["date"] = func(layout string, t any) string { return toTime(t).Format(layout) }
+	funcs["ago"] = func(t any) string { return formatElapsedTime(time.Since(toTime(t)), false) }
+	return funcs
+}()
+
+// RegisterTemplateHelpers adds the template helper functions to t. It must be
+// called before t parses any text that uses them.
+func RegisterTemplateHelpers(t *template.Template) *template.Template {
+	return t.Funcs(templateHelpers)
+}
+
+// toTime converts a time.Time, *time.Time, Unix seconds or RFC 3339 string to
+// a time. Anything else is the zero time.
+func toTime(v any) time.Time {
+	switch t := v.(type) {
+	case time.Time:
+		return t
+	case *time.Time:
+		if t != nil {
+			return *t
+		}
+	case int64:
+		return time.Unix(t, 0)
+	case int:
+		return time.Unix(int64(t), 0)
+	case string:
+		return scanTime(t)
+	}
+	return time.Time{}
+}
+
+// templateReport is the data -output-template templates are rendered with:
+// the scan's fields, such as .DateTime and .Ports, and the diff as .Diff
+type templateReport struct {
+	ScanResult
+	Diff DiffReport
+}
+
+// writeTemplateReport renders the template file tmplPath with the scan and
+// its diff, writing to path, or to stdout if path is empty
+func writeTemplateReport(tmplPath, path string, scan ScanResult, diff DiffReport) error {
+	text, err := os.ReadFile(tmplPath)
+	if err != nil {
+		return err
+	}
+	t, err := RegisterTemplateHelpers(template.New(tmplPath)).Parse(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid template: %v", err)
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, templateReport{ScanResult: scan, Diff: diff}); err != nil {
+		return err
+	}
+	if path == "" {
+		_, err := fmt.Print(sb.String())
+		return err
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
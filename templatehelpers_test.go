@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestTemplateHelpers(t *testing.T) {
+	scan := templateReport{ScanResult: ScanResult{
+		DateTime: "2024-05-01T10:00:00Z",
+		Target:   "10.0.0.0/24",
+		Ports:    map[string][]string{"10.0.0.1": {"22/tcp [open] (ssh)", "80/tcp [open] (http)"}},
+	}}
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{`{{ .DateTime | date "Jan 2, 2006" }}`, "May 1, 2024"},
+		{`{{ .DateTime | toDate "2006-01-02T15:04:05Z07:00" | date "2006-01-02" }}`, "2024-05-01"},
+		{`{{ .Target | upper | replace "/" " /" }}`, "10.0.0.0 /24"},
+		{`{{ index .Ports "10.0.0.1" | join ", " }}`, "22/tcp [open] (ssh), 80/tcp [open] (http)"},
+		{`{{ .Command | default "unknown" }}`, "unknown"},
+		{`{{ list "b" "a" "b" | uniq | sortAlpha | join "," }}`, "a,b"},
+		{`{{ "http" | title }} {{ add 1 2 }} {{ "x" | repeat 3 }}`, "Http 3 xxx"},
+	}
+	for _, tt := range tests {
+		tmpl, err := RegisterTemplateHelpers(template.New("test")).Parse(tt.tmpl)
+		if err != nil {
+			t.Errorf("%s: %v", tt.tmpl, err)
+			continue
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, scan); err != nil {
+			t.Errorf("%s: %v", tt.tmpl, err)
+			continue
+		}
+		if sb.String() != tt.want {
+			t.Errorf("%s = %q, want %q", tt.tmpl, sb.String(), tt.want)
+		}
+	}
+}
+
+func TestTemplateHelpersAgo(t *testing.T) {
+	hourAgo := time.Now().Add(-time.Hour)
+	for _, v := range []any{hourAgo, hourAgo.Format(time.RFC3339), hourAgo.Unix()} {
+		tmpl := template.Must(RegisterTemplateHelpers(template.New("ago")).Parse(`{{ ago . }}`))
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, v); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(sb.String(), "hour") {
+			t.Errorf("ago(%T) = %q, want about an hour", v, sb.String())
+		}
+	}
+}
+
+func TestWriteTemplateReport(t *testing.T) {
+	dir := t.TempDir()
+	tmplPath, outPath := filepath.Join(dir, "report.tmpl"), filepath.Join(dir, "report.txt")
+	text := `Scan of {{ .Target }} on {{ .DateTime | date "2006-01-02" }}: {{ len .Diff.Hosts }} changed hosts`
+	if err := os.WriteFile(tmplPath, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scan := ScanResult{DateTime: "2024-05-01T10:00:00Z", Target: "10.0.0.1"}
+	diff := DiffReport{Hosts: []HostDiff{{IP: "10.0.0.1", Added: []string{"22/tcp [open] (ssh)"}}}}
+	if err := writeTemplateReport(tmplPath, outPath, scan, diff); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Scan of 10.0.0.1 on 2024-05-01: 1 changed hosts"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceHop is one hop of an nmap --traceroute path. IP is empty for hops
+// that did not answer.
+type TraceHop struct {
+	HopNum   int     `json:"hop"`
+	IP       string  `json:"ip,omitempty"`
+	RTT      float64 `json:"rtt,omitempty"` // Milliseconds
+	Hostname string  `json:"hostname,omitempty"`
+}
+
+var (
+	// "1   0.45 ms  192.168.1.1" or "2   12.30 ms router.isp.net (203.0.113.1)"
+	traceHopRe = regexp.MustCompile(`^(\d+)\s+(?:([\d.]+) ms|--)\s+(?:(\S+) \(([^)]+)\)|(\S+))$`)
+	// "3   ..." or "3   ... 5" for hops that did not answer
+	traceSilentRe = regexp.MustCompile(`^(\d+)\s+\.\.\.(?:\s+(\d+))?$`)
+	// "-   Hops 1-3 are the same as for 10.0.0.2"
+	traceSameAsRe = regexp.MustCompile(`^-\s+Hops? (\d+)(?:-(\d+))? (?:is|are) the same as for (\S+)$`)
+)
+
+// ParseTraceroute extracts the TRACEROUTE section for ip from nmap's normal
+// output. Hops nmap abbreviates as "the same as for" another host are copied
+// from that host's route.
+func ParseTraceroute(output string, ip string) []TraceHop {
+	return parseTraceroute(output, ip, 0)
+}
+
+func parseTraceroute(output, ip string, depth int) []TraceHop {
+	if depth > 8 {
+		return nil // nmap never chains references this far; avoid looping on bad input
+	}
+	var hops []TraceHop
+	inHost, inTrace := false, false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if m := scanReportRe.FindStringSubmatch(line); m != nil {
+			if inHost {
+				break
+			}
+			inHost = m[1] == ip
+			continue
+		}
+		if !inHost {
+			continue
+		}
+		if strings.HasPrefix(line, "TRACEROUTE") {
+			inTrace = true
+			continue
+		}
+		if !inTrace || strings.HasPrefix(line, "HOP") {
+			continue
+		}
+
+		if m := traceHopRe.FindStringSubmatch(line); m != nil {
+			hop := TraceHop{Hostname: m[3], IP: m[4]}
+			hop.HopNum, _ = strconv.Atoi(m[1])
+			hop.RTT, _ = strconv.ParseFloat(m[2], 64)
+			if hop.IP == "" {
+				hop.IP = m[5]
+			}
+			hops = append(hops, hop)
+		} else if m := traceSilentRe.FindStringSubmatch(line); m != nil {
+			from, _ := strconv.Atoi(m[1])
+			to := from
+			if m[2] != "" {
+				to, _ = strconv.Atoi(m[2])
+			}
+			for n := from; n <= to; n++ {
+				hops = append(hops, TraceHop{HopNum: n})
+			}
+		} else if m := traceSameAsRe.FindStringSubmatch(line); m != nil {
+			from, _ := strconv.Atoi(m[1])
+			to := from
+			if m[2] != "" {
+				to, _ = strconv.Atoi(m[2])
+			}
+			for _, hop := range parseTraceroute(output, m[3], depth+1) {
+				if hop.HopNum >= from && hop.HopNum <= to {
+					hops = append(hops, hop)
+				}
+			}
+		} else {
+			inTrace = false // The section ends at the first line that is not a hop
+		}
+	}
+	return hops
+}
+
+// applyTraceroutes copies the routes in nmap's text output onto the scan's hosts
+func applyTraceroutes(scan *ScanResult, output string) {
+	if !strings.Contains(output, "TRACEROUTE") {
+		return
+	}
+	for ip, host := range scan.Hosts {
+		if hops := ParseTraceroute(output, ip); len(hops) > 0 {
+			host.Traceroute = hops
+			scan.Hosts[ip] = host
+		}
+	}
+}
+
+// RouteChange is a host reached over a different path than before, which may
+// mean a topology change or, for external hosts, a BGP hijack. A host that
+// suddenly answers much more slowly is reported too, with only the latencies
+// set unless its traceroute changed as well.
+type RouteChange struct {
+	IP      string     `json:"ip"`
+	OldHops []TraceHop `json:"old_hops"`
+	NewHops []TraceHop `json:"new_hops"`
+
+	OldLatency time.Duration `json:"old_latency,omitempty"`
+	NewLatency time.Duration `json:"new_latency,omitempty"`
+}
+
+func (c RouteChange) String() string {
+	if c.NewLatency != 0 {
+		s := fmt.Sprintf("Latency to %s rose from %s to %s, it may be reached over a longer path", c.IP, c.OldLatency, c.NewLatency)
+		if len(c.OldHops) > 0 && len(c.NewHops) > 0 && routeChanged(c.OldHops, c.NewHops) {
+			s += ": " + routePath(c.NewHops)
+		}
+		return s
+	}
+	if len(c.OldHops) != len(c.NewHops) {
+		return fmt.Sprintf("Route to %s changed from %d to %d hops: %s", c.IP, len(c.OldHops), len(c.NewHops), routePath(c.NewHops))
+	}
+	var changed []string
+	for i, hop := range c.NewHops {
+		if old := c.OldHops[i]; old.IP != "" && hop.IP != "" && old.IP != hop.IP {
+			changed = append(changed, fmt.Sprintf("hop %d %s (was %s)", hop.HopNum, hop.IP, old.IP))
+		}
+	}
+	return fmt.Sprintf("Route to %s changed: %s", c.IP, strings.Join(changed, ", "))
+}
+
+// DiffRoutes lists hosts whose route has a different number of hops or
+// different intermediate hops, or whose latency rose latencyChangeFactor
+// times. Hosts traced in only one scan are skipped, as are hops that did not
+// answer in either scan.
+func DiffRoutes(old, new ScanResult) []RouteChange {
+	var changes []RouteChange
+	for _, ip := range sortedHostKeys(new.Hosts) {
+		n, o := new.Hosts[ip], old.Hosts[ip]
+		traced := len(n.Traceroute) > 0 && len(o.Traceroute) > 0 && routeChanged(o.Traceroute, n.Traceroute)
+		slower := latencyJumped(o.Latency, n.Latency)
+		if !traced && !slower {
+			continue
+		}
+		change := RouteChange{IP: ip, OldHops: o.Traceroute, NewHops: n.Traceroute}
+		if slower {
+			change.OldLatency, change.NewLatency = o.Latency, n.Latency
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func routeChanged(old, new []TraceHop) bool {
+	if len(old) != len(new) {
+		return true
+	}
+	for i := range new {
+		if old[i].IP != "" && new[i].IP != "" && old[i].IP != new[i].IP {
+			return true
+		}
+	}
+	return false
+}
+
+// routePath formats hops as "10.0.0.1 > * > 203.0.113.1"
+func routePath(hops []TraceHop) string {
+	parts := make([]string, len(hops))
+	for i, hop := range hops {
+		parts[i] = cmp.Or(hop.IP, "*")
+	}
+	return strings.Join(parts, " > ")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// tracerouteOutput is nmap --traceroute output for two hosts, the second
+// sharing its first hops with the first
+const tracerouteOutput = `Starting Nmap 7.94 ( https://nmap.org ) at 2024-05-01 10:00 UTC
+Nmap scan report for 203.0.113.10
+Host is up (0.012s latency).
+Not shown: 999 closed tcp ports (conn-refused)
+PORT   STATE SERVICE
+80/tcp open  http
+
+TRACEROUTE (using port 80/tcp)
+HOP RTT      ADDRESS
+1   0.45 ms  192.168.1.1
+2   ...
+3   12.30 ms core1.isp.net (198.51.100.1)
+4   12.80 ms 203.0.113.10
+
+Nmap scan report for www.example.com (203.0.113.20)
+Host is up (0.013s latency).
+PORT    STATE SERVICE
+443/tcp open  https
+
+TRACEROUTE (using port 443/tcp)
+HOP RTT      ADDRESS
+-   Hops 1-3 are the same as for 203.0.113.10
+4   13.10 ms 203.0.113.20
+
+Nmap done: 2 IP addresses (2 hosts up) scanned in 5.10 seconds
+`
+
+func TestParseTraceroute(t *testing.T) {
+	hops := ParseTraceroute(tracerouteOutput, "203.0.113.10")
+	want := []TraceHop{
+		{HopNum: 1, IP: "192.168.1.1", RTT: 0.45},
+		{HopNum: 2},
+		{HopNum: 3, IP: "198.51.100.1", RTT: 12.30, Hostname: "core1.isp.net"},
+		{HopNum: 4, IP: "203.0.113.10", RTT: 12.80},
+	}
+	if len(hops) != len(want) {
+		t.Fatalf("got %+v, want %+v", hops, want)
+	}
+	for i := range want {
+		if hops[i] != want[i] {
+			t.Errorf("hop %d: got %+v, want %+v", i+1, hops[i], want[i])
+		}
+	}
+
+	// Hops 1-3 are copied from the first host's route
+	hops = ParseTraceroute(tracerouteOutput, "203.0.113.20")
+	if routePath(hops) != "192.168.1.1 > * > 198.51.100.1 > 203.0.113.20" {
+		t.Errorf("shared route parsed as %s", routePath(hops))
+	}
+
+	if hops := ParseTraceroute(tracerouteOutput, "203.0.113.99"); hops != nil {
+		t.Errorf("unscanned host has hops %+v", hops)
+	}
+}
+
+func TestDiffRoutes(t *testing.T) {
+	route := func(ips ...string) []TraceHop {
+		hops := make([]TraceHop, len(ips))
+		for i, ip := range ips {
+			hops[i] = TraceHop{HopNum: i + 1, IP: ip}
+		}
+		return hops
+	}
+	scan := func(hops []TraceHop) ScanResult {
+		return ScanResult{Hosts: map[string]HostResult{"203.0.113.10": {IP: "203.0.113.10", Traceroute: hops}}}
+	}
+	base := route("192.168.1.1", "198.51.100.1", "203.0.113.10")
+
+	tests := []struct {
+		name     string
+		new      ScanResult
+		wantText string
+	}{
+		{"unchanged", scan(route("192.168.1.1", "198.51.100.1", "203.0.113.10")), ""},
+		{"silent hop", scan(route("192.168.1.1", "", "203.0.113.10")), ""},
+		{"not traced", scan(nil), ""},
+		{"extra hop", scan(route("192.168.1.1", "198.51.100.1", "198.51.100.7", "203.0.113.10")), "from 3 to 4 hops"},
+		{"different hop", scan(route("192.168.1.1", "192.0.2.66", "203.0.113.10")), "hop 2 192.0.2.66 (was 198.51.100.1)"},
+	}
+	for _, tt := range tests {
+		changes := DiffRoutes(scan(base), tt.new)
+		if tt.wantText == "" {
+			if len(changes) != 0 {
+				t.Errorf("%s: got %v, want no change", tt.name, changes)
+			}
+			continue
+		}
+		if len(changes) != 1 || !strings.Contains(changes[0].String(), tt.wantText) {
+			t.Errorf("%s: got %v, want one change mentioning %q", tt.name, changes, tt.wantText)
+		}
+	}
+}
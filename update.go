@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// releasesURL is the GitHub API endpoint for the latest PortHunter release
+var releasesURL = "https://api.github.com/repos/AssassinUKG/PortHunter/releases/latest"
+
+// updateChecksumsAsset is the release asset listing each binary's SHA-256, in sha256sum format
+const updateChecksumsAsset = "checksums.txt"
+
+// updateCheckInterval is how often the startup check asks GitHub for a new release
+const updateCheckInterval = 24 * time.Hour
+
+// githubRelease is the part of a GitHub release PortHunter uses
+type githubRelease struct {
+	TagName string `json:"tag_name"` // e.g. "v1.2.0"
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL returns the download URL of the asset called name
+func (r githubRelease) assetURL(name string) (string, error) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.URL, nil
+		}
+	}
+	return "", fmt.Errorf("release %s has no %s", r.TagName, name)
+}
+
+// updateAssetName is the release asset built for this platform, e.g. porthunter_linux_amd64
+func updateAssetName() string {
+	name := "porthunter_" + runtime.GOOS + "_" + runtime.GOARCH
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+var updateClient = &http.Client{Timeout: 30 * time.Second}
+
+// httpGet fetches url, failing on any status other than 200
+func httpGet(client *http.Client, url string) (*http.Response, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// LatestRelease asks GitHub for the newest PortHunter release
+func LatestRelease(client *http.Client) (githubRelease, error) {
+	var release githubRelease
+	resp, err := httpGet(client, releasesURL)
+	if err != nil {
+		return release, fmt.Errorf("checking for updates: %v", err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return release, fmt.Errorf("checking for updates: %v", err)
+	}
+	return release, nil
+}
+
+// newerThanRunning reports whether tag (e.g. "v1.2.0") is a later version
+// than this binary. Development builds have no version to compare, so never are.
+func newerThanRunning(tag string) bool {
+	return version != "dev" && compareVersions(strings.TrimPrefix(tag, "v"), strings.TrimPrefix(version, "v")) > 0
+}
+
+// releaseChecksum looks up the SHA-256 of the asset called name in the release's checksum file
+func releaseChecksum(release githubRelease, name string) (string, error) {
+	url, err := release.assetURL(updateChecksumsAsset)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpGet(updateClient, url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		// "<hex digest>  <file name>", with a '*' before the name in binary mode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", updateChecksumsAsset, name)
+}
+
+// downloadVerified downloads url into a new temporary file in dir, checking
+// its SHA-256 against want, and returns the file's path
+func downloadVerified(url, want, dir string) (string, error) {
+	resp, err := httpGet(updateClient, url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	tmp, err := os.CreateTemp(dir, ".porthunter-update-*")
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+			err = fmt.Errorf("checksum mismatch: downloaded %s, release lists %s", got, want)
+		}
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0755)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// replaceExecutable moves the binary at newPath over exe. The old binary is
+// moved aside first, as Windows won't overwrite a running executable.
+func replaceExecutable(exe, newPath string) error {
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(newPath, exe); err != nil {
+		os.Rename(old, exe)
+		return err
+	}
+	os.Remove(old) // Fails harmlessly on Windows while the old binary runs
+	return nil
+}
+
+// installRelease downloads this platform's asset from release, checks it
+// against the release's checksums and moves it over exe. A download that
+// fails the check is deleted and exe is left untouched.
+func installRelease(release githubRelease, exe string) error {
+	name := updateAssetName()
+	url, err := release.assetURL(name)
+	if err != nil {
+		return err
+	}
+	sum, err := releaseChecksum(release, name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Downloading %s %s...\n", name, release.TagName)
+	// Download next to the binary so the rename stays on one filesystem
+	newPath, err := downloadVerified(url, sum, filepath.Dir(exe))
+	if err != nil {
+		return err
+	}
+	if err := replaceExecutable(exe, newPath); err != nil {
+		os.Remove(newPath)
+		return fmt.Errorf("replacing %s: %v", exe, err)
+	}
+	return nil
+}
+
+// runUpdate implements "porthunter update": it replaces this binary with the
+// latest release, if it is newer, and re-executes it to confirm the new version
+func runUpdate() error {
+	if version == "dev" {
+		return errors.New("this is a development build; install a release to use update")
+	}
+	release, err := LatestRelease(updateClient)
+	if err != nil {
+		return err
+	}
+	if !newerThanRunning(release.TagName) {
+		fmt.Printf("PortHunter %s is up to date\n", version)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	if err := installRelease(release, exe); err != nil {
+		return err
+	}
+	fmt.Println("Updated to", release.TagName)
+	return reexec(exe, "-version")
+}
+
+// updateCheckFile records the last startup update check
+func updateCheckFile() string { return filepath.Join(scanFolder, "update_check.json") }
+
+// updateCheck is the content of updateCheckFile
+type updateCheck struct {
+	Checked time.Time `json:"checked"`
+	Latest  string    `json:"latest"` // Tag of the newest release at the time
+}
+
+// CheckForUpdate prints a notice if a newer release is available. GitHub is
+// asked at most once per updateCheckInterval; in between the last answer is
+// reused. Failures are only logged, so a machine without internet access
+// scans as normal.
+func CheckForUpdate() {
+	if version == "dev" {
+		return
+	}
+	var check updateCheck
+	if data, err := os.ReadFile(updateCheckFile()); err == nil {
+		json.Unmarshal(data, &check)
+	}
+	if time.Since(check.Checked) >= updateCheckInterval {
+		release, err := LatestRelease(&http.Client{Timeout: 3 * time.Second})
+		if err != nil {
+			logger.Debug("%v", err)
+			return
+		}
+		check = updateCheck{Checked: time.Now(), Latest: release.TagName}
+		if err := EnsureScanFolderExists(); err == nil {
+			data, _ := json.Marshal(check)
+			os.WriteFile(updateCheckFile(), data, 0644)
+		}
+	}
+	if newerThanRunning(check.Latest) {
+		fmt.Printf("PortHunter %s is available (you have %s); run \"porthunter update\" to install it\n", check.Latest, version)
+	}
+}
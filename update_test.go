@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeRelease serves a release whose asset for this platform is binary and
+// whose checksums.txt is checksums, counting asset downloads
+func fakeRelease(t *testing.T, binary, checksums string) (githubRelease, *int) {
+	downloads := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+updateChecksumsAsset, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, checksums)
+	})
+	mux.HandleFunc("/"+updateAssetName(), func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		fmt.Fprint(w, binary)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	var release githubRelease
+	release.TagName = "v9.9.9"
+	for _, name := range []string{updateChecksumsAsset, updateAssetName()} {
+		release.Assets = append(release.Assets, struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		}{name, server.URL + "/" + name})
+	}
+	return release, &downloads
+}
+
+// installedBinary writes a stand-in for the running binary to a new directory
+func installedBinary(t *testing.T) string {
+	exe := filepath.Join(t.TempDir(), "porthunter")
+	if err := os.WriteFile(exe, []byte("old binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return exe
+}
+
+// checkBinary fails unless exe holds want and is the only file in its directory
+func checkBinary(t *testing.T, exe, want string) {
+	t.Helper()
+	if data, err := os.ReadFile(exe); err != nil || string(data) != want {
+		t.Errorf("binary holds %q (%v), want %q", data, err, want)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	if len(entries) != 1 {
+		t.Errorf("%d files left next to the binary, want only the binary", len(entries))
+	}
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestInstallRelease(t *testing.T) {
+	exe := installedBinary(t)
+	release, _ := fakeRelease(t, "new binary",
+		sha256Hex("other")+"  porthunter_plan9_386\n"+sha256Hex("new binary")+" *"+updateAssetName()+"\n")
+
+	if err := installRelease(release, exe); err != nil {
+		t.Fatalf("installRelease: %v", err)
+	}
+	checkBinary(t, exe, "new binary")
+}
+
+func TestInstallReleaseRejectsChecksumMismatch(t *testing.T) {
+	exe := installedBinary(t)
+	release, _ := fakeRelease(t, "tampered binary", sha256Hex("new binary")+"  "+updateAssetName()+"\n")
+
+	if err := installRelease(release, exe); err == nil {
+		t.Fatal("installRelease accepted an asset that does not match its checksum")
+	}
+	checkBinary(t, exe, "old binary")
+}
+
+func TestInstallReleaseWithoutChecksumEntry(t *testing.T) {
+	exe := installedBinary(t)
+	release, downloads := fakeRelease(t, "new binary", sha256Hex("new binary")+"  porthunter_plan9_386\n")
+
+	if err := installRelease(release, exe); err == nil {
+		t.Fatal("installRelease installed an asset missing from checksums.txt")
+	}
+	if *downloads != 0 {
+		t.Errorf("asset downloaded %d times without a checksum to verify it against", *downloads)
+	}
+	checkBinary(t, exe, "old binary")
+}
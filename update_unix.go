@@ -0,0 +1,14 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reexec replaces this process with the binary at exe, run with args
+func reexec(exe string, args ...string) error {
+	return unix.Exec(exe, append([]string{exe}, args...), os.Environ())
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Back-off after failed scans: each consecutive failure doubles the wait,
+// and the notifiers hear about it once failureAlertAfter scans have failed
+const (
+	maxFailureBackoff = time.Hour
+	failureAlertAfter = 3
+)
+
+// AdaptiveWatcher re-runs scans on an interval that grows while nothing changes
+// and snaps back to MinInterval as soon as a scan detects changes
+type AdaptiveWatcher struct {
+	MinInterval   time.Duration
+	MaxInterval   time.Duration
+	BackoffFactor float64
+
+	// OnFailureAlert, if set, is called when failureAlertAfter scans in a row have failed
+	OnFailureAlert func(failures int, err error)
+
+	interval time.Duration
+	failures int // Consecutive failed scans
+}
+
+// NextInterval returns the delay before the next scan, given whether the
+// previous scan detected changes
+func (w *AdaptiveWatcher) NextInterval(changed bool) time.Duration {
+	if w.MaxInterval < w.MinInterval {
+		w.MaxInterval = w.MinInterval
+	}
+	if w.BackoffFactor < 1 {
+		w.BackoffFactor = 1
+	}
+
+	if changed || w.interval == 0 {
+		w.interval = w.MinInterval
+		return w.interval
+	}
+
+	next := time.Duration(float64(w.interval) * w.BackoffFactor)
+	if next > w.MaxInterval {
+		next = w.MaxInterval
+	}
+	w.interval = next
+	return w.interval
+}
+
+// FailureInterval returns the delay after a failed scan: twice the normal
+// interval after the first failure in a row, four times after the second and
+// so on, up to an hour
+func (w *AdaptiveWatcher) FailureInterval() time.Duration {
+	normal := w.interval
+	if normal == 0 {
+		normal = w.MinInterval
+	}
+	next := normal
+	for i := 0; i < w.failures && next < maxFailureBackoff; i++ {
+		next *= 2
+	}
+	return max(normal, min(next, maxFailureBackoff))
+}
+
+// Run calls scan immediately and then again after each computed interval until ctx is cancelled.
+// scan reports whether changes were detected, or why it failed; failed scans
+// are retried with an exponential back-off instead of at the normal interval.
+func (w *AdaptiveWatcher) Run(ctx context.Context, scan func() (bool, error)) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Watch mode stopped.")
+			return
+		case <-timer.C:
+		}
+
+		changed, err := scan()
+		var interval time.Duration
+		if err != nil {
+			w.failures++
+			interval = w.FailureInterval()
+			logger.Warn("Scan failed (%d in a row), backing off to %s: %v", w.failures, interval, err)
+			if w.failures == failureAlertAfter && w.OnFailureAlert != nil {
+				w.OnFailureAlert(w.failures, err)
+			}
+		} else {
+			w.failures = 0
+			interval = w.NextInterval(changed)
+		}
+		fmt.Printf("Next scan in %s\n", interval)
+		timer.Reset(interval)
+	}
+}
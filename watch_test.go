@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveWatcherNextInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		watcher AdaptiveWatcher
+		changed []bool
+		want    []time.Duration
+	}{
+		{
+			name:    "slows down while nothing changes",
+			watcher: AdaptiveWatcher{MinInterval: time.Minute, MaxInterval: time.Hour, BackoffFactor: 2},
+			changed: []bool{false, false, false, false},
+			want:    []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute},
+		},
+		{
+			name:    "speeds up to the minimum on a change",
+			watcher: AdaptiveWatcher{MinInterval: time.Minute, MaxInterval: time.Hour, BackoffFactor: 3},
+			changed: []bool{false, false, false, true, false},
+			want:    []time.Duration{time.Minute, 3 * time.Minute, 9 * time.Minute, time.Minute, 3 * time.Minute},
+		},
+		{
+			name:    "clamped to the maximum",
+			watcher: AdaptiveWatcher{MinInterval: 10 * time.Minute, MaxInterval: 25 * time.Minute, BackoffFactor: 2},
+			changed: []bool{false, false, false, false},
+			want:    []time.Duration{10 * time.Minute, 20 * time.Minute, 25 * time.Minute, 25 * time.Minute},
+		},
+		{
+			name:    "maximum below the minimum",
+			watcher: AdaptiveWatcher{MinInterval: 10 * time.Minute, MaxInterval: time.Minute, BackoffFactor: 2},
+			changed: []bool{false, false},
+			want:    []time.Duration{10 * time.Minute, 10 * time.Minute},
+		},
+		{
+			name:    "factor below 1 never speeds up",
+			watcher: AdaptiveWatcher{MinInterval: time.Minute, MaxInterval: time.Hour, BackoffFactor: 0.5},
+			changed: []bool{false, false},
+			want:    []time.Duration{time.Minute, time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		w := tt.watcher
+		for i, changed := range tt.changed {
+			if got := w.NextInterval(changed); got != tt.want[i] {
+				t.Errorf("%s: interval %d = %s, want %s", tt.name, i+1, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestAdaptiveWatcherFailureInterval(t *testing.T) {
+	tests := []struct {
+		interval time.Duration // The interval before the failures; 0 if no scan succeeded yet
+		failures int
+		want     time.Duration
+	}{
+		{0, 0, time.Minute},
+		{0, 1, 2 * time.Minute},
+		{5 * time.Minute, 1, 10 * time.Minute},
+		{5 * time.Minute, 2, 20 * time.Minute},
+		{5 * time.Minute, 3, 40 * time.Minute},
+		{5 * time.Minute, 4, maxFailureBackoff},
+		{5 * time.Minute, 50, maxFailureBackoff},
+		{2 * time.Hour, 1, 2 * time.Hour}, // Never sooner than the normal interval
+	}
+	for _, tt := range tests {
+		w := AdaptiveWatcher{MinInterval: time.Minute, MaxInterval: 3 * time.Hour, BackoffFactor: 2, interval: tt.interval, failures: tt.failures}
+		if got := w.FailureInterval(); got != tt.want {
+			t.Errorf("interval %s after %d failures: %s, want %s", tt.interval, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestAdaptiveWatcherRunResetsFailures(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Three failures, a success, then three more failures
+	outcomes := []error{errors.New("nmap exited 1"), errors.New("nmap exited 1"), errors.New("nmap exited 1"), nil,
+		errors.New("nmap exited 1"), errors.New("nmap exited 1"), errors.New("nmap exited 1")}
+	var alerts, failuresSeen []int
+	scans := 0
+	w := &AdaptiveWatcher{
+		MinInterval:    time.Microsecond,
+		MaxInterval:    time.Microsecond,
+		OnFailureAlert: func(failures int, err error) { alerts = append(alerts, failures) },
+	}
+	w.Run(ctx, func() (bool, error) {
+		if scans == len(outcomes) {
+			return false, nil // The timer won the race with cancel
+		}
+		failuresSeen = append(failuresSeen, w.failures)
+		err := outcomes[scans]
+		if scans++; scans == len(outcomes) {
+			cancel()
+		}
+		return false, err
+	})
+
+	want := []int{0, 1, 2, 3, 0, 1, 2}
+	for i := range want {
+		if failuresSeen[i] != want[i] {
+			t.Fatalf("failures counted before each scan: %v, want %v", failuresSeen, want)
+		}
+	}
+	if len(alerts) != 2 || alerts[0] != failureAlertAfter || alerts[1] != failureAlertAfter {
+		t.Errorf("alerts after %v failures, want one alert per run of %d failures", alerts, failureAlertAfter)
+	}
+}
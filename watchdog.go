@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// watchdogInterval is how often the watchdog checks for an overdue scan
+const watchdogInterval = 5 * time.Minute
+
+// Watchdog raises an alert when no scan has been saved for Threshold, which
+// catches a PortHunter that has silently stopped (a crashed watch loop, an
+// OOM kill). It alerts once per overdue period. A change window covering
+// every target holds the alert back until it ends.
+type Watchdog struct {
+	Threshold     time.Duration
+	Notifiers     []Notifier
+	DataDir       string // Folder whose host index records the last scan
+	ChangeWindows []ChangeWindow
+
+	alertedFor time.Time // Last scan time the current alert was raised for
+	heldFor    time.Time // Last scan time an alert was held back for
+}
+
+// LastScanTime returns the time of the most recent scan saved in dir, from
+// the host index, or the zero time if there is none. Indexes written before it
+// recorded the scan time fall back to the latest time any host was seen.
+func LastScanTime(dir string) (time.Time, error) {
+	index, err := LoadHistoryIndex(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	last := scanTime(index.LastScan)
+	for _, record := range index.Hosts {
+		if seen := scanTime(record.LastSeen); seen.After(last) {
+			last = seen
+		}
+	}
+	return last, nil
+}
+
+// Check alerts every notifier if the last scan is older than the threshold at now
+func (w *Watchdog) Check(now time.Time) error {
+	last, err := LastScanTime(w.DataDir)
+	if err != nil {
+		return err
+	}
+	age := now.Sub(last)
+	if last.IsZero() || age <= w.Threshold || last.Equal(w.alertedFor) {
+		return nil
+	}
+	if cw, ok := alertWindow(w.ChangeWindows, "", now); ok {
+		if !last.Equal(w.heldFor) { // Count each overdue period once, not every check
+			recordHeldBack(cw, func(record *changeWindowRecord) { record.Alerts++ })
+			logger.Info("Overdue scan alert suppressed during maintenance window %s (until %s)", cw.Reason, cw.End.Format(time.RFC3339))
+			w.heldFor = last
+		}
+		return nil
+	}
+
+	subject := "PortHunter scan overdue"
+	body := fmt.Sprintf("No scan has been saved since %s (%s ago), more than the %s threshold.\n"+
+		"Check that PortHunter is still running on this machine.\n",
+		last.Format(time.RFC3339), age.Round(time.Minute), w.Threshold)
+	logger.Warn("scan overdue: last scan %s ago", age.Round(time.Minute))
+	var errs []error
+	for _, n := range w.Notifiers {
+		// JIRA opens at most one overdue ticket a day however long the outage
+		if err := n.Send(subject, body); err != nil && !errors.Is(err, ErrTicketRateLimited) {
+			errs = append(errs, err)
+		}
+	}
+	w.alertedFor = last
+	return errors.Join(errs...)
+}
+
+// Run checks every watchdogInterval until ctx is cancelled
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.Check(time.Now()); err != nil {
+			logger.Error("watchdog: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runWatchdog implements "porthunter watchdog [flags]", which checks for
+// overdue scans until interrupted. It is meant to run alongside, not inside,
+// the PortHunter process it watches.
+func runWatchdog(cfg Config) error {
+	notifiers := configuredNotifiers(cfg)
+	if len(notifiers) == 0 {
+		return errors.New("the watchdog needs a notifier: configure email or jira")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("Watchdog checking %s every %s for scans older than %s\n", indexFile(cfg.DataDir), watchdogInterval, cfg.WatchdogThreshold)
+	watchdog := &Watchdog{Threshold: cfg.WatchdogThreshold, Notifiers: notifiers, DataDir: cfg.DataDir, ChangeWindows: cfg.ChangeWindows}
+	watchdog.Run(ctx)
+	return nil
+}
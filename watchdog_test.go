@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchdogCheck(t *testing.T) {
+	dir := t.TempDir()
+	last := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	index := HistoryIndex{LastScan: last.Format(time.RFC3339), Hosts: map[string]HostRecord{}}
+	if err := SaveHistoryIndex(dir, index); err != nil {
+		t.Fatal(err)
+	}
+
+	notifier := &recordingNotifier{}
+	watchdog := &Watchdog{Threshold: 24 * time.Hour, Notifiers: []Notifier{notifier}, DataDir: dir}
+	check := func(now time.Time, wantAlerts int) {
+		t.Helper()
+		if err := watchdog.Check(now); err != nil {
+			t.Fatal(err)
+		}
+		if len(notifier.subjects) != wantAlerts {
+			t.Fatalf("at %s: %d alerts, want %d", now.Sub(last), len(notifier.subjects), wantAlerts)
+		}
+	}
+
+	check(last.Add(23*time.Hour), 0)
+	check(last.Add(25*time.Hour), 1)
+	if body := notifier.bodies[0]; !strings.Contains(body, "since 2024-05-01T10:00:00Z (25h0m0s ago)") {
+		t.Errorf("alert body %q", body)
+	}
+	check(last.Add(30*time.Hour), 1) // Once per overdue period
+
+	// A new scan ends the period; the next overdue period alerts again
+	last = last.Add(26 * time.Hour)
+	index.LastScan = last.Format(time.RFC3339)
+	if err := SaveHistoryIndex(dir, index); err != nil {
+		t.Fatal(err)
+	}
+	check(last.Add(time.Hour), 1)
+	check(last.Add(25*time.Hour), 2)
+}
+
+func TestLastScanTime(t *testing.T) {
+	dir := t.TempDir()
+	if last, err := LastScanTime(dir); err != nil || !last.IsZero() {
+		t.Errorf("no index: got %v, %v; want the zero time", last, err)
+	}
+
+	// Indexes from before LastScan was recorded fall back to the hosts
+	index := HistoryIndex{Hosts: map[string]HostRecord{
+		"10.0.0.1": {LastSeen: "2024-05-01T10:00:00Z"},
+		"10.0.0.2": {LastSeen: "2024-05-02T10:00:00Z"},
+	}}
+	if err := SaveHistoryIndex(dir, index); err != nil {
+		t.Fatal(err)
+	}
+	last, err := LastScanTime(dir)
+	if err != nil || !last.Equal(time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %v, %v; want the latest LastSeen", last, err)
+	}
+}
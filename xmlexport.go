@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"net/netip"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// nmapXMLOutputVersion is the nmap XML schema version ExportNmapXML writes
+const nmapXMLOutputVersion = "1.05"
+
+// nmapStartStrLayout is how nmap formats times in its XML, like C's ctime
+const nmapStartStrLayout = "Mon Jan _2 15:04:05 2006"
+
+// The nmap*XML types are the elements of nmap's XML output (nmap.dtd) that
+// ExportNmapXML writes. Attributes the DTD requires but a ScanResult doesn't
+// record, such as why a port is open, are filled with neutral values.
+type nmapRunXML struct {
+	XMLName          xml.Name `xml:"nmaprun"`
+	Scanner          string   `xml:"scanner,attr"`
+	Args             string   `xml:"args,attr,omitempty"`
+	Start            int64    `xml:"start,attr,omitempty"`
+	StartStr         string   `xml:"startstr,attr,omitempty"`
+	Version          string   `xml:"version,attr"`
+	XMLOutputVersion string   `xml:"xmloutputversion,attr"`
+	Verbose          struct {
+		Level int `xml:"level,attr"`
+	} `xml:"verbose"`
+	Debugging struct {
+		Level int `xml:"level,attr"`
+	} `xml:"debugging"`
+	Hosts    []nmapHostXML `xml:"host"`
+	RunStats struct {
+		Finished struct {
+			Time    int64  `xml:"time,attr"`
+			TimeStr string `xml:"timestr,attr,omitempty"`
+			Elapsed string `xml:"elapsed,attr"`
+			Exit    string `xml:"exit,attr"`
+		} `xml:"finished"`
+		Hosts struct {
+			Up    int `xml:"up,attr"`
+			Down  int `xml:"down,attr"`
+			Total int `xml:"total,attr"`
+		} `xml:"hosts"`
+	} `xml:"runstats"`
+}
+
+type nmapHostXML struct {
+	Status struct {
+		State     string `xml:"state,attr"`
+		Reason    string `xml:"reason,attr"`
+		ReasonTTL string `xml:"reason_ttl,attr"`
+	} `xml:"status"`
+	Addresses []nmapAddressXML  `xml:"address"`
+	Hostnames []nmapHostnameXML `xml:"hostnames>hostname,omitempty"`
+	Ports     []nmapPortXML     `xml:"ports>port"`
+	OS        *nmapOSXML        `xml:"os"`
+	Distance  *nmapDistanceXML  `xml:"distance"`
+	Trace     *nmapTraceXML     `xml:"trace"`
+	Times     *nmapTimesXML     `xml:"times"`
+}
+
+type nmapAddressXML struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+	Vendor   string `xml:"vendor,attr,omitempty"`
+}
+
+type nmapHostnameXML struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapPortXML struct {
+	Protocol string `xml:"protocol,attr"`
+	PortID   int    `xml:"portid,attr"`
+	State    struct {
+		State     string `xml:"state,attr"`
+		Reason    string `xml:"reason,attr"`
+		ReasonTTL string `xml:"reason_ttl,attr"`
+	} `xml:"state"`
+	Service struct {
+		Name   string `xml:"name,attr"`
+		Method string `xml:"method,attr"`
+		Conf   string `xml:"conf,attr"`
+	} `xml:"service"`
+	Scripts []nmapScriptXML `xml:"script"`
+}
+
+type nmapScriptXML struct {
+	ID     string `xml:"id,attr"`
+	Output string `xml:"output,attr"`
+}
+
+type nmapOSXML struct {
+	Matches      []nmapOSMatchXML       `xml:"osmatch"`
+	Fingerprints []nmapOSFingerprintXML `xml:"osfingerprint"`
+}
+
+type nmapOSMatchXML struct {
+	Name     string `xml:"name,attr"`
+	Accuracy string `xml:"accuracy,attr"`
+	Line     string `xml:"line,attr"`
+}
+
+type nmapOSFingerprintXML struct {
+	Fingerprint string `xml:"fingerprint,attr"`
+}
+
+type nmapDistanceXML struct {
+	Value int `xml:"value,attr"`
+}
+
+type nmapTraceXML struct {
+	Hops []nmapHopXML `xml:"hop"`
+}
+
+type nmapTimesXML struct {
+	SRTT   int64 `xml:"srtt,attr"` // Microseconds
+	RTTVar int64 `xml:"rttvar,attr"`
+	To     int64 `xml:"to,attr"`
+}
+
+type nmapHopXML struct {
+	TTL    int    `xml:"ttl,attr"`
+	IPAddr string `xml:"ipaddr,attr,omitempty"`
+	RTT    string `xml:"rtt,attr,omitempty"`
+	Host   string `xml:"host,attr,omitempty"`
+}
+
+// ExportNmapXML writes result as nmap XML (-oX) that is valid against
+// nmap.dtd, so tools that consume nmap XML can read scans from any source
+// PortHunter imports. It is the inverse of ParseNmapXMLOutput: parsing the
+// output gives back the same hosts. Hosts known only by their port strings,
+// as in scans parsed from nmap's text output, are exported too.
+//
+// What nmap XML has no place for does not survive the round trip: a host's
+// DNSTTL, Asset and Shodan details, and the scan's PerHostTiming,
+// ExcludeRules and ExcludedTargets. Command is written to the args attribute,
+// but ParseNmapXMLOutput doesn't read it back.
+func ExportNmapXML(result ScanResult, w io.Writer) error {
+	run := nmapRunXML{
+		Scanner:          "nmap",
+		Args:             result.Command,
+		Version:          result.ScannerVersion,
+		XMLOutputVersion: nmapXMLOutputVersion,
+	}
+	if started, err := time.Parse(time.RFC3339, result.DateTime); err == nil {
+		run.Start, run.StartStr = started.Unix(), started.Format(nmapStartStrLayout)
+		run.RunStats.Finished.Time, run.RunStats.Finished.TimeStr = run.Start, run.StartStr
+	}
+
+	ips := make(map[string]bool, len(result.Ports))
+	for ip := range result.Ports {
+		ips[ip] = true
+	}
+	for ip := range result.Hosts {
+		ips[ip] = true
+	}
+	for _, ip := range sortedHostKeys(ips) {
+		host, ok := result.Hosts[ip]
+		if !ok {
+			host = HostFromPortStrings(ip, result.Ports[ip])
+		}
+		if host.IP == "" {
+			host.IP = ip
+		}
+		run.Hosts = append(run.Hosts, nmapHostToXML(host))
+		if host.State == "" || host.State == HostUp {
+			run.RunStats.Hosts.Up++
+		} else {
+			run.RunStats.Hosts.Down++
+		}
+	}
+	run.RunStats.Hosts.Total = len(run.Hosts)
+	run.RunStats.Finished.Elapsed = "0.00" // Not recorded
+	run.RunStats.Finished.Exit = "success"
+
+	if _, err := io.WriteString(w, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(run); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// nmapHostToXML converts a host to its <host> element
+func nmapHostToXML(host HostResult) nmapHostXML {
+	var x nmapHostXML
+	x.Status.State = host.State
+	if x.Status.State == "" {
+		x.Status.State = HostUp
+	}
+	// "user-set" is nmap's reason for hosts it was told were up (-Pn)
+	x.Status.Reason, x.Status.ReasonTTL = "user-set", "0"
+
+	addrType := "ipv4"
+	if addr, err := netip.ParseAddr(host.IP); err == nil && addr.Is6() && !addr.Is4In6() {
+		addrType = "ipv6"
+	}
+	x.Addresses = append(x.Addresses, nmapAddressXML{Addr: host.IP, AddrType: addrType})
+	if host.MAC != "" {
+		x.Addresses = append(x.Addresses, nmapAddressXML{Addr: host.MAC, AddrType: "mac", Vendor: host.Vendor})
+	}
+	if host.Hostname != "" {
+		kind := "PTR"
+		if host.DNSTTL > 0 {
+			kind = "user" // Scanned by this name
+		}
+		x.Hostnames = []nmapHostnameXML{{Name: host.Hostname, Type: kind}}
+	}
+
+	x.Ports = make([]nmapPortXML, 0, len(host.Ports))
+	for _, p := range host.Ports {
+		var port nmapPortXML
+		port.Protocol, port.PortID = p.Protocol, p.Port
+		port.State.State, port.State.Reason, port.State.ReasonTTL = p.State, "unknown", "0"
+		// conf 3 and method "table" are what nmap writes for names from nmap-services
+		port.Service.Name, port.Service.Method, port.Service.Conf = p.Service, "table", "3"
+		ids := make([]string, 0, len(p.Scripts))
+		for id := range p.Scripts {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			port.Scripts = append(port.Scripts, nmapScriptXML{ID: id, Output: p.Scripts[id]})
+		}
+		x.Ports = append(x.Ports, port)
+	}
+
+	if host.OS != "" || host.OSFingerprint != "" {
+		x.OS = &nmapOSXML{}
+		if host.OS != "" {
+			x.OS.Matches = []nmapOSMatchXML{{Name: host.OS, Accuracy: "100", Line: "0"}}
+		}
+		if host.OSFingerprint != "" {
+			x.OS.Fingerprints = []nmapOSFingerprintXML{{Fingerprint: host.OSFingerprint}}
+		}
+	}
+	if host.Hops > 0 {
+		x.Distance = &nmapDistanceXML{Value: host.Hops}
+	}
+	if len(host.Traceroute) > 0 {
+		x.Trace = &nmapTraceXML{}
+		for _, hop := range host.Traceroute {
+			h := nmapHopXML{TTL: hop.HopNum, IPAddr: hop.IP, Host: hop.Hostname}
+			if hop.RTT > 0 {
+				h.RTT = strconv.FormatFloat(hop.RTT, 'f', -1, 64)
+			}
+			x.Trace.Hops = append(x.Trace.Hops, h)
+		}
+	}
+	if host.Latency > 0 {
+		srtt := host.Latency.Microseconds()
+		// nmap never times a probe out in under 100ms
+		x.Times = &nmapTimesXML{SRTT: srtt, To: max(srtt, 100000)}
+	}
+	return x
+}
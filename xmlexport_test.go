@@ -0,0 +1,134 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTripXML is nmap -oX output with everything ExportNmapXML writes:
+// MAC and vendor, hostnames, scripts, OS detection, traceroute and timing
+const roundTripXML = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE nmaprun>
+<nmaprun scanner="nmap" args="nmap -sV -O --traceroute -oX - 10.0.0.0/30" start="1714557600" version="7.94" xmloutputversion="1.05">
+<host><status state="up" reason="arp-response" reason_ttl="0"/>
+<address addr="10.0.0.1" addrtype="ipv4"/>
+<address addr="00:1A:2B:3C:4D:5E" addrtype="mac" vendor="Cisco Systems"/>
+<hostnames><hostname name="router.example.com" type="PTR"/></hostnames>
+<ports>
+<port protocol="tcp" portid="22"><state state="open" reason="syn-ack" reason_ttl="64"/><service name="ssh" product="OpenSSH" method="probed" conf="10"/>
+<script id="ssh-hostkey" output="&#xa;  2048 aa:bb:cc (RSA)"/><script id="banner" output="SSH-2.0-OpenSSH_9.6"/></port>
+<port protocol="udp" portid="161"><state state="open|filtered" reason="no-response" reason_ttl="0"/><service name="snmp" method="table" conf="3"/></port>
+</ports>
+<os><osmatch name="Linux 5.0 - 5.4" accuracy="98" line="67000"/><osfingerprint fingerprint="OS:SCAN(V=7.94%E=4)"/></os>
+<distance value="2"/>
+<trace port="22" proto="tcp"><hop ttl="1" ipaddr="192.168.1.1" rtt="0.52" host="gw.local"/><hop ttl="2" ipaddr="10.0.0.1" rtt="1.10"/></trace>
+<times srtt="1100" rttvar="500" to="100000"/>
+</host>
+<host><status state="up" reason="echo-reply" reason_ttl="64"/>
+<address addr="2001:db8::1" addrtype="ipv6"/>
+<ports><port protocol="tcp" portid="443"><state state="open" reason="syn-ack" reason_ttl="64"/><service name="https" method="table" conf="3"/></port></ports>
+</host>
+<runstats><finished time="1714557700" elapsed="100.00" exit="success"/><hosts up="2" down="0" total="2"/></runstats>
+</nmaprun>
+`
+
+func TestExportNmapXMLRoundTrip(t *testing.T) {
+	ports, hosts, err := ParseNmapXMLOutput(roundTripXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 2 || len(hosts["10.0.0.1"].Traceroute) != 2 {
+		t.Fatalf("fixture parsed to %+v", hosts)
+	}
+	scan := ScanResult{
+		Version:        currentScanVersion,
+		DateTime:       "2024-05-01T10:00:00Z",
+		Ports:          ports,
+		Hosts:          hosts,
+		ScannerVersion: "7.94",
+	}
+
+	var out strings.Builder
+	if err := ExportNmapXML(scan, &out); err != nil {
+		t.Fatal(err)
+	}
+	gotPorts, gotHosts, err := ParseNmapXMLOutput(out.String())
+	if err != nil {
+		t.Fatalf("parsing the export: %v\n%s", err, out.String())
+	}
+	if !reflect.DeepEqual(gotPorts, ports) {
+		t.Errorf("ports after the round trip:\n got %v\nwant %v", gotPorts, ports)
+	}
+	for ip, want := range hosts {
+		if got := gotHosts[ip]; !reflect.DeepEqual(got, want) {
+			t.Errorf("host %s after the round trip:\n got %+v\nwant %+v", ip, got, want)
+		}
+	}
+	if !strings.Contains(out.String(), `version="7.94"`) {
+		t.Errorf("scanner version not exported:\n%s", out.String())
+	}
+}
+
+// TestExportNmapXMLLostFields pins down what nmap XML has no place for, as
+// documented on ExportNmapXML
+func TestExportNmapXMLLostFields(t *testing.T) {
+	host := HostResult{
+		IP:       "10.0.0.1",
+		Hostname: "www.example.com",
+		Ports:    []PortEntry{{Port: 80, Protocol: "tcp", State: "open", Service: "http"}},
+		DNSTTL:   300,
+		Asset:    &AssetInfo{Owner: "web team"},
+	}
+	scan := ScanResult{
+		Version:         currentScanVersion,
+		DateTime:        "2024-05-01T10:00:00Z",
+		Ports:           map[string][]string{"10.0.0.1": host.PortStrings()},
+		Hosts:           map[string]HostResult{"10.0.0.1": host},
+		Command:         "nmap www.example.com",
+		PerHostTiming:   map[string]time.Duration{"10.0.0.1": time.Second},
+		ExcludeRules:    []string{"10.0.0.2"},
+		ExcludedTargets: []string{"10.0.0.2"},
+	}
+
+	var out strings.Builder
+	if err := ExportNmapXML(scan, &out); err != nil {
+		t.Fatal(err)
+	}
+	_, hosts, err := ParseNmapXMLOutput(out.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := hosts["10.0.0.1"]
+	if got.Hostname != host.Hostname || len(got.Ports) != 1 {
+		t.Errorf("host did not survive: %+v", got)
+	}
+	if got.DNSTTL != 0 || got.Asset != nil {
+		t.Errorf("DNSTTL and Asset are not in nmap XML, but came back as %d, %+v", got.DNSTTL, got.Asset)
+	}
+	if !strings.Contains(out.String(), `args="nmap www.example.com"`) {
+		t.Errorf("Command not written to args:\n%s", out.String())
+	}
+}
+
+// TestExportNmapXMLTextOnlyHosts checks scans parsed from text output, which
+// have port strings but no HostResults
+func TestExportNmapXMLTextOnlyHosts(t *testing.T) {
+	scan := ScanResult{
+		Version:  currentScanVersion,
+		DateTime: "2024-05-01T10:00:00Z",
+		Ports:    map[string][]string{"10.0.0.5": {"22/tcp [open] (ssh)", "80/tcp [open] (http)"}},
+	}
+	var out strings.Builder
+	if err := ExportNmapXML(scan, &out); err != nil {
+		t.Fatal(err)
+	}
+	ports, _, err := ParseNmapXMLOutput(out.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ports, scan.Ports) {
+		t.Errorf("got %v, want %v", ports, scan.Ports)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PortEntry is a single port discovered on a host
+type PortEntry struct {
+	Port     int               `json:"port"`
+	Protocol string            `json:"protocol"`
+	State    string            `json:"state"`
+	Service  string            `json:"service"`
+	Scripts  map[string]string `json:"scripts,omitempty"` // NSE script id -> output
+
+	UnexpectedService bool `json:"unexpected_service,omitempty"` // Not the IANA service for a well-known port
+	UnexpectedPort    bool `json:"unexpected_port,omitempty"`    // Open but not in the host's CMDB expected ports
+}
+
+// String formats the entry the same way ParseNmapOutput does, e.g. "80/tcp [open] (http)"
+func (p PortEntry) String() string {
+	return fmt.Sprintf("%d/%s [%s] (%s)", p.Port, p.Protocol, p.State, p.Service)
+}
+
+// ParsePortEntry converts a port string such as "80/tcp [open] (http)" back into a PortEntry
+func ParsePortEntry(entry string) PortEntry {
+	port, proto, state, service := splitPortEntry(entry)
+	n, _ := strconv.Atoi(port)
+	return PortEntry{Port: n, Protocol: proto, State: state, Service: service}
+}
+
+// HostFromPortStrings builds a HostResult from the port strings stored in ScanResult.Ports
+func HostFromPortStrings(ip string, ports []string) HostResult {
+	host := HostResult{IP: ip, State: HostUp, Ports: make([]PortEntry, 0, len(ports))}
+	for _, entry := range ports {
+		host.Ports = append(host.Ports, ParsePortEntry(entry))
+	}
+	host.setExposureFlags()
+	host.markUnexpectedServices()
+	return host
+}
+
+// HostResult is everything parsed for a single host
+type HostResult struct {
+	IP       string      `json:"ip"`
+	Hostname string      `json:"hostname,omitempty"`
+	State    string      `json:"state,omitempty"`
+	MAC      string      `json:"mac,omitempty"`    // Only known for hosts on the local network
+	Vendor   string      `json:"vendor,omitempty"` // Manufacturer of the MAC's network card
+	OS       string      `json:"os,omitempty"`     // nmap -O's best match, e.g. "Linux 5.0 - 5.4"
+	Ports    []PortEntry `json:"ports"`
+
+	OSFingerprint string `json:"os_fingerprint,omitempty"` // Raw TCP/IP stack fingerprint from nmap -O
+
+	Traceroute []TraceHop `json:"traceroute,omitempty"` // From nmap --traceroute
+
+	DNSTTL int `json:"dns_ttl,omitempty"` // Seconds; only for hosts scanned by hostname, see ResolveWithTTL
+
+	Asset  *AssetInfo  `json:"asset,omitempty"`  // From the CMDB, if one is configured
+	Shodan *ShodanInfo `json:"shodan,omitempty"` // From -import-shodan
+
+	HostMetrics // Latency and network distance
+
+	// Open Windows remote-management services, see setExposureFlags
+	RemotingExposed bool `json:"remoting_exposed,omitempty"` // WinRM / PowerShell remoting (5985, 5986)
+	RPCExposed      bool `json:"rpc_exposed,omitempty"`      // MS-RPC endpoint mapper (135)
+	SMBExposed      bool `json:"smb_exposed,omitempty"`      // SMB (445)
+	RDPExposed      bool `json:"rdp_exposed,omitempty"`      // Remote Desktop (3389)
+}
+
+// PortStrings returns the host's ports in the string form used for diffing
+func (h HostResult) PortStrings() []string {
+	ports := make([]string, 0, len(h.Ports))
+	for _, p := range h.Ports {
+		ports = append(ports, p.String())
+	}
+	return ports
+}
+
+// xmlHost mirrors the parts of nmap's <host> element that PortHunter uses
+type xmlHost struct {
+	Status struct {
+		State string `xml:"state,attr"`
+	} `xml:"status"`
+	Addresses []struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+		Vendor   string `xml:"vendor,attr"`
+	} `xml:"address"`
+	Hostnames []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"hostnames>hostname"`
+	Ports []struct {
+		Protocol string `xml:"protocol,attr"`
+		PortID   int    `xml:"portid,attr"`
+		State    struct {
+			State string `xml:"state,attr"`
+		} `xml:"state"`
+		Service struct {
+			Name string `xml:"name,attr"`
+		} `xml:"service"`
+		Scripts []struct {
+			ID     string `xml:"id,attr"`
+			Output string `xml:"output,attr"`
+		} `xml:"script"`
+	} `xml:"ports>port"`
+	Trace []struct {
+		TTL    int    `xml:"ttl,attr"`
+		IPAddr string `xml:"ipaddr,attr"`
+		RTT    string `xml:"rtt,attr"`
+		Host   string `xml:"host,attr"`
+	} `xml:"trace>hop"`
+	OSMatches []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"os>osmatch"` // Best match first
+	OSFingerprint struct {
+		Fingerprint string `xml:"fingerprint,attr"`
+	} `xml:"os>osfingerprint"`
+	Distance struct {
+		Value int `xml:"value,attr"`
+	} `xml:"distance"`
+	Times struct {
+		SRTT string `xml:"srtt,attr"` // Smoothed round-trip time in microseconds
+	} `xml:"times"`
+}
+
+// toHostResult converts the decoded XML into a HostResult
+func (x xmlHost) toHostResult() HostResult {
+	host := HostResult{State: x.Status.State, Ports: []PortEntry{}}
+
+	for _, addr := range x.Addresses {
+		switch {
+		case addr.AddrType == "mac":
+			host.MAC, host.Vendor = strings.ToUpper(addr.Addr), addr.Vendor
+		case host.IP == "" && (addr.AddrType == "ipv4" || addr.AddrType == "ipv6"):
+			host.IP = addr.Addr
+		}
+	}
+	if len(x.Hostnames) > 0 {
+		host.Hostname = x.Hostnames[0].Name
+	}
+	if len(x.OSMatches) > 0 {
+		host.OS = x.OSMatches[0].Name
+	}
+	host.OSFingerprint = strings.TrimSpace(x.OSFingerprint.Fingerprint)
+	host.Hops = x.Distance.Value
+	if srtt, err := strconv.Atoi(x.Times.SRTT); err == nil {
+		host.Latency = time.Duration(srtt) * time.Microsecond
+	}
+	for _, h := range x.Trace {
+		rtt, _ := strconv.ParseFloat(h.RTT, 64) // "--" when nmap has no timing
+		host.Traceroute = append(host.Traceroute, TraceHop{HopNum: h.TTL, IP: h.IPAddr, RTT: rtt, Hostname: h.Host})
+	}
+
+	for _, p := range x.Ports {
+		entry := PortEntry{
+			Port:     p.PortID,
+			Protocol: p.Protocol,
+			State:    p.State.State,
+			Service:  p.Service.Name,
+		}
+		if entry.Service == "" {
+			entry.Service = "unknown"
+		}
+		entry.Service = nameUnknownService(entry.Port, entry.Protocol, entry.Service)
+		if len(p.Scripts) > 0 {
+			entry.Scripts = make(map[string]string, len(p.Scripts))
+			for _, s := range p.Scripts {
+				entry.Scripts[s.ID] = s.Output
+			}
+		}
+		host.Ports = append(host.Ports, entry)
+	}
+	host.setExposureFlags()
+	host.markUnexpectedServices()
+	return host
+}
+
+// ParseNmapXMLStream parses nmap XML (-oX) output incrementally, sending each
+// host as soon as its </host> element closes. Only one host is held in memory
+// at a time. Both channels are closed when parsing finishes; at most one
+// error is sent.
+func ParseNmapXMLStream(r io.Reader) (<-chan HostResult, <-chan error) {
+	hosts := make(chan HostResult)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(hosts)
+		defer close(errc)
+
+		decoder := xml.NewDecoder(r)
+		for {
+			tok, err := decoder.Token()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				errc <- fmt.Errorf("parsing nmap XML: %v", err)
+				return
+			}
+
+			start, ok := tok.(xml.StartElement)
+			if !ok || start.Name.Local != "host" {
+				continue
+			}
+
+			var x xmlHost
+			if err := decoder.DecodeElement(&x, &start); err != nil {
+				errc <- fmt.Errorf("parsing nmap XML host: %v", err)
+				return
+			}
+			host := x.toHostResult()
+			if host.IP != "" {
+				hosts <- host
+			}
+		}
+	}()
+
+	return hosts, errc
+}
+
+// ParseNmapXMLOutput parses a complete nmap XML document into the same
+// structure as ParseNmapOutput, plus the full per-host results
+func ParseNmapXMLOutput(output string) (map[string][]string, map[string]HostResult, error) {
+	return collectXMLHosts(ParseNmapXMLStream(strings.NewReader(output)))
+}
+
+// collectXMLHosts drains a host stream into port strings and host results
+func collectXMLHosts(hosts <-chan HostResult, errc <-chan error) (map[string][]string, map[string]HostResult, error) {
+	ports := make(map[string][]string)
+	results := make(map[string]HostResult)
+	for host := range hosts {
+		if host.State != "" && host.State != HostUp {
+			continue
+		}
+		ports[host.IP] = host.PortStrings()
+		sortPortEntries(ports[host.IP])
+		results[host.IP] = host
+	}
+	return ports, results, <-errc
+}
+
+// wantsXMLOutput reports whether the command sends XML to stdout ("-oX -")
+func wantsXMLOutput(args []string) bool {
+	for i, arg := range args {
+		if arg == "-oX" && i+1 < len(args) && args[i+1] == "-" {
+			return true
+		}
+	}
+	return false
+}